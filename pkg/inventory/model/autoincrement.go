@@ -0,0 +1,49 @@
+package model
+
+import (
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"reflect"
+)
+
+//
+// Errors
+var (
+	// Autoincrement field error.
+	AutoIncrementErr = errors.New("autoincrement field must be an int PK")
+)
+
+//
+// Get whether the (int) PK is assigned by the DB.
+// The PK column is declared AUTOINCREMENT and omitted
+// on insert when zero. The assigned (rowid) value is
+// set on the model after the insert.
+func (f *Field) AutoIncrement() bool {
+	return f.hasOpt("autoincrement")
+}
+
+//
+// Validate the autoincrement field.
+func (f *Field) validateAutoIncrement() error {
+	if !f.AutoIncrement() {
+		return nil
+	}
+	if !f.Pk() || f.Custom() {
+		return liberr.Wrap(AutoIncrementErr)
+	}
+	switch f.Value.Kind() {
+	case reflect.Int,
+		reflect.Int32,
+		reflect.Int64:
+	default:
+		return liberr.Wrap(AutoIncrementErr)
+	}
+
+	return nil
+}
+
+//
+// Get whether the PK is to be assigned by the DB on insert.
+func (f *Field) assigned() bool {
+	return f.AutoIncrement() && f.Value.Int() == 0
+}
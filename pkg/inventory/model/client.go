@@ -1,15 +1,63 @@
 package model
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	liberr "github.com/konveyor/controller/pkg/error"
+	"github.com/mattn/go-sqlite3"
+	"io"
+	"net/url"
 	"os"
 	"reflect"
+	"regexp"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 const (
 	Pragma = "PRAGMA foreign_keys = ON"
+	// Foreign keys disabled.
+	PragmaFkOff = "PRAGMA foreign_keys = OFF"
+	// Write-ahead log (WAL) journal mode.
+	PragmaWAL = "PRAGMA journal_mode = WAL"
+	// Default max idle (pooled) connections.
+	MaxIdleConns = 2
+)
+
+//
+// Errors
+var (
+	// Client closed (or not opened).
+	ClosedErr = errors.New("client closed")
+	// Transaction already ended.
+	TxEndedErr = errors.New("transaction ended")
+	// Close attempted with a transaction open.
+	TxOpenErr = errors.New("transaction must be ended before close")
+	// Invalid attached database alias.
+	AliasErr = errors.New("alias must be an identifier")
+	// Write attempted on a read-only client.
+	ReadOnlyErr = errors.New("client is read-only")
+	// Invalid pragma.
+	PragmaErr = errors.New("pragma name must be an identifier and value must be (identifier, number)")
+	// Table referenced by another table.
+	DropRefErr = errors.New("table referenced (fk) by another table must not be dropped")
+)
+
+//
+// Regex used to validate attached database aliases.
+var AliasRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+//
+// Regex used to validate pragma names and values.
+var (
+	PragmaRegex      = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	PragmaValueRegex = regexp.MustCompile(`^[-+]?[A-Za-z0-9_.]+$`)
 )
 
 //
@@ -19,20 +67,114 @@ type DB interface {
 	Open(bool) error
 	// Close.
 	Close(bool) error
+	// Drop the tables of all models.
+	DropAll() error
+	// Create the table of the model.
+	CreateTable(Model) error
+	// Drop the table of the model.
+	DropTable(Model) error
 	// Get the specified model.
 	Get(Model) error
+	// Get the specified model (fields) by detail level.
+	GetDetail(Model, int) error
+	// Get the model by (SQLite) rowid.
+	GetByRowID(Model, int64) error
+	// Get models by PK.
+	GetMany(Model, []string, interface{}) error
 	// List models based on the type of slice.
 	List(interface{}, ListOptions) error
+	// List models and report whether more exist beyond the page.
+	ListMore(interface{}, ListOptions) (bool, error)
+	// List models (keyset paged) following the cursor.
+	ListCursor(interface{}, ListOptions, string) (string, error)
+	// Search (full-text) models.
+	Search(interface{}, string, ListOptions) error
+	// List models as maps keyed by column name.
+	ListMaps(Model, ListOptions) ([]map[string]interface{}, error)
 	// Count based on the specified model.
 	Count(Model, Predicate) (int64, error)
+	// Get the number of (all) models.
+	Len(Model) (int64, error)
+	// Get whether there are no models.
+	IsEmpty(Model) (bool, error)
+	// Count models by the value of a field.
+	CountBy(Model, string, Predicate) (map[string]int64, error)
+	// Count models by the values of fields.
+	CountByGroup(Model, []string, Predicate) ([]GroupCount, error)
+	// Count models by the values of fields (options).
+	CountGroups(Model, []string, ListOptions) ([]GroupCount, error)
+	// Find duplicate natural keys.
+	DuplicateKeys(Model) ([]GroupCount, error)
+	// Get a (single) scalar value of a field.
+	Scalar(Model, string, string, Predicate) (interface{}, error)
+	// List the distinct values of a field.
+	DistinctValues(Model, string, Predicate) ([]interface{}, error)
+	// Aggregate (SUM, AVG, MIN, MAX) a field.
+	Aggregate(Model, string, string, Predicate) (float64, error)
+	// Join models.
+	Join(Join) ([]JoinRow, error)
+	// Compile a (prepared) list query.
+	Compile(Model, ListOptions) (*Query, error)
+	// Export models as a JSON array.
+	ExportJSON(Model, io.Writer, ListOptions) error
+	// Import (upsert) models from a JSON array.
+	ImportJSON(Model, io.Reader) error
+	// Export models as CSV.
+	ExportCSV(Model, io.Writer, ListOptions) error
+	// Import (upsert) models from CSV.
+	ImportCSV(Model, io.Reader) error
 	// Begin a transaction.
 	Begin() (*Tx, error)
+	// Begin a transaction with context.
+	BeginTx(context.Context) (*Tx, error)
 	// Insert a model.
 	Insert(Model) error
 	// Update a model.
 	Update(Model) error
+	// Touch a model.
+	Touch(Model) error
+	// Set the value of a key in a map field.
+	MapSet(Model, string, string, string) error
+	// Get the value of a key in a map field.
+	MapGet(Model, string, string) (string, bool, error)
+	// Delete models not touched since the cutoff.
+	DeleteStale(Model, time.Time) (int64, error)
 	// Delete a model.
 	Delete(Model) error
+	// Enable/disable foreign key enforcement.
+	SetForeignKeys(bool) error
+	// Set a (per-connection) pragma.
+	SetPragma(string, string) error
+	// Get a pragma.
+	GetPragma(string) (string, error)
+	// Set the keyring used for encrypted fields.
+	SetKeyring(*Keyring)
+	// Gather query planner statistics for a model.
+	Analyze(Model) error
+	// Gather query planner statistics for all models.
+	AnalyzeAll() error
+	// Rebuild the indexes for a model.
+	Reindex(Model) error
+	// Get query planner statistics for a model.
+	PlannerStats(Model) ([]PlannerStat, error)
+	// Describe the schema.
+	Describe() ([]TableSchema, error)
+	// Attach a database and bind models to it.
+	Attach(string, string, ...interface{}) error
+	// Detach a database.
+	Detach(string) error
+	// Run schema migrations.
+	Migrate([]Migration) error
+	// Applied schema migration versions.
+	Migrations() ([]int, error)
+	// SQLite limits.
+	Limits() (Limits, error)
+	// Client statistics.
+	Stats() Stats
+	// Seed (load) models.
+	Seed(...Model) error
+	// Seed (load) models from JSON.
+	SeedFromJSON(io.Reader) error
 	// Watch a model collection.
 	Watch(Model, EventHandler) (*Watch, error)
 	// End a watch.
@@ -42,246 +184,1911 @@ type DB interface {
 //
 // Database client.
 type Client struct {
-	labeler Labeler
+	// Table and column naming strategy.
+	// Must be set before Open().
+	Namer Namer
+	// Generated PK hashing strategy.
+	// Defaults to the SHA1Hasher. PKs are persisted
+	// so the strategy must not change once created.
+	// Must be set before Open().
+	Hasher Hasher
+	// Encoded (struct, slice, map) field encoder.
+	// Defaults to the JSONEncoder.
+	// Must be set before Open().
+	Encoder Encoder
+	// Encoded (struct, slice, map) field decoder.
+	// Defaults to the JSONDecoder.
+	// Must be set before Open().
+	Decoder Decoder
+	// Bind parameters by position (?) rather than by
+	// name (:name). Intended for driver compatibility.
+	// Default: named.
+	Positional bool
+	// Disable foreign key enforcement.
+	// Must be set before Open().
+	DisableForeignKeys bool
+	// Read-only. The DB file is opened read-only and the
+	// schema is not created; it must already exist. Models
+	// may be read (Get, List, Count, ...) but writes
+	// (Insert, Update, Delete, Begin, Migrate) and
+	// maintenance (Analyze, Reindex, Attach, SetPragma)
+	// return ReadOnlyErr. Intended for read replicas (copies)
+	// of a DB maintained by another client.
+	// Must be set before Open().
+	ReadOnly bool
+	// Disable labels. The Label table is not created
+	// and model labels are not stored.
+	// Must be set before Open().
+	DisableLabels bool
+	// Called by Open() with the generated DDL statements
+	// before they are executed. Returns the statements to
+	// be executed which may be modified (example: to add
+	// statements). An error fails the Open(). Not called
+	// when read-only.
+	// Must be set before Open().
+	BeforeDDL func(statements []string) ([]string, error)
+	// Maximum page limit.
+	// Larger limits are capped. 0 = unlimited.
+	MaxPageLimit int
+	// Maximum number of models listed without a page.
+	// See: Table.MaxListRows. 0 = unlimited.
+	MaxListRows int
+	// Strict bool columns.
+	// See: Table.StrictBool.
+	// Must be set before Open().
+	StrictBool bool
+	// Bool columns stored as TEXT ('true','false').
+	// See: Table.BoolText.
+	// Must be set before Open().
+	BoolText bool
+	// Field value transforms by name.
+	// See: Table.Transforms.
+	// Must be set before Open().
+	Transforms map[string]Transform
+	// Intercepts (and may rewrite) the predicate of each
+	// read (list, count, get, aggregate) of the client and
+	// its transactions. Called with the context passed to
+	// BeginTx() for transaction reads; else context.TODO()
+	// which includes the Get() made by Update().
+	// See: PredicateInterceptor.
+	Interceptor PredicateInterceptor
+	// Keyring used for encrypted fields.
+	// Required when models have encrypted fields.
+	// See: SetKeyring().
+	Keyring *Keyring
+	// Logger.
+	// Default: NopLogger.
+	Log Logger
+	// Log each statement (at info) using the Log.
+	LogStatements bool
+	// Context key of the request (correlation) ID.
+	// The context value is included (as `trace`) in the
+	// statement log entries of transactions begun using
+	// BeginTx(). See: LogStatements.
+	TraceKey interface{}
+	labeler  Labeler
+	// Single writer. Non-transactional writes (insert,
+	// update, delete, touch) are queued and executed in
+	// order by a single goroutine. Intended for write-heavy
+	// (single process) deployments where many goroutines
+	// write concurrently. Transactions are not queued but
+	// remain serialized with the queued writes.
+	// Must be set before Open().
+	SingleWriter bool
+	// Write-ahead log (WAL) journal mode. Reads (on other
+	// pooled connections) proceed while a write or transaction
+	// is in progress and do not delay the commit. Persisted
+	// in the DB file. Ignored for in-memory and read-only
+	// databases.
+	// Must be set before Open().
+	WAL bool
 	// The sqlite3 database will not support
 	// concurrent write operations.
+	// Lock hierarchy: dbMutex must be acquired before
+	// stateMutex and is held for the duration of each
+	// write (and transaction). Begin() acquires dbMutex
+	// which is released when the transaction has ended
+	// (committed or rolled back). Reads acquire (only)
+	// stateMutex (briefly) and never block on dbMutex.
+	// So, there is a single writer and many readers; reads
+	// made outside the transaction see committed models.
+	// The in-memory database has a single connection so
+	// reads made outside the transaction wait for it to end.
 	dbMutex sync.Mutex
 	// file path.
 	path string
 	// Model
 	models []interface{}
+	// Protects the (open/closed) DB state
+	// and the model bindings.
+	// Never held while acquiring dbMutex.
+	stateMutex sync.RWMutex
+	// Model types by type name.
+	kinds map[string]reflect.Type
+	// Attached database (alias) by model type name.
+	schemas map[string]string
 	// Database connection.
+	// Nil when closed.
 	db *sql.DB
+	// Database connector.
+	connector *connector
+	// In-memory database.
+	memory bool
+	// In-memory database connection (outside the pool)
+	// which keeps the DB from being destroyed when the
+	// pooled connections are closed.
+	keep driver.Conn
 	// Journal
 	journal Journal
+	// Number of statements executed.
+	statements *uint64
+	// A transaction is in progress (1).
+	txOpen int32
+	// Write queue.
+	// Set when opened as a single writer.
+	writer *writer
 }
 
 //
 // Create the database.
 // Build the schema to support the specified models.
 // Optionally `purge` (delete) the DB first.
+// Re-opening does not register the models again.
+// Returns *SchemaError when a model cannot be built.
 func (r *Client) Open(purge bool) error {
 	if purge {
-		os.Remove(r.path)
+		if r.ReadOnly {
+			return liberr.Wrap(ReadOnlyErr)
+		}
+		if !r.memory {
+			os.Remove(r.path)
+			os.Remove(r.path + "-wal")
+			os.Remove(r.path + "-shm")
+		}
+	}
+	r.connector = &connector{
+		path:        r.path,
+		foreignKeys: !r.DisableForeignKeys,
+		readOnly:    r.ReadOnly && !r.memory,
+		wal:         r.WAL && !r.ReadOnly && !r.memory,
+	}
+	db := sql.OpenDB(r.connector)
+	db.SetMaxIdleConns(MaxIdleConns)
+	var keep driver.Conn
+	if r.memory {
+		db.SetMaxOpenConns(1)
+		conn, err := r.connector.Connect(context.TODO())
+		if err != nil {
+			db.Close()
+			return liberr.Wrap(err)
+		}
+		keep = conn
+	}
+	fail := func() {
+		db.Close()
+		if keep != nil {
+			keep.Close()
+		}
 	}
-	db, err := sql.Open("sqlite3", r.path)
+	r.labeler.Disabled = r.DisableLabels
+	models := r.schema()
+	kinds := map[string]reflect.Type{}
+	for _, m := range models {
+		kinds[r.kind(m).Name()] = r.kind(m)
+	}
+	r.stateMutex.Lock()
+	r.kinds = kinds
+	r.schemas = map[string]string{}
+	r.stateMutex.Unlock()
+	statements, err := r.ddl(db, models)
 	if err != nil {
-		panic(err)
+		fail()
+		return err
 	}
-	statements := []string{Pragma}
-	r.models = append(r.models, &Label{})
-	for _, m := range r.models {
-		ddl, err := Table{}.DDL(m)
+	if r.ReadOnly {
+		statements = nil
+	} else if r.BeforeDDL != nil {
+		statements, err = r.BeforeDDL(statements)
 		if err != nil {
-			panic(err)
+			fail()
+			return liberr.Wrap(err)
 		}
-		statements = append(statements, ddl...)
 	}
 	for _, ddl := range statements {
-		_, err = db.Exec(ddl)
+		_, err := db.Exec(ddl)
 		if err != nil {
-			db.Close()
+			fail()
 			return liberr.Wrap(err)
 		}
 	}
 
+	r.stateMutex.Lock()
+	previous := r.db
+	previousKeep := r.keep
 	r.db = db
+	r.keep = keep
+	r.statements = new(uint64)
+	if r.SingleWriter && r.writer == nil {
+		r.writer = &writer{}
+		r.writer.start(&r.dbMutex)
+	}
+	r.stateMutex.Unlock()
+	if previous != nil {
+		err = previous.Close()
+		if err != nil {
+			r.log().Error(err, "Close (previous) DB failed.")
+		}
+	}
+	if previousKeep != nil {
+		err = previousKeep.Close()
+		if err != nil {
+			r.log().Error(err, "Close (previous) connection failed.")
+		}
+	}
+
+	r.log().Info(
+		"Opened.",
+		"path",
+		r.path,
+		"ddl",
+		len(statements))
+
+	return nil
+}
+
+//
+// Close the database.
+// Optionally purge (delete) the DB.
+// Waits for in-progress writes to end. Transactions must be
+// ended first; returns TxOpenErr when a transaction is open
+// (rather than waiting on the transaction which deadlocks
+// when called by the goroutine that began it).
+// Safe to call multiple times (and concurrently).
+func (r *Client) Close(purge bool) error {
+	if atomic.LoadInt32(&r.txOpen) == 1 {
+		return liberr.Wrap(TxOpenErr)
+	}
+	r.dbMutex.Lock()
+	defer r.dbMutex.Unlock()
+	r.stateMutex.Lock()
+	defer r.stateMutex.Unlock()
+	if r.db == nil {
+		return nil
+	}
+	err := r.db.Close()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	r.db = nil
+	if r.writer != nil {
+		r.writer.stop()
+		r.writer = nil
+	}
+	if r.keep != nil {
+		err = r.keep.Close()
+		if err != nil {
+			r.log().Error(err, "Close connection failed.")
+		}
+		r.keep = nil
+	}
+	if purge && !r.memory {
+		os.Remove(r.path)
+		os.Remove(r.path + "-wal")
+		os.Remove(r.path + "-shm")
+	}
+
+	r.log().Info(
+		"Closed.",
+		"path",
+		r.path,
+		"purged",
+		purge)
+
+	return nil
+}
+
+//
+// Drop the tables (and indexes) of all models.
+// Tables are dropped in reverse (FK) dependency order so
+// references do not block the drop. Unlike Close(purge),
+// the DB file (and pragmas) are kept. The tables are
+// rebuilt by Open(). Migrations are not dropped.
+func (r *Client) DropAll() error {
+	if r.ReadOnly {
+		return liberr.Wrap(ReadOnlyErr)
+	}
+	models := []Model{}
+	byKind := map[string]Model{}
+	for _, m := range r.schema() {
+		if model, cast := m.(Model); cast {
+			models = append(models, model)
+			byKind[r.kind(m).Name()] = model
+		}
+	}
+	tx, err := r.Begin()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	defer tx.Rollback()
+	table := r.table(tx.real)
+	kinds := r.seedOrder(models)
+	for i := len(kinds) - 1; i >= 0; i-- {
+		ddl, err := table.DropDDL(byKind[kinds[i]])
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		for _, stmt := range ddl {
+			_, err = tx.real.Exec(stmt)
+			if err != nil {
+				return liberr.Wrap(err)
+			}
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+
+	r.log().Info("Dropped (all) tables.")
+
+	return nil
+}
+
+//
+// Create the table (and indexes) of the model.
+// The model is registered (when not already) so the table
+// is also built by Open(). Intended to rebuild a (dropped)
+// table or add a model without reopening. Tables referenced
+// (FK) by the model must be created first.
+// Returns *SchemaError when the model cannot be built.
+// The registration is reverted when the table is not created.
+func (r *Client) CreateTable(model Model) (err error) {
+	if r.ReadOnly {
+		return liberr.Wrap(ReadOnlyErr)
+	}
+	tx, err := r.Begin()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	defer tx.Rollback()
+	mt := r.kind(model)
+	r.stateMutex.Lock()
+	prevKinds, prevModels := r.kinds, r.models
+	defer func() {
+		if err != nil {
+			r.stateMutex.Lock()
+			r.kinds, r.models = prevKinds, prevModels
+			r.stateMutex.Unlock()
+		}
+	}()
+	kinds := map[string]reflect.Type{}
+	for k, v := range r.kinds {
+		kinds[k] = v
+	}
+	kinds[mt.Name()] = mt
+	r.kinds = kinds
+	registered := false
+	for _, m := range r.models {
+		if r.kind(m) == mt {
+			registered = true
+			break
+		}
+	}
+	if !registered {
+		r.models = append(r.models, model)
+	}
+	r.stateMutex.Unlock()
+	ddl, err := r.table(tx.real).DDL(model)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range ddl {
+		_, err = tx.real.Exec(stmt)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+
+	r.log().Info("Table created.", "kind", mt.Name())
+
+	return nil
+}
+
+//
+// Drop the table (and indexes) of the model.
+// The model remains registered and the table is rebuilt
+// by Open() or CreateTable(). Returns DropRefErr when the
+// table is referenced (FK) by the table of another model;
+// they must be dropped first. Otherwise, the (cascaded)
+// delete would silently delete the referencing models.
+func (r *Client) DropTable(model Model) error {
+	if r.ReadOnly {
+		return liberr.Wrap(ReadOnlyErr)
+	}
+	tx, err := r.Begin()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	defer tx.Rollback()
+	table := r.table(tx.real)
+	kind := r.kind(model).Name()
+	for _, m := range r.schema() {
+		if r.kind(m).Name() == kind {
+			continue
+		}
+		fields, err := table.Fields(m)
+		if err != nil {
+			continue
+		}
+		for _, f := range fields {
+			if fk := f.Fk(); fk != nil && fk.Table == kind {
+				exists, err := r.exists(tx.real, table, m)
+				if err != nil {
+					return liberr.Wrap(err)
+				}
+				if exists {
+					return liberr.Wrap(DropRefErr)
+				}
+			}
+		}
+	}
+	ddl, err := table.DropDDL(model)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	for _, stmt := range ddl {
+		_, err = tx.real.Exec(stmt)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+
+	r.log().Info("Table dropped.", "kind", kind)
+
+	return nil
+}
+
+//
+// Get whether the table of the model exists.
+func (r *Client) exists(db DBTX, table Table, model interface{}) (bool, error) {
+	schema, name := table.split(model)
+	master := "sqlite_master"
+	if schema != "" {
+		master = schema + "." + master
+	}
+	n := 0
+	row := db.QueryRow(
+		"SELECT COUNT(*) FROM "+master+" WHERE type = 'table' AND name = ?;",
+		name)
+	err := row.Scan(&n)
+	if err != nil {
+		return false, liberr.Wrap(err)
+	}
+
+	return n > 0, nil
+}
+
+//
+// Get the model.
+func (r *Client) Get(model Model) error {
+	db, err := r.pool()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	return r.table(db).Get(model)
+}
+
+//
+// Get the model fields matching the detail level.
+// The PK is always fetched. Omitted fields (including
+// encoded fields) are set to their zero values.
+// See: ListOptions.Detail.
+func (r *Client) GetDetail(model Model, detail int) error {
+	db, err := r.pool()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	return r.table(db).GetDetail(model, detail)
+}
+
+//
+// Get the model by (SQLite) rowid.
+// See: Table.GetByRowID().
+func (r *Client) GetByRowID(model Model, rowid int64) error {
+	db, err := r.pool()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	return r.table(db).GetByRowID(model, rowid)
+}
+
+//
+// Get models by PK.
+// The `list` must be: *[]Model. Models are returned in
+// the order of the specified PKs. PKs not found are omitted.
+func (r *Client) GetMany(model Model, pks []string, list interface{}) error {
+	db, err := r.pool()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	return r.table(db).GetMany(model, pks, list)
+}
+
+//
+// List models.
+// The `list` must be: *[]Model.
+func (r *Client) List(list interface{}, options ListOptions) error {
+	db, err := r.pool()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	return r.table(db).List(list, options)
+}
+
+//
+// List models and report whether more models exist
+// beyond the page. See: Table.ListMore().
+func (r *Client) ListMore(list interface{}, options ListOptions) (bool, error) {
+	db, err := r.pool()
+	if err != nil {
+		return false, liberr.Wrap(err)
+	}
+	return r.table(db).ListMore(list, options)
+}
+
+//
+// List models (keyset paged) following the cursor (token)
+// and return the cursor for the next page. See: Table.ListCursor().
+func (r *Client) ListCursor(list interface{}, options ListOptions, token string) (string, error) {
+	db, err := r.pool()
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+	return r.table(db).ListCursor(list, options, token)
+}
+
+//
+// Search (full-text) models.
+// See: Table.Search().
+func (r *Client) Search(list interface{}, query string, options ListOptions) error {
+	db, err := r.pool()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	return r.table(db).Search(list, query, options)
+}
+
+//
+// List models as maps keyed by column name.
+// Encoded (json) columns are decoded.
+func (r *Client) ListMaps(model Model, options ListOptions) ([]map[string]interface{}, error) {
+	db, err := r.pool()
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	return r.table(db).ListMaps(model, options)
+}
+
+//
+// Count models.
+func (r *Client) Count(model Model, predicate Predicate) (int64, error) {
+	db, err := r.pool()
+	if err != nil {
+		return 0, liberr.Wrap(err)
+	}
+	return r.table(db).Count(model, predicate)
+}
+
+//
+// Get the number of (all) models.
+func (r *Client) Len(model Model) (int64, error) {
+	db, err := r.pool()
+	if err != nil {
+		return 0, liberr.Wrap(err)
+	}
+	return r.table(db).Len(model)
+}
+
+//
+// Get whether there are no models.
+func (r *Client) IsEmpty(model Model) (bool, error) {
+	db, err := r.pool()
+	if err != nil {
+		return false, liberr.Wrap(err)
+	}
+	return r.table(db).IsEmpty(model)
+}
+
+//
+// Count models in the DB grouped by the value of a
+// field. Returns the count by (text) value.
+func (r *Client) CountBy(model Model, field string, predicate Predicate) (map[string]int64, error) {
+	db, err := r.pool()
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	return r.table(db).CountBy(model, field, predicate)
+}
+
+//
+// Count models in the DB grouped by the values
+// of the specified fields.
+func (r *Client) CountByGroup(model Model, group []string, predicate Predicate) ([]GroupCount, error) {
+	db, err := r.pool()
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	return r.table(db).CountByGroup(model, group, predicate)
+}
+
+//
+// Count models in the DB grouped by the values of
+// the specified fields. Supports the `Having` option.
+// See: Table.CountGroups().
+func (r *Client) CountGroups(model Model, group []string, options ListOptions) ([]GroupCount, error) {
+	db, err := r.pool()
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	return r.table(db).CountGroups(model, group, options)
+}
+
+//
+// Get a (single) scalar value of a field in the DB.
+// See: Table.Scalar().
+func (r *Client) Scalar(model Model, function, field string, predicate Predicate) (interface{}, error) {
+	db, err := r.pool()
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	return r.table(db).Scalar(model, function, field, predicate)
+}
+
+//
+// Find duplicate natural keys.
+// Intended to audit models having a natural key which
+// is not enforced unique. See: Table.DuplicateKeys().
+func (r *Client) DuplicateKeys(model Model) ([]GroupCount, error) {
+	db, err := r.pool()
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	return r.table(db).DuplicateKeys(model)
+}
+
+//
+// List the distinct values of a field in the DB.
+// Qualified by the predicate. The values are ordered
+// and typed by the field.
+func (r *Client) DistinctValues(model Model, field string, predicate Predicate) ([]interface{}, error) {
+	db, err := r.pool()
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	return r.table(db).DistinctValues(model, field, predicate)
+}
+
+//
+// Compile a (prepared) list query for the model.
+// See: Query.
+func (r *Client) Compile(model Model, options ListOptions) (*Query, error) {
+	db, err := r.pool()
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	return r.table(db).Compile(model, options)
+}
+
+//
+// Join models in the DB.
+// See: Join.
+func (r *Client) Join(join Join) ([]JoinRow, error) {
+	db, err := r.pool()
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	return r.table(db).Join(join)
+}
+
+//
+// Aggregate a numeric field of models in the DB.
+// The `function` is one of: (SUM, AVG, MIN, MAX).
+// Qualified by the predicate. Returns 0 when no
+// models are matched.
+func (r *Client) Aggregate(model Model, function, field string, predicate Predicate) (float64, error) {
+	db, err := r.pool()
+	if err != nil {
+		return 0, liberr.Wrap(err)
+	}
+	return r.table(db).Aggregate(model, function, field, predicate)
+}
+
+//
+// Export models as a JSON array of objects keyed by
+// column name. Qualified (and sorted) by the list options.
+// Encoded (json) columns are exported as nested objects.
+// Enumerated (int) columns are exported by name.
+// See: Table.ExportJSON().
+func (r *Client) ExportJSON(model Model, w io.Writer, options ListOptions) error {
+	db, err := r.pool()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	return r.table(db).ExportJSON(model, w, options)
+}
+
+//
+// Import models from a JSON array of objects keyed by
+// column name (see: ExportJSON). Each model is inserted or
+// updated (upsert) within a single transaction. Labels are
+// not imported.
+func (r *Client) ImportJSON(model Model, rd io.Reader) (err error) {
+	mt := reflect.TypeOf(model)
+	if mt.Kind() != reflect.Ptr {
+		return liberr.Wrap(MustBePtrErr)
+	}
+	decoder := json.NewDecoder(rd)
+	token, err := decoder.Token()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	if delim, cast := token.(json.Delim); !cast || delim != '[' {
+		return liberr.Wrap(MustBeSlicePtrErr)
+	}
+	tx, err := r.Begin()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	defer tx.Rollback()
+	table := r.table(tx.real)
+	for decoder.More() {
+		values := map[string]json.RawMessage{}
+		err = decoder.Decode(&values)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		m := reflect.New(mt.Elem()).Interface().(Model)
+		err = table.SetColumns(m, values)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		err = tx.Insert(m)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+	}
+	_, err = decoder.Token()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	err = tx.Commit()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+
+	return nil
+}
+
+//
+// Export models as CSV (RFC 4180) with a header row of
+// column names. Qualified (and sorted) by the list options.
+// Encoded (json) columns are exported as json strings.
+// Enumerated (int) columns are exported by name.
+func (r *Client) ExportCSV(model Model, w io.Writer, options ListOptions) error {
+	db, err := r.pool()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	return r.table(db).ExportCSV(model, w, options)
+}
+
+//
+// Import models from CSV (RFC 4180) with a header row of
+// column names (see: ExportCSV). Each model is inserted or
+// updated (upsert) within a single transaction. Labels are
+// not imported.
+func (r *Client) ImportCSV(model Model, rd io.Reader) (err error) {
+	mt := reflect.TypeOf(model)
+	if mt.Kind() != reflect.Ptr {
+		return liberr.Wrap(MustBePtrErr)
+	}
+	reader := csv.NewReader(rd)
+	header, err := reader.Read()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	tx, err := r.Begin()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	defer tx.Rollback()
+	table := r.table(tx.real)
+	for {
+		record, rErr := reader.Read()
+		if rErr != nil {
+			if rErr == io.EOF {
+				break
+			}
+			return liberr.Wrap(rErr)
+		}
+		values := map[string]string{}
+		for i, column := range header {
+			values[column] = record[i]
+		}
+		m := reflect.New(mt.Elem()).Interface().(Model)
+		err = table.SetText(m, values)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		err = tx.Insert(m)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+
+	return nil
+}
+
+//
+// Begin a transaction.
+// Reads within the transaction must use the Tx (Get, List,
+// Count, ...) to see the uncommitted writes. The client
+// has no notion of an active transaction; client reads are
+// isolated and see only committed models.
+// Example:
+//   tx, _ := client.Begin()
+//   defer tx.Rollback()
+//   tx.Insert(model)
+//   tx.Get(model)
+//   tx.Commit()
+func (r *Client) Begin() (*Tx, error) {
+	return r.BeginTx(context.TODO())
+}
+
+//
+// Begin a transaction with context.
+// The transaction is rolled back automatically when the
+// context is cancelled (or the deadline is exceeded) before
+// the transaction has ended. This releases the client and
+// is intended as a safety net for transactions that would
+// otherwise be left open. Once rolled back, Commit() will
+// return TxEndedErr.
+// Example:
+//   ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+//   defer cancel()
+//   tx, _ := client.BeginTx(ctx)
+//   defer tx.Rollback()
+//   tx.Insert(model)
+//   tx.Commit()
+func (r *Client) BeginTx(ctx context.Context) (*Tx, error) {
+	if r.ReadOnly {
+		return nil, liberr.Wrap(ReadOnlyErr)
+	}
+	r.dbMutex.Lock()
+	tx, err := r.begin(ctx)
+	if err != nil {
+		r.dbMutex.Unlock()
+		return nil, liberr.Wrap(err)
+	}
+	tx.dbMutex = &r.dbMutex
+
+	return tx, nil
+}
+
+//
+// Begin a transaction.
+// The caller must hold dbMutex. The mutex is not released
+// when the transaction ends unless Tx.dbMutex is set.
+func (r *Client) begin(ctx context.Context) (*Tx, error) {
+	db, err := r.pool()
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	real, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		conn.Close()
+		return nil, liberr.Wrap(err)
+	}
+	tx := &Tx{
+		labeler: r.labeler,
+		client:  r,
+		journal: &r.journal,
+		conn:    conn,
+		real:    real,
+		ctx:     ctx,
+	}
+	atomic.StoreInt32(&r.txOpen, 1)
+	if r.TraceKey != nil {
+		if id := ctx.Value(r.TraceKey); id != nil {
+			tx.trace = []interface{}{"trace", id}
+		}
+	}
+	if ctx.Done() != nil {
+		tx.done = make(chan struct{})
+		go tx.watch(ctx)
+	}
+
+	return tx, nil
+}
+
+//
+// Insert the model.
+func (r *Client) Insert(model Model) error {
+	return r.write(
+		func(db *sql.DB) error {
+			table := r.table(db)
+			err := table.Insert(model)
+			if err != nil {
+				return liberr.Wrap(err)
+			}
+			err = r.labeler.Insert(table, model)
+			if err != nil {
+				return liberr.Wrap(err)
+			}
+			r.journal.Created(model)
+			r.journal.Commit()
+
+			return nil
+		})
+}
+
+//
+// Update the model.
+func (r *Client) Update(model Model) error {
+	return r.write(
+		func(db *sql.DB) error {
+			table := r.table(db)
+			current := Clone(model)
+			err := table.Get(current)
+			if err != nil {
+				return liberr.Wrap(err)
+			}
+			err = table.Update(model)
+			if err != nil {
+				return liberr.Wrap(err)
+			}
+			err = r.labeler.Replace(table, model)
+			if err != nil {
+				return liberr.Wrap(err)
+			}
+			r.journal.Updated(current, model)
+			r.journal.Commit()
+
+			return nil
+		})
+}
+
+//
+// Touch the model.
+// Only the `touch` field is updated. Watches are
+// not notified.
+func (r *Client) Touch(model Model) error {
+	return r.write(
+		func(db *sql.DB) error {
+			err := r.table(db).Touch(model)
+			if err != nil {
+				return liberr.Wrap(err)
+			}
+
+			return nil
+		})
+}
+
+//
+// Set the value of a key in a `map` field.
+// Only the entry is written. Watches are not notified.
+func (r *Client) MapSet(model Model, field, key, value string) error {
+	return r.write(
+		func(db *sql.DB) error {
+			err := r.table(db).MapSet(model, field, key, value)
+			if err != nil {
+				return liberr.Wrap(err)
+			}
+
+			return nil
+		})
+}
+
+//
+// Get the value of a key in a `map` field.
+func (r *Client) MapGet(model Model, field, key string) (string, bool, error) {
+	db, err := r.pool()
+	if err != nil {
+		return "", false, liberr.Wrap(err)
+	}
+	return r.table(db).MapGet(model, field, key)
+}
+
+//
+// Delete models not touched since the cutoff.
+// The model must have a `touch` field. Intended to delete
+// models not seen (touched, inserted or updated) during a
+// sync. Returns the number of models deleted.
+// Example:
+//   cutoff := time.Now()
+//   // insert, update and touch models seen.
+//   n, err := DB.DeleteStale(&Person{}, cutoff)
+func (r *Client) DeleteStale(model Model, cutoff time.Time) (n int64, err error) {
+	tx, err := r.Begin()
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	defer tx.Rollback()
+	n, err = tx.DeleteStale(model, cutoff)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+
+	return
+}
+
+//
+// Delete the model.
+func (r *Client) Delete(model Model) error {
+	return r.write(
+		func(db *sql.DB) error {
+			table := r.table(db)
+			err := table.Delete(model)
+			if err != nil {
+				return liberr.Wrap(err)
+			}
+			err = r.labeler.Delete(table, model)
+			if err != nil {
+				return liberr.Wrap(err)
+			}
+			r.journal.Deleted(model)
+			r.journal.Commit()
+
+			return nil
+		})
+}
+
+//
+// Enable/disable foreign key enforcement.
+// Disabling enforcement supports bulk loading models
+// in arbitrary (dependency) order. While disabled, the DB
+// will accept references to models that do not exist and
+// will not cascade deletes. Such inconsistencies are NOT
+// detected when enforcement is enabled again.
+// The setting is applied to each (pooled) connection. Idle
+// connections are closed so they are replaced by connections
+// using the new setting. Connections in use (reads in progress)
+// keep the previous setting until returned to the pool, where
+// they are discarded. Waits for open transactions to end.
+func (r *Client) SetForeignKeys(enabled bool) error {
+	r.dbMutex.Lock()
+	defer r.dbMutex.Unlock()
+	db, err := r.pool()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	r.connector.setForeignKeys(enabled)
+	r.recycle(db)
+
+	return nil
+}
+
+//
+// Set a pragma.
+// Many pragmas (example: cache_size, mmap_size, temp_store)
+// are per-connection. The pragma is applied to each (pooled)
+// connection. Idle connections are closed so they are replaced
+// by connections using the pragma. Waits for open transactions
+// to end. Connections in use (reads in progress) are not updated
+// until returned to the pool, where they are discarded.
+// Pragmas persisted in the DB file (example: user_version)
+// are also re-applied on each new connection.
+// The pragma is first applied on a single connection and an
+// error is returned when it fails.
+func (r *Client) SetPragma(name, value string) error {
+	if !PragmaRegex.MatchString(name) || !PragmaValueRegex.MatchString(value) {
+		return liberr.Wrap(PragmaErr)
+	}
+	if r.ReadOnly {
+		return liberr.Wrap(ReadOnlyErr)
+	}
+	r.dbMutex.Lock()
+	defer r.dbMutex.Unlock()
+	db, err := r.pool()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	conn, err := db.Conn(context.TODO())
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	defer conn.Close()
+	_, err = conn.ExecContext(context.TODO(), "PRAGMA "+name+" = "+value)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	r.connector.setPragma(name, value)
+	r.recycle(db)
+
+	return nil
+}
+
+//
+// Get a pragma (value).
+// Queried on a (pooled) connection. See: SetPragma().
+// Returns "" when the pragma has no value.
+func (r *Client) GetPragma(name string) (string, error) {
+	if !PragmaRegex.MatchString(name) {
+		return "", liberr.Wrap(PragmaErr)
+	}
+	db, err := r.pool()
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+	value := sql.NullString{}
+	err = db.QueryRow("PRAGMA " + name).Scan(&value)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
+		}
+		return "", liberr.Wrap(err)
+	}
+
+	return value.String, nil
+}
+
+//
+// The SQLite limits.
+// Reported by the SQLite library and used to reject
+// predicates (before execution) and chunk bulk operations.
+func (r *Client) Limits() (Limits, error) {
+	db, err := r.pool()
+	if err != nil {
+		return Limits{}, liberr.Wrap(err)
+	}
+	err = db.Ping()
+	if err != nil {
+		return Limits{}, liberr.Wrap(err)
+	}
+
+	return r.connector.getLimits(), nil
+}
+
+//
+// Set (replace) the keyring used for encrypted fields.
+// Supports key rotation. Rows encrypted using a key no
+// longer in the keyring cannot be decrypted. See: Keyring.
+func (r *Client) SetKeyring(keyring *Keyring) {
+	r.stateMutex.Lock()
+	defer r.stateMutex.Unlock()
+	r.Keyring = keyring
+}
+
+//
+// Attach a database.
+// The `models` are bound to the attached database using
+// the `alias` as the schema. Their tables (and indexes) are
+// created in the attached database and referenced using the
+// qualified name: `alias.Table`. Models in the main and
+// attached databases may be queried together (example:
+// the Exists predicate). Foreign keys must reference tables
+// in the same database.
+// The database is attached to each (pooled) connection.
+// Idle connections are closed so they are replaced by
+// connections with the database attached. Connections in
+// use (reads in progress) are discarded when returned to
+// the pool. Should be called when reads are not in progress
+// since they cannot reference the attached database.
+func (r *Client) Attach(alias, path string, models ...interface{}) error {
+	if !AliasRegex.MatchString(alias) {
+		return liberr.Wrap(AliasErr)
+	}
+	if r.ReadOnly {
+		return liberr.Wrap(ReadOnlyErr)
+	}
+	r.dbMutex.Lock()
+	defer r.dbMutex.Unlock()
+	db, err := r.pool()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	r.connector.attach(alias, path)
+	r.recycle(db)
+	r.stateMutex.Lock()
+	kinds := map[string]reflect.Type{}
+	for k, v := range r.kinds {
+		kinds[k] = v
+	}
+	schemas := map[string]string{}
+	for k, v := range r.schemas {
+		schemas[k] = v
+	}
+	for _, m := range models {
+		mt := reflect.TypeOf(m)
+		if mt.Kind() == reflect.Ptr {
+			mt = mt.Elem()
+		}
+		kinds[mt.Name()] = mt
+		schemas[mt.Name()] = alias
+	}
+	r.kinds = kinds
+	r.schemas = schemas
+	r.stateMutex.Unlock()
+	for _, m := range models {
+		ddl, err := r.table(db).DDL(m)
+		if err != nil {
+			return err
+		}
+		for _, stmt := range ddl {
+			_, err = db.Exec(stmt)
+			if err != nil {
+				return liberr.Wrap(err)
+			}
+		}
+	}
+
+	return nil
+}
+
+//
+// Detach a database.
+// Models bound to the database are unbound.
+// See: Attach().
+func (r *Client) Detach(alias string) error {
+	r.dbMutex.Lock()
+	defer r.dbMutex.Unlock()
+	db, err := r.pool()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	r.connector.detach(alias)
+	r.recycle(db)
+	r.stateMutex.Lock()
+	schemas := map[string]string{}
+	for k, v := range r.schemas {
+		if v != alias {
+			schemas[k] = v
+		}
+	}
+	r.schemas = schemas
+	r.stateMutex.Unlock()
+
+	return nil
+}
+
+//
+// Close idle (pooled) connections so they are replaced
+// by connections using the current connector configuration.
+func (r *Client) recycle(db *sql.DB) {
+	db.SetMaxIdleConns(0)
+	db.SetMaxIdleConns(MaxIdleConns)
+}
+
+//
+// Gather query planner statistics for the model.
+// Should be called after a bulk load (or periodically) so
+// the query planner can choose the best indexes.
+func (r *Client) Analyze(model Model) error {
+	if r.ReadOnly {
+		return liberr.Wrap(ReadOnlyErr)
+	}
+	r.dbMutex.Lock()
+	defer r.dbMutex.Unlock()
+	db, err := r.pool()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	return r.table(db).Analyze(model)
+}
+
+//
+// Gather query planner statistics for all models.
+// See: Analyze().
+func (r *Client) AnalyzeAll() error {
+	if r.ReadOnly {
+		return liberr.Wrap(ReadOnlyErr)
+	}
+	r.dbMutex.Lock()
+	defer r.dbMutex.Unlock()
+	db, err := r.pool()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	return r.table(db).Analyze(nil)
+}
+
+//
+// Rebuild the indexes for the model.
+func (r *Client) Reindex(model Model) error {
+	if r.ReadOnly {
+		return liberr.Wrap(ReadOnlyErr)
+	}
+	r.dbMutex.Lock()
+	defer r.dbMutex.Unlock()
+	db, err := r.pool()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	return r.table(db).Reindex(model)
+}
+
+//
+// Get query planner statistics for the model.
+// Empty until analyzed.
+func (r *Client) PlannerStats(model Model) ([]PlannerStat, error) {
+	db, err := r.pool()
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	return r.table(db).PlannerStats(model)
+}
+
+//
+// Describe the schema.
+// Returns a description of the table for each model.
+func (r *Client) Describe() ([]TableSchema, error) {
+	list := []TableSchema{}
+	for _, m := range r.schema() {
+		schema, err := r.table(nil).Describe(m)
+		if err != nil {
+			return nil, liberr.Wrap(err)
+		}
+		list = append(list, *schema)
+	}
+
+	return list, nil
+}
+
+//
+// Watch model events.
+func (r *Client) Watch(model Model, handler EventHandler) (*Watch, error) {
+	mt := reflect.TypeOf(model)
+	switch mt.Kind() {
+	case reflect.Ptr:
+		mt = mt.Elem()
+	}
+	db, err := r.pool()
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	watch, err := r.journal.Watch(model, handler)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	listPtr := reflect.New(reflect.SliceOf(mt))
+	err = r.table(db).List(listPtr.Interface(), ListOptions{})
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	list := listPtr.Elem()
+	watch.Start(&list)
+
+	return watch, nil
+}
+
+//
+// End watch.
+func (r *Client) EndWatch(watch *Watch) {
+	r.journal.End(watch)
+}
+
+//
+// Get the models in the schema.
+// Includes the Label model unless disabled.
+func (r *Client) schema() []interface{} {
+	all := append([]interface{}{}, r.models...)
+	if !r.DisableLabels {
+		all = append(all, &Label{})
+	}
+	models := []interface{}{}
+	found := map[reflect.Type]bool{}
+	for _, m := range all {
+		mt := r.kind(m)
+		if found[mt] {
+			continue
+		}
+		found[mt] = true
+		models = append(models, m)
+	}
+
+	return models
+}
+
+//
+// Get the (struct) type of a model.
+func (r *Client) kind(model interface{}) reflect.Type {
+	mt := reflect.TypeOf(model)
+	if mt.Kind() == reflect.Ptr {
+		mt = mt.Elem()
+	}
+
+	return mt
+}
+
+//
+// Build the DDL for the specified models.
+func (r *Client) ddl(db DBTX, models []interface{}) ([]string, error) {
+	statements := []string{}
+	for _, m := range models {
+		ddl, err := r.table(db).DDL(m)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, ddl...)
+	}
+
+	return statements, nil
+}
+
+//
+// Get the DB connection (pool).
+// Returns ClosedErr when not open.
+func (r *Client) pool() (*sql.DB, error) {
+	r.stateMutex.RLock()
+	defer r.stateMutex.RUnlock()
+	if r.db == nil {
+		return nil, liberr.Wrap(ClosedErr)
+	}
+
+	return r.db, nil
+}
+
+//
+// Get the statement logger.
+// Nil when statements are not logged.
+func (r *Client) statementLog() Logger {
+	if r.LogStatements {
+		return r.log()
+	}
+
+	return nil
+}
+
+//
+// Get the logger.
+func (r *Client) log() Logger {
+	if r.Log != nil {
+		return r.Log
+	}
+
+	return NopLogger{}
+}
+
+//
+// Build a table using the client configuration.
+func (r *Client) table(db DBTX) Table {
+	r.stateMutex.RLock()
+	defer r.stateMutex.RUnlock()
+	return Table{
+		DB:           db,
+		Namer:        r.Namer,
+		Hasher:       r.Hasher,
+		Encoder:      r.Encoder,
+		Decoder:      r.Decoder,
+		Positional:   r.Positional,
+		MaxPageLimit: r.MaxPageLimit,
+		MaxListRows:  r.MaxListRows,
+		StrictBool:   r.StrictBool,
+		BoolText:     r.BoolText,
+		Transforms:   r.Transforms,
+		Interceptor:  r.Interceptor,
+		Log:          r.statementLog(),
+		Limits:       r.connector.getLimits(),
+		kinds:        r.kinds,
+		schemas:      r.schemas,
+		keyring:      r.Keyring,
+		counter:      r.statements,
+	}
+}
 
-	return nil
+//
+// Database connector.
+// Each (pooled) connection is configured using pragmas.
+type connector struct {
+	mutex sync.RWMutex
+	// sqlite3 driver.
+	driver sqlite3.SQLiteDriver
+	// file path.
+	path string
+	// Foreign keys enforced.
+	foreignKeys bool
+	// Open the file read-only.
+	readOnly bool
+	// Write-ahead log (WAL) journal mode.
+	wal bool
+	// Attached databases (path) by alias.
+	attached map[string]string
+	// Pragmas (value) by name.
+	pragma map[string]string
+	// SQLite limits reported by the first connection.
+	limits Limits
+	// Configuration generation.
+	// Incremented when the configuration is changed.
+	generation uint64
 }
 
 //
-// Close the database.
-// Optionally purge (delete) the DB.
-func (r *Client) Close(purge bool) error {
-	if r.db == nil {
-		return nil
+// Open and configure a connection.
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	dsn := c.path
+	if c.readOnly {
+		path := url.URL{Path: c.path}
+		dsn = "file:" + path.EscapedPath() + "?mode=ro"
 	}
-	err := r.db.Close()
+	generation := c.current()
+	conn, err := c.driver.Open(dsn)
 	if err != nil {
-		return liberr.Wrap(err)
+		return nil, liberr.Wrap(err)
 	}
-	r.db = nil
-	if purge {
-		os.Remove(r.path)
+	c.setLimits(conn.(*sqlite3.SQLiteConn))
+	for _, pragma := range c.pragmas() {
+		_, err = conn.(*sqlite3.SQLiteConn).Exec(pragma, nil)
+		if err != nil {
+			conn.Close()
+			return nil, liberr.Wrap(err)
+		}
+	}
+	for alias, path := range c.attachments() {
+		_, err = conn.(*sqlite3.SQLiteConn).Exec(
+			"ATTACH DATABASE ? AS "+alias,
+			[]driver.Value{path})
+		if err != nil {
+			conn.Close()
+			return nil, liberr.Wrap(err)
+		}
 	}
 
-	return nil
+	pooled := &pooledConn{
+		SQLiteConn: conn.(*sqlite3.SQLiteConn),
+		connector:  c,
+		generation: generation,
+	}
+
+	return pooled, nil
 }
 
 //
-// Get the model.
-func (r *Client) Get(model Model) error {
-	return Table{r.db}.Get(model)
+// The driver.
+func (c *connector) Driver() driver.Driver {
+	return &c.driver
 }
 
 //
-// List models.
-// The `list` must be: *[]Model.
-func (r *Client) List(list interface{}, options ListOptions) error {
-	return Table{r.db}.List(list, options)
+// Pooled connection.
+// Configured using the connector generation (at connect).
+type pooledConn struct {
+	*sqlite3.SQLiteConn
+	// Connector.
+	connector *connector
+	// Configuration generation.
+	generation uint64
 }
 
 //
-// Count models.
-func (r *Client) Count(model Model, predicate Predicate) (int64, error) {
-	return Table{r.db}.Count(model, predicate)
+// The connection is valid (reusable) when configured by the
+// current connector generation. Called by the pool when the
+// connection is returned. Stale connections are discarded.
+func (c *pooledConn) IsValid() bool {
+	return c.generation == c.connector.current()
 }
 
 //
-// Begin a transaction.
-// Example:
-//   tx, _ := client.Begin()
-//   defer tx.End()
-//   client.Insert(model)
-//   client.Insert(model)
-//   tx.Commit()
-func (r *Client) Begin() (*Tx, error) {
-	r.dbMutex.Lock()
-	real, err := r.db.Begin()
-	if err != nil {
-		return nil, err
+// Record the SQLite limits reported by the connection.
+func (c *connector) setLimits(conn *sqlite3.SQLiteConn) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.limits != (Limits{}) {
+		return
 	}
-	tx := &Tx{
-		dbMutex: &r.dbMutex,
-		journal: &r.journal,
-		real:    real,
+	c.limits = Limits{
+		Variables: conn.GetLimit(sqlite3.SQLITE_LIMIT_VARIABLE_NUMBER),
+		ExprDepth: conn.GetLimit(sqlite3.SQLITE_LIMIT_EXPR_DEPTH),
 	}
-
-	return tx, nil
 }
 
 //
-// Insert the model.
-func (r *Client) Insert(model Model) error {
-	r.dbMutex.Lock()
-	defer r.dbMutex.Unlock()
-	table := Table{r.db}
-	err := table.Insert(model)
-	if err != nil {
-		return liberr.Wrap(err)
-	}
-	err = r.labeler.Insert(table, model)
-	if err != nil {
-		return liberr.Wrap(err)
+// The SQLite limits.
+func (c *connector) getLimits() Limits {
+	if c == nil {
+		return Limits{}
 	}
-	r.journal.Created(model)
-	r.journal.Commit()
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.limits
+}
 
-	return nil
+//
+// Enable/disable foreign keys.
+func (c *connector) setForeignKeys(enabled bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.foreignKeys = enabled
+	c.generation++
 }
 
 //
-// Update the model.
-func (r *Client) Update(model Model) error {
-	r.dbMutex.Lock()
-	defer r.dbMutex.Unlock()
-	table := Table{r.db}
-	current := Clone(model)
-	err := table.Get(current)
-	if err != nil {
-		return liberr.Wrap(err)
-	}
-	err = table.Update(model)
-	if err != nil {
-		return liberr.Wrap(err)
-	}
-	err = r.labeler.Replace(table, model)
-	if err != nil {
-		return liberr.Wrap(err)
-	}
-	r.journal.Updated(current, model)
-	r.journal.Commit()
+// The configuration generation.
+func (c *connector) current() uint64 {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.generation
+}
 
-	return nil
+//
+// Attach a database.
+func (c *connector) attach(alias, path string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	attached := map[string]string{}
+	for k, v := range c.attached {
+		attached[k] = v
+	}
+	attached[alias] = path
+	c.attached = attached
+	c.generation++
 }
 
 //
-// Delete the model.
-func (r *Client) Delete(model Model) error {
-	r.dbMutex.Lock()
-	defer r.dbMutex.Unlock()
-	table := Table{r.db}
-	err := table.Delete(model)
-	if err != nil {
-		return liberr.Wrap(err)
+// Detach a database.
+func (c *connector) detach(alias string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	attached := map[string]string{}
+	for k, v := range c.attached {
+		if k != alias {
+			attached[k] = v
+		}
 	}
-	err = r.labeler.Delete(table, model)
-	if err != nil {
-		return liberr.Wrap(err)
+	c.attached = attached
+	c.generation++
+}
+
+//
+// Set a pragma.
+func (c *connector) setPragma(name, value string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	pragma := map[string]string{}
+	for k, v := range c.pragma {
+		pragma[k] = v
 	}
-	r.journal.Deleted(model)
-	r.journal.Commit()
+	pragma[name] = value
+	c.pragma = pragma
+	c.generation++
+}
 
-	return nil
+//
+// Databases attached on connect.
+func (c *connector) attachments() map[string]string {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.attached
 }
 
 //
-// Watch model events.
-func (r *Client) Watch(model Model, handler EventHandler) (*Watch, error) {
-	mt := reflect.TypeOf(model)
-	switch mt.Kind() {
-	case reflect.Ptr:
-		mt = mt.Elem()
+// Pragmas applied on connect.
+func (c *connector) pragmas() (list []string) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if c.foreignKeys {
+		list = append(list, Pragma)
+	} else {
+		list = append(list, PragmaFkOff)
 	}
-	watch, err := r.journal.Watch(model, handler)
-	if err != nil {
-		return nil, liberr.Wrap(err)
+	if c.wal {
+		list = append(list, PragmaWAL)
 	}
-	listPtr := reflect.New(reflect.SliceOf(mt))
-	err = Table{r.db}.List(listPtr.Interface(), ListOptions{})
-	if err != nil {
-		return nil, liberr.Wrap(err)
+	names := []string{}
+	for name := range c.pragma {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		list = append(list, "PRAGMA "+name+" = "+c.pragma[name])
 	}
-	list := listPtr.Elem()
-	watch.Start(&list)
-
-	return watch, nil
-}
 
-//
-// End watch.
-func (r *Client) EndWatch(watch *Watch) {
-	r.journal.End(watch)
+	return
 }
 
 //
 // Database transaction.
+// Reads (Get, List, Count, ...) are performed within the
+// transaction and see its uncommitted writes.
 type Tx struct {
 	labeler Labeler
 	// Associated client.
+	client *Client
+	// Client mutex (released when ended).
 	dbMutex *sync.Mutex
 	// Journal
 	journal *Journal
+	// Dedicated connection.
+	conn *sql.Conn
 	// Reference to real sql.Tx.
 	real *sql.Tx
+	// Protects ended.
+	mutex sync.Mutex
 	// Ended
 	ended bool
+	// Closed when ended.
+	done chan struct{}
+	// Trace (key/value) included in the
+	// statement log entries.
+	trace []interface{}
+	// Context passed to BeginTx().
+	ctx context.Context
+}
+
+//
+// Build a table using the transaction.
+func (r *Tx) table() Table {
+	t := r.client.table(r.real)
+	t.values = r.trace
+	t.ctx = r.ctx
+	return t
 }
 
 //
 // Get the model.
 func (r *Tx) Get(model Model) error {
-	return Table{r.real}.Get(model)
+	return r.table().Get(model)
+}
+
+//
+// Get the model fields matching the detail level.
+// See: Client.GetDetail().
+func (r *Tx) GetDetail(model Model, detail int) error {
+	return r.table().GetDetail(model, detail)
+}
+
+//
+// Get the model by (SQLite) rowid.
+// See: Client.GetByRowID().
+func (r *Tx) GetByRowID(model Model, rowid int64) error {
+	return r.table().GetByRowID(model, rowid)
+}
+
+//
+// Get models by PK.
+// See: Client.GetMany().
+func (r *Tx) GetMany(model Model, pks []string, list interface{}) error {
+	return r.table().GetMany(model, pks, list)
 }
 
 //
 // List models.
 // The `list` must be: *[]Model.
 func (r *Tx) List(list interface{}, options ListOptions) error {
-	return Table{r.real}.List(list, options)
+	return r.table().List(list, options)
+}
+
+//
+// List models and report whether more exist beyond the page.
+func (r *Tx) ListMore(list interface{}, options ListOptions) (bool, error) {
+	return r.table().ListMore(list, options)
+}
+
+//
+// List models (keyset paged) following the cursor.
+func (r *Tx) ListCursor(list interface{}, options ListOptions, token string) (string, error) {
+	return r.table().ListCursor(list, options, token)
+}
+
+//
+// Search (full-text) models.
+func (r *Tx) Search(list interface{}, query string, options ListOptions) error {
+	return r.table().Search(list, query, options)
 }
 
 //
 // Count models.
 func (r *Tx) Count(model Model, predicate Predicate) (int64, error) {
-	return Table{r.real}.Count(model, predicate)
+	return r.table().Count(model, predicate)
+}
+
+//
+// Get the number of (all) models.
+func (r *Tx) Len(model Model) (int64, error) {
+	return r.table().Len(model)
+}
+
+//
+// Get whether there are no models.
+func (r *Tx) IsEmpty(model Model) (bool, error) {
+	return r.table().IsEmpty(model)
+}
+
+//
+// Count models grouped by the value of a field.
+func (r *Tx) CountBy(model Model, field string, predicate Predicate) (map[string]int64, error) {
+	return r.table().CountBy(model, field, predicate)
+}
+
+//
+// Count models grouped by the values of fields.
+func (r *Tx) CountByGroup(model Model, group []string, predicate Predicate) ([]GroupCount, error) {
+	return r.table().CountByGroup(model, group, predicate)
+}
+
+//
+// Count models grouped by the values of fields.
+// See: Client.CountGroups().
+func (r *Tx) CountGroups(model Model, group []string, options ListOptions) ([]GroupCount, error) {
+	return r.table().CountGroups(model, group, options)
+}
+
+//
+// Get a (single) scalar value of a field.
+// See: Client.Scalar().
+func (r *Tx) Scalar(model Model, function, field string, predicate Predicate) (interface{}, error) {
+	return r.table().Scalar(model, function, field, predicate)
+}
+
+//
+// Find duplicate natural keys.
+func (r *Tx) DuplicateKeys(model Model) ([]GroupCount, error) {
+	return r.table().DuplicateKeys(model)
+}
+
+//
+// List the distinct values of a field.
+func (r *Tx) DistinctValues(model Model, field string, predicate Predicate) ([]interface{}, error) {
+	return r.table().DistinctValues(model, field, predicate)
+}
+
+//
+// Compile a (prepared) list query.
+// The query may only be run within the transaction.
+func (r *Tx) Compile(model Model, options ListOptions) (*Query, error) {
+	return r.table().Compile(model, options)
+}
+
+//
+// Join models.
+func (r *Tx) Join(join Join) ([]JoinRow, error) {
+	return r.table().Join(join)
+}
+
+//
+// Aggregate a numeric field.
+func (r *Tx) Aggregate(model Model, function, field string, predicate Predicate) (float64, error) {
+	return r.table().Aggregate(model, function, field, predicate)
+}
+
+//
+// Execute a (raw) SQL statement.
+// Intended for schema migrations.
+func (r *Tx) Exec(stmt string, params ...interface{}) (sql.Result, error) {
+	result, err := r.real.Exec(stmt, params...)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+
+	return result, nil
 }
 
 //
 // Insert the model.
 func (r *Tx) Insert(model Model) error {
-	table := Table{r.real}
+	table := r.table()
 	err := table.Insert(model)
 	if err != nil {
 		return liberr.Wrap(err)
@@ -298,7 +2105,7 @@ func (r *Tx) Insert(model Model) error {
 //
 // Update the model.
 func (r *Tx) Update(model Model) error {
-	table := Table{r.real}
+	table := r.table()
 	current := Clone(model)
 	err := table.Get(current)
 	if err != nil {
@@ -317,10 +2124,78 @@ func (r *Tx) Update(model Model) error {
 	return nil
 }
 
+//
+// Touch the model.
+func (r *Tx) Touch(model Model) error {
+	err := r.table().Touch(model)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+
+	return nil
+}
+
+//
+// Set the value of a key in a `map` field.
+func (r *Tx) MapSet(model Model, field, key, value string) error {
+	err := r.table().MapSet(model, field, key, value)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+
+	return nil
+}
+
+//
+// Get the value of a key in a `map` field.
+func (r *Tx) MapGet(model Model, field, key string) (string, bool, error) {
+	return r.table().MapGet(model, field, key)
+}
+
+//
+// Delete models not touched since the cutoff.
+func (r *Tx) DeleteStale(model Model, cutoff time.Time) (int64, error) {
+	table := r.table()
+	fields, err := table.Fields(model)
+	if err != nil {
+		return 0, liberr.Wrap(err)
+	}
+	touch := table.TouchField(fields)
+	if touch == nil {
+		return 0, liberr.Wrap(TouchErr)
+	}
+	deleted := reflect.New(reflect.SliceOf(r.client.kind(model)))
+	if r.journal.Watched(model) {
+		err = table.List(
+			deleted.Interface(),
+			ListOptions{
+				Detail:    DetailAll,
+				Predicate: Lt(touch.Name, cutoff.UnixNano()),
+			})
+		if err != nil {
+			return 0, liberr.Wrap(err)
+		}
+	}
+	err = r.labeler.DeleteStale(table, model, cutoff)
+	if err != nil {
+		return 0, liberr.Wrap(err)
+	}
+	n, err := table.DeleteStale(model, cutoff)
+	if err != nil {
+		return 0, liberr.Wrap(err)
+	}
+	deleted = deleted.Elem()
+	for i := 0; i < deleted.Len(); i++ {
+		r.journal.Deleted(deleted.Index(i).Addr().Interface().(Model))
+	}
+
+	return n, nil
+}
+
 //
 // Delete the model.
 func (r *Tx) Delete(model Model) error {
-	table := Table{r.real}
+	table := r.table()
 	err := table.Delete(model)
 	if err != nil {
 		return liberr.Wrap(err)
@@ -337,17 +2212,23 @@ func (r *Tx) Delete(model Model) error {
 //
 // Commit a transaction.
 // Staged changes are committed in the DB.
-// This will end the transaction.
+// This will end the transaction (even when the commit
+// fails). Returns TxEndedErr when already ended.
 func (r *Tx) Commit() (err error) {
-	if r.ended {
+	if !r.end() {
+		err = liberr.Wrap(TxEndedErr)
 		return
 	}
-	defer func() {
-		r.dbMutex.Unlock()
-		r.ended = true
-	}()
+	defer r.release()
 	err = r.real.Commit()
 	if err != nil {
+		// The transaction remains open when the
+		// commit fails (example: deferred FK).
+		_, rbErr := r.conn.ExecContext(context.TODO(), "ROLLBACK")
+		if rbErr != nil {
+			r.client.log().Error(rbErr, "Rollback (failed commit) failed.")
+		}
+		r.journal.Unstage()
 		err = liberr.Wrap(err)
 		return
 	}
@@ -360,34 +2241,85 @@ func (r *Tx) Commit() (err error) {
 //
 // End a transaction.
 // Staged changes are discarded.
-// See: Commit().
+// See: Rollback().
 func (r *Tx) End() (err error) {
-	if r.ended {
+	return r.Rollback()
+}
+
+//
+// Rollback a transaction.
+// Staged changes are discarded.
+// This will end the transaction. Has no effect
+// when the transaction has already ended.
+// See: Commit().
+func (r *Tx) Rollback() (err error) {
+	if !r.end() {
 		return
 	}
-	defer func() {
-		r.dbMutex.Unlock()
-		r.ended = true
-	}()
+	defer r.release()
+	r.journal.Unstage()
 	err = r.real.Rollback()
 	if err != nil {
 		err = liberr.Wrap(err)
 		return
 	}
 
-	r.journal.Unstage()
-
 	return
 }
 
+//
+// Mark the transaction ended.
+// Returns false when already ended. Only the caller
+// that ends the transaction may release it.
+func (r *Tx) end() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.ended {
+		return false
+	}
+	r.ended = true
+	if r.done != nil {
+		close(r.done)
+	}
+	return true
+}
+
+//
+// Watch the context.
+// Rollback when the context is done before
+// the transaction has ended.
+func (r *Tx) watch(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		_ = r.Rollback()
+	case <-r.done:
+	}
+}
+
+//
+// Release the dedicated connection and the client
+// mutex acquired by Begin().
+func (r *Tx) release() {
+	_ = r.conn.Close()
+	atomic.StoreInt32(&r.client.txOpen, 0)
+	if r.dbMutex != nil {
+		r.dbMutex.Unlock()
+	}
+}
+
 //
 // Labeler.
 type Labeler struct {
+	// Labels disabled.
+	Disabled bool
 }
 
 //
 // Insert labels for the model into the DB.
 func (r *Labeler) Insert(table Table, model Model) error {
+	if r.Disabled {
+		return nil
+	}
 	for l, v := range model.Labels() {
 		label := &Label{
 			Parent: model.Pk(),
@@ -407,6 +2339,9 @@ func (r *Labeler) Insert(table Table, model Model) error {
 //
 // Delete labels for a model in the DB.
 func (r *Labeler) Delete(table Table, model Model) error {
+	if r.Disabled {
+		return nil
+	}
 	list := []Label{}
 	err := table.List(
 		&list,
@@ -0,0 +1,62 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+//
+// Encoded field (json) encoder.
+// Used to encode (struct, slice, map) fields.
+type Encoder interface {
+	// Encode the object.
+	Encode(object interface{}) ([]byte, error)
+}
+
+//
+// Encoded field (json) decoder.
+// Used to decode (struct, slice, map) fields.
+type Decoder interface {
+	// Decode the encoded object.
+	Decode(encoded []byte, object interface{}) error
+}
+
+//
+// JSON (encoding/json) encoder.
+// The zero value is equivalent to json.Marshal().
+type JSONEncoder struct {
+	// Indent (nested) elements using the indent.
+	// Intended for human readable columns.
+	Indent string
+	// Disable escaping of HTML characters (<, >, &).
+	// Intended for columns containing URLs.
+	DisableEscapeHTML bool
+}
+
+//
+// Encode the object.
+func (e JSONEncoder) Encode(object interface{}) ([]byte, error) {
+	if e.Indent == "" && !e.DisableEscapeHTML {
+		return json.Marshal(object)
+	}
+	bfr := &bytes.Buffer{}
+	encoder := json.NewEncoder(bfr)
+	encoder.SetIndent("", e.Indent)
+	encoder.SetEscapeHTML(!e.DisableEscapeHTML)
+	err := encoder.Encode(object)
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimRight(bfr.Bytes(), "\n"), nil
+}
+
+//
+// JSON (encoding/json) decoder.
+type JSONDecoder struct{}
+
+//
+// Decode the encoded object.
+func (d JSONDecoder) Decode(encoded []byte, object interface{}) error {
+	return json.Unmarshal(encoded, object)
+}
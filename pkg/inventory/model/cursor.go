@@ -0,0 +1,283 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"reflect"
+	"strings"
+)
+
+//
+// Errors
+var (
+	// Cursor (options) error.
+	CursorErr = errors.New("cursor must be a valid token and list paged (no offset, nulls or OR filter) by plain fields")
+	// Cursor query shape error.
+	CursorShapeErr = errors.New("cursor does not match the query (model, detail, sort, filter or predicate)")
+)
+
+//
+// List cursor (continuation token).
+// Self-contained; the sort key and PK of the last listed
+// model, the sort spec and the (fingerprint of the) query
+// shape. May be persisted and used to resume the list in
+// another process. See: Table.ListCursor().
+type Cursor struct {
+	// Query shape (fingerprint).
+	Shape string `json:"shape"`
+	// Sort (key) field names. The PK is last.
+	Sort []string `json:"sort"`
+	// Sort (key) values of the last listed model.
+	Values []json.RawMessage `json:"values"`
+}
+
+//
+// Encode the cursor as an (opaque) token.
+func EncodeCursor(cursor *Cursor) (string, error) {
+	b, err := json.Marshal(cursor)
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+//
+// Decode the token into a cursor.
+// Returns CursorErr when the token is not valid.
+func DecodeCursor(token string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, liberr.Wrap(CursorErr)
+	}
+	cursor := &Cursor{}
+	err = json.Unmarshal(b, cursor)
+	if err != nil || len(cursor.Sort) == 0 || len(cursor.Sort) != len(cursor.Values) {
+		return nil, liberr.Wrap(CursorErr)
+	}
+
+	return cursor, nil
+}
+
+//
+// List models (keyset paged) following the cursor.
+// The `list` must be: *[]Model. The `token` is the cursor
+// returned by the previous call; "" for the first page. The
+// page limit is required and the offset must be 0. Models are
+// ordered by the sort fields and then the PK (by PK when no
+// sort is specified). Returns the cursor (token) for the next
+// page; "" when no models remain. Returns CursorShapeErr when
+// the cursor does not match the (current) query.
+// Example:
+//   options := ListOptions{Page: &Page{Limit: 100}, Sort: []int{2}}
+//   next, err := DB.ListCursor(&persons, options, "")
+//   ...
+//   next, err = DB.ListCursor(&persons, options, next)
+func (t Table) ListCursor(list interface{}, options ListOptions, token string) (next string, err error) {
+	lt := reflect.TypeOf(list)
+	if lt.Kind() != reflect.Ptr || lt.Elem().Kind() != reflect.Slice {
+		err = liberr.Wrap(MustBeSlicePtrErr)
+		return
+	}
+	if options.Page == nil ||
+		options.Page.Offset != 0 ||
+		len(options.Nulls) > 0 ||
+		(options.Filter != nil && options.Combine == CombineOr) {
+		err = liberr.Wrap(CursorErr)
+		return
+	}
+	model := reflect.New(lt.Elem().Elem()).Interface()
+	options = options.Clone()
+	options.DisableTieBreak = false
+	t.detail(model, &options)
+	fields, err := t.Fields(model)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	keys, err := t.keys(fields, &options)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	shape, err := t.shape(model, fields, options)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	if token != "" {
+		cursor, dErr := DecodeCursor(token)
+		if dErr != nil {
+			err = liberr.Wrap(dErr)
+			return
+		}
+		names := []string{}
+		for _, f := range keys {
+			names = append(names, f.Name)
+		}
+		if cursor.Shape != shape || strings.Join(cursor.Sort, ",") != strings.Join(names, ",") {
+			err = liberr.Wrap(CursorShapeErr)
+			return
+		}
+		keyset := &keysetPredicate{}
+		for i, f := range keys {
+			v := reflect.New(f.Value.Type())
+			dErr = json.Unmarshal(cursor.Values[i], v.Interface())
+			if dErr != nil {
+				err = liberr.Wrap(CursorErr)
+				return
+			}
+			keyset.Fields = append(keyset.Fields, f.Name)
+			keyset.Values = append(keyset.Values, v.Elem().Interface())
+		}
+		if options.Predicate != nil {
+			options.Predicate = And(options.Predicate, keyset)
+		} else {
+			options.Predicate = keyset
+		}
+	}
+	hasMore, err := t.ListMore(list, options)
+	if err != nil || !hasMore {
+		err = liberr.Wrap(err)
+		return
+	}
+	lv := reflect.ValueOf(list).Elem()
+	last := lv.Index(lv.Len() - 1).Addr().Interface()
+	fields, err = t.Fields(last)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	cursor := &Cursor{Shape: shape}
+	for _, key := range keys {
+		f, _ := t.find(key.Name, fields)
+		b, mErr := json.Marshal(f.Value.Interface())
+		if mErr != nil {
+			err = liberr.Wrap(mErr)
+			return
+		}
+		cursor.Sort = append(cursor.Sort, f.Name)
+		cursor.Values = append(cursor.Values, b)
+	}
+	next, err = EncodeCursor(cursor)
+
+	return
+}
+
+//
+// Get the (keyset) sort key fields.
+// The sorted fields followed by the PK. The PK is sorted
+// when no sort is specified. Sorted fields must be plain
+// (not encoded or encrypted).
+func (t Table) keys(fields []*Field, options *ListOptions) (keys []*Field, err error) {
+	selected := []*Field{}
+	for _, f := range fields {
+		if f.MatchDetail(options.Detail) {
+			selected = append(selected, f)
+		}
+	}
+	pk := 0
+	for i, f := range selected {
+		if f.Pk() {
+			pk = i + 1
+			break
+		}
+	}
+	if pk == 0 {
+		err = liberr.Wrap(MustHavePkErr)
+		return
+	}
+	if len(options.Sort) == 0 {
+		options.Sort = []int{pk}
+	}
+	for _, n := range options.Sort {
+		if n < 1 || n > len(selected) {
+			err = liberr.Wrap(CursorErr)
+			return
+		}
+		f := selected[n-1]
+		if f.Encoded() || f.Encrypted() {
+			err = liberr.Wrap(CursorErr)
+			return
+		}
+		keys = append(keys, f)
+		if n == pk {
+			return
+		}
+	}
+	keys = append(keys, selected[pk-1])
+
+	return
+}
+
+//
+// Get the query shape (fingerprint).
+// The hash of the (unpaged) list SQL and parameter values.
+func (t Table) shape(model interface{}, fields []*Field, options ListOptions) (string, error) {
+	options = options.Clone()
+	options.Page = nil
+	stmt, err := t.listSQL(t.Name(model), fields, &options)
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+	h := sha256.New()
+	h.Write([]byte(stmt))
+	h.Write([]byte(fmt.Sprintf("%#v", options.Params())))
+
+	return hex.EncodeToString(h.Sum(nil)[:16]), nil
+}
+
+//
+// Keyset predicate.
+// Matches rows ordered after the (sort key) values using
+// a row value comparison: (a,b,pk) > (:a,:b,:pk).
+type keysetPredicate struct {
+	// Field names.
+	Fields []string
+	// Field values.
+	Values []interface{}
+	// SQL expression.
+	expr string
+}
+
+//
+// Build.
+func (p *keysetPredicate) Build(options *ListOptions) error {
+	columns := []string{}
+	params := []string{}
+	for i, name := range p.Fields {
+		f, found := (&SimplePredicate{}).field(name, options.fields)
+		if !found {
+			return liberr.Wrap(PredicateRefErr)
+		}
+		if f.Encrypted() {
+			return liberr.Wrap(PredicateEncryptedErr)
+		}
+		v, err := f.AsValue(p.Values[i])
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		columns = append(columns, f.Column)
+		params = append(params, options.Param(f.Name, v))
+	}
+	p.expr = "(" + strings.Join(columns, ",") + ") > (" + strings.Join(params, ",") + ")"
+
+	return nil
+}
+
+//
+// Render the expression.
+func (p *keysetPredicate) Expr() string {
+	return p.expr
+}
+
+//
+// String representation.
+func (p *keysetPredicate) String() string {
+	return "KEYSET(" + strings.Join(p.Fields, ",") + ")"
+}
@@ -5,19 +5,101 @@
 // is described using tags:
 //   `sql:"pk"`
 //       The primary key.
+//   `sql:"pk,autoincrement"`
+//       The (int) primary key is AUTOINCREMENT. When zero, it
+//       is omitted on insert and set to the assigned value.
 //   `sql:"key"`
 //       The field is part of the natural key.
+//       The natural key is enforced unique.
+//   `sql:"nonunique"`
+//       Used with `key`. The natural key is not enforced unique.
 //   `sql:"fk:T(F)"`
 //       Foreign key `T` = model type, `F` = model field.
+//   `sql:"fk:T(F,deferred)"`
+//       Foreign key checked on transaction commit which
+//       supports inserting models in any order.
 //   `sql:"unique(G)"`
 //       Unique index. `G` = unique-together fields.
 //   `sql:"const"`
 //       The field is immutable and not included on update.
+//   `sql:"writeonce"`
+//       The field is set on insert and not updated. Unlike
+//       const, an update changing the value returns
+//       *WriteOnceError (WriteOnceErr).
 //   `sql:"virtual"`
 //       The field is read-only and managed internally by the DB.
+//   `sql:"rowid"`
+//       The (int64) field is virtual and carries the SQLite rowid.
+//       Listed and usable in predicates and sort (keyset
+//       pagination). Not valid for WITHOUT ROWID tables.
+//   `sql:"collate(C)"`
+//       Column collation `C` = (binary|nocase|rtrim).
+//   `sql:"generated(E, M)"`
+//       Generated (read-only) column computed by the DB.
+//       `E` = SQL expression, `M` = (stored|virtual).
+//   `sql:"enum(A|B|C)"`
+//       The (str) value must be one of the enumerated values.
+//       An (int) value is the ordinal (0=A,1=B,2=C) and is
+//       exported (and imported) by name.
 //   `sql:"dn"`
 //       The field detail level.  n = level number (0-9).
+//       Listed when n <= `ListOptions.Detail` (1 = all).
+//       By default: pk, key and virtual fields = 0;
+//       encoded fields = 3; other fields = 2.
+//   `sql:"encrypt"`
+//       The (str, encoded) value is encrypted (AES-GCM)
+//       using the client `Keyring`. Not valid in predicates.
+//   `sql:"compress"`
+//       The encoded value is compressed (gzip). Values stored
+//       before the field was compressed are read as-is.
+//   `sql:"doc(D)"`
+//       Column description `D`. Ignored by the DB but
+//       reported by `DB.Describe()`.
+//   `sql:"fts"`
+//       The (str) field is full-text searchable using
+//       `DB.Search()`. Requires sqlite3 built with FTS5
+//       (build tag: sqlite_fts5).
+//   `sql:"text"`
+//       The (bool) value is stored as TEXT ('true','false')
+//       rather than INTEGER (0,1). See: `Client.BoolText`.
+//   `sql:"map"`
+//       The (map[string]string) value is stored in a (child)
+//       key/value table named <table>_<field> rather than
+//       json encoded. Entries may be matched using the
+//       `MapEq` and `MapHas` predicates.
+//   `sql:"transform(A|B)"`
+//       The (str) value is transformed (in order) when written,
+//       read and used in predicates. Built-in: (lower|upper|trim).
+//       Others may be registered using `Client.Transforms`.
+//   `sql:"touch"`
+//       The (int64) field is set to the current time (Unix
+//       nanoseconds) on insert, update and `DB.Touch()`.
+// Fields with types implementing `sql.Scanner` and `driver.Valuer`
+// are stored using those interfaces (not json encoded) and are
+// nullable.
+// Interface (polymorphic) fields are json encoded with the name
+// of the concrete type which must be registered using
+// `RegisterType()`.
 // Each struct must implement the `Model` interface.
+// Models may optionally implement `Triggers` to have triggers
+// created with the table, `WithoutRowID` to have the table
+// created WITHOUT ROWID and `DetailDefault` to declare the
+// detail level used when not specified by `ListOptions`.
+// Table and column names are determined by the `Namer`
+// set on the client. The default `IdentityNamer` uses the
+// type and field names verbatim. The `SnakeNamer` uses
+// snake_case (and optionally pluralized) names.
+// Models may be bound to an attached database using
+// `DB.Attach()`. Their tables are qualified by the alias.
+// Models (tags) may be validated without a DB using
+// `ValidateModel()` which reports all problems found.
+// Model labels are stored in the `Label` table unless
+// disabled using `Client.DisableLabels`.
+// Client events (open, close, migrations) are logged using
+// the `Client.Log` which may be set to the host logger.
+// A read-only client (`Client.ReadOnly`) opens the DB file
+// read-only and rejects writes with `ReadOnlyErr`. Reads see
+// the changes committed by other (writable) clients.
 // Basic CRUD operations may be performed on each model using
 // the `DB` interface which together with the `Model` interface
 // provides value-added features and optimizations.
@@ -48,7 +130,8 @@
 //
 // In the event the primary key (PK) field is not populated,
 // the DB will derive (generate) its value as a sha1 of the
-// natural key fields.
+// natural key fields. The hash and encoding may be changed
+// using `Client.Hasher`. Example: `SHA256Hasher{Base32: true}`.
 //
 // Update the model:
 //   person.Age = 62
@@ -80,7 +163,7 @@
 // List() result set.  All predicates may be combined.
 //
 // Count (only):
-//   err := DB.List(&persons, ListOptions{Count: true})
+//   n, err := DB.Count(&Person{}, Gt("Age", 17))
 //
 // Paginate the result:
 //   err := DB.List(
@@ -92,6 +175,13 @@
 //           },
 //       })
 //
+// Paginate (keyset) with a cursor (token) which may be
+// persisted and used to resume (even after a restart).
+// The token is "" when no models remain.
+//   options := ListOptions{Page: &Page{Limit: 10}}
+//   next, err := DB.ListCursor(&persons, options, "")
+//   next, err = DB.ListCursor(&persons, options, next)
+//
 // List specific models.
 // List persons with the last name of "Fudd" and legal to vote.
 //   err := DB.List(
@@ -103,8 +193,101 @@
 //           },
 //       })
 //
+// List persons with a last name of "Fudd" or "Duck" or none (NULL).
+//   err := DB.List(
+//       &persons,
+//       ListOptions{
+//           Predicate: OneOf("Last", "Fudd", "Duck", nil),
+//       })
+//
+// List persons with the same first and last name.
+//   err := DB.List(
+//       &persons,
+//       ListOptions{
+//           Predicate: Eq("First", FieldRef("Last")),
+//       })
+//
+// List persons having at least one (child) pet older than 10.
+//   err := DB.List(
+//       &persons,
+//       ListOptions{
+//           Predicate: Exists(&Pet{}, "Owner", Gt("Age", 10)),
+//       })
+//
+// List models matching the non-zero fields of a filter
+// (selector) model. List persons with the last name of "Fudd".
+// Zero values are ignored.
+//   err := DB.List(
+//       &persons,
+//       ListOptions{
+//           Filter: &Person{Last: "Fudd"},
+//       })
+//
+// List persons with a long (> 10 characters) first name.
+// Functions are limited to those in `Functions`.
+//   err := DB.List(
+//       &persons,
+//       ListOptions{
+//           Predicate: Length("First").Gt(10),
+//       })
+//
+// Predicates may be rendered (logged) before they are built:
+//   And(Eq("Last", "Fudd"), Gt("Age", 17)).String()
+// renders: (Last = :p0 AND Age > :p1)
+//
+// Reads may be (row-level) restricted by an interceptor
+// which rewrites the predicate. Only persons of the tenant
+// (in the transaction context) are listed, counted or fetched:
+//   client.Interceptor = func(ctx context.Context, m interface{}, p Predicate) (Predicate, error) {
+//       tenant := Eq("Tenant", ctx.Value(TenantKey))
+//       if p == nil {
+//           return tenant, nil
+//       }
+//       return And(p, tenant), nil
+//   }
+//
+// Count persons by last name:
+//   counts, err := DB.CountBy(&Person{}, "Last", Gt("Age", 17))
+//
+// Count persons by last name shared by more than 10 persons:
+//   counts, err := DB.CountGroups(
+//       &Person{},
+//       []string{"Last"},
+//       ListOptions{
+//           Having: CountAll().Gt(10),
+//       })
+//
+// List the (distinct) last names of persons:
+//   names, err := DB.DistinctValues(&Person{}, "Last", nil)
+//
+// Join persons and their pets older than 10:
+//   rows, err := DB.Join(
+//       Join{
+//           Left:           &Person{},
+//           Right:          &Pet{},
+//           RightPredicate: Gt("Age", 10),
+//       })
+//
+// List persons with the "role" tag of "hunter":
+//   err := DB.List(
+//       &persons,
+//       ListOptions{
+//           Predicate: MapEq("Tags", "role", "hunter"),
+//       })
+//
+// Average age of persons with the last name of "Fudd":
+//   age, err := DB.Aggregate(&Person{}, "AVG", "Age", Eq("Last", "Fudd"))
+//
+// Search (full-text) persons with a bio mentioning "hunting":
+//   err := DB.Search(&persons, "hunting", ListOptions{})
+//
 package model
 
+import (
+	"fmt"
+	"sync/atomic"
+)
+
 //
 // New database.
 func New(path string, models ...interface{}) DB {
@@ -113,3 +296,23 @@ func New(path string, models ...interface{}) DB {
 		models: models,
 	}
 }
+
+//
+// New in-memory database.
+// Each is a distinct (shared cache) in-memory DB that
+// exists while the client is open. Intended for tests.
+// The pool is limited to a single connection so reads
+// wait while a transaction is in progress. The `purge`
+// flag on Open() and Close() is ignored.
+func NewInMemory(models ...interface{}) DB {
+	n := atomic.AddUint64(&memorySerial, 1)
+	return &Client{
+		path:   fmt.Sprintf("file:memdb%d?mode=memory&cache=shared", n),
+		models: models,
+		memory: true,
+	}
+}
+
+//
+// Serial number used to name in-memory databases.
+var memorySerial uint64
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"hash"
+	"strings"
+)
+
+//
+// Generated PK hashing strategy.
+// PKs are persisted so the strategy must be stable
+// for the life of the DB.
+type Hasher interface {
+	// Create a new hash.
+	New() hash.Hash
+	// Encode the hash (sum) as the PK.
+	Encode(sum []byte) string
+}
+
+//
+// SHA-1 hasher.
+// The PK is the (40 character) hex encoded sum.
+type SHA1Hasher struct{}
+
+//
+// Create a new hash.
+func (h SHA1Hasher) New() hash.Hash {
+	return sha1.New()
+}
+
+//
+// Encode the sum as hex.
+func (h SHA1Hasher) Encode(sum []byte) string {
+	return hex.EncodeToString(sum)
+}
+
+//
+// SHA-256 hasher.
+// The PK is the hex encoded sum (64 characters) or
+// optionally the base32 encoded sum (52 characters).
+// The sum may be truncated to trade PK length for
+// collision resistance.
+type SHA256Hasher struct {
+	// Encode using (lower case, unpadded) base32.
+	Base32 bool
+	// Number of (leading) bytes of the sum encoded.
+	// 0 = all (32).
+	Length int
+}
+
+//
+// Create a new hash.
+func (h SHA256Hasher) New() hash.Hash {
+	return sha256.New()
+}
+
+//
+// Encode the (truncated) sum.
+func (h SHA256Hasher) Encode(sum []byte) string {
+	if h.Length > 0 && h.Length < len(sum) {
+		sum = sum[:h.Length]
+	}
+	if h.Base32 {
+		encoded := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(sum)
+		return strings.ToLower(encoded)
+	}
+
+	return hex.EncodeToString(sum)
+}
@@ -0,0 +1,114 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	liberr "github.com/konveyor/controller/pkg/error"
+)
+
+//
+// Predicate interceptor.
+// Called with the (read) context, the model and the predicate
+// about to be run. Returns the predicate to be run instead;
+// usually the predicate AND-ed with additional constraints.
+// The predicate is nil when the read is not qualified. The
+// (returned) predicate is built against the model fields.
+// An error fails the read. Intended for row-level security
+// such as (multi-tenant) isolation.
+// The context is the one passed to BeginTx() for reads made
+// using the transaction. All other (client) reads, including
+// the Get() of the stored model made by Client.Update(), are
+// passed context.TODO(). So, an interceptor which fails
+// without a (tenant) context value fails those reads (and
+// updates); use transactions or fall back to a default.
+// Example:
+//   client.Interceptor = func(ctx context.Context, m interface{}, p Predicate) (Predicate, error) {
+//       tenant, found := ctx.Value(TenantKey).(string)
+//       if !found {
+//           return nil, NoTenantErr
+//       }
+//       if p == nil {
+//           return Eq("TenantID", tenant), nil
+//       }
+//       return And(p, Eq("TenantID", tenant)), nil
+//   }
+type PredicateInterceptor func(ctx context.Context, model interface{}, predicate Predicate) (Predicate, error)
+
+//
+// Bind the model and the interceptor (when set) to the
+// options. The interceptor is applied by Build() to the
+// (combined) predicate.
+func (t Table) intercept(model interface{}, options *ListOptions) {
+	options.model = model
+	if t.Interceptor == nil {
+		return
+	}
+	ctx := t.ctx
+	if ctx == nil {
+		ctx = context.TODO()
+	}
+	options.intercept = func(predicate Predicate) (Predicate, error) {
+		return t.Interceptor(ctx, model, predicate)
+	}
+}
+
+//
+// Get the (built) options qualifying a read of a single
+// model by the interceptor. Returns nil when no interceptor
+// is set or the intercepted predicate is nil.
+func (t Table) intercepted(model interface{}, fields []*Field) (*ListOptions, error) {
+	if t.Interceptor == nil {
+		return nil, nil
+	}
+	options := &ListOptions{
+		namer:  t.namer(),
+		limits: t.Limits,
+		prefix: "i",
+	}
+	t.intercept(model, options)
+	err := options.Build(t.Name(model), fields)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	if options.predicate == nil {
+		return nil, nil
+	}
+
+	return options, nil
+}
+
+//
+// Validate the model (row) is matched by the intercepted
+// predicate. Expects the PK or natural keys to be set.
+// Returns NotFound when not matched.
+func (t Table) visible(model interface{}) error {
+	fields, err := t.Fields(model)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	err = t.SetPk(fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	options, err := t.intercepted(model, fields)
+	if err != nil || options == nil {
+		return liberr.Wrap(err)
+	}
+	pk := t.PkField(fields)
+	params := []interface{}{sql.Named("parent", pk.Value.Interface())}
+	params = append(params, options.Params()...)
+	found := 0
+	err = t.db().QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM "+t.Name(model)+
+			" WHERE "+pk.Column+" = :parent AND "+
+			options.predicate.Expr()+");",
+		params...).Scan(&found)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	if found == 0 {
+		return liberr.Wrap(NotFound)
+	}
+
+	return nil
+}
@@ -0,0 +1,320 @@
+package model
+
+import (
+	"bytes"
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+var JoinSQL = `
+SELECT
+{{ range $i,$c := .Columns -}}
+{{ if $i }},{{ end -}}
+{{ $c }}
+{{ end -}}
+FROM {{ .Left }} L
+{{ .Kind }} JOIN {{ .Right }} R ON
+{{ range $i,$on := .On -}}
+{{ if $i }}AND {{ end -}}
+{{ $on }}
+{{ end -}}
+{{ if .Predicate -}}
+WHERE
+{{ .Predicate }}
+{{ end -}}
+ORDER BY
+{{ range $i,$s := .Sort -}}
+{{ if $i }},{{ end -}}
+{{ $s }}
+{{ end -}}
+;
+`
+
+//
+// Errors
+var (
+	// Join error.
+	JoinErr = errors.New("join fields must be known and specified or derived from a single fk")
+)
+
+//
+// Join kind.
+type JoinKind int
+
+const (
+	// Models matched on both sides.
+	JoinInner JoinKind = iota
+	// All left models; the right model when matched.
+	JoinLeft
+)
+
+//
+// Join (left and right) models.
+// The models are joined on the `On` fields. When not
+// specified, the join fields are derived from the (single)
+// FK on one model referencing the other.
+// Example:
+//   join := Join{
+//       Left:  &Person{},
+//       Right: &Pet{},
+//       RightPredicate: Gt("Age", 10),
+//   }
+type Join struct {
+	// Join kind.
+	// Default: JoinInner.
+	Kind JoinKind
+	// Left model.
+	Left Model
+	// Right model.
+	Right Model
+	// Join fields.
+	On []JoinOn
+	// Predicate over the left model.
+	LeftPredicate Predicate
+	// Predicate over the right model.
+	RightPredicate Predicate
+}
+
+//
+// Join fields.
+// The left field is matched (equal) with the right field.
+type JoinOn struct {
+	// Left field name.
+	Left string
+	// Right field name.
+	Right string
+}
+
+//
+// Joined models.
+type JoinRow struct {
+	// Left model.
+	Left Model
+	// Right model.
+	// Nil when not matched (JoinLeft).
+	Right Model
+}
+
+//
+// Join models in the DB.
+// Rows are ordered by the left and right PKs.
+func (t Table) Join(join Join) ([]JoinRow, error) {
+	if join.Left == nil || join.Right == nil {
+		return nil, liberr.Wrap(JoinErr)
+	}
+	lFields, err := t.joinFields(join.Left, "L")
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	rFields, err := t.joinFields(join.Right, "R")
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	on, err := t.joinOn(join, lFields, rFields)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	predicates := []string{}
+	params := []interface{}{}
+	sides := []struct {
+		model     Model
+		predicate Predicate
+		alias     string
+		prefix    string
+		fields    []*Field
+	}{
+		{join.Left, join.LeftPredicate, "L", "", lFields},
+		{join.Right, join.RightPredicate, "R", "r", rFields},
+	}
+	for _, side := range sides {
+		if side.predicate == nil && t.Interceptor == nil {
+			continue
+		}
+		options := ListOptions{
+			Predicate: side.predicate,
+			namer:     t.namer(),
+			limits:    t.Limits,
+			prefix:    side.prefix,
+		}
+		t.intercept(side.model, &options)
+		err = options.Build(side.alias, side.fields)
+		if err != nil {
+			return nil, liberr.Wrap(err)
+		}
+		if options.predicate == nil {
+			continue
+		}
+		predicates = append(predicates, options.predicate.Expr())
+		params = append(params, options.Params()...)
+	}
+	columns := []string{}
+	for _, f := range lFields {
+		columns = append(columns, f.Column)
+	}
+	for _, f := range rFields {
+		column := f.Column
+		if join.Kind == JoinLeft && !f.Custom() {
+			zero := "''"
+			if f.SqlType() == "INTEGER" {
+				zero = "0"
+			}
+			column = "COALESCE(" + column + "," + zero + ")"
+		}
+		columns = append(columns, column)
+	}
+	rPk := t.PkField(rFields)
+	columns = append(columns, rPk.Column+" IS NOT NULL")
+	kind := "INNER"
+	if join.Kind == JoinLeft {
+		kind = "LEFT"
+	}
+	tpl := template.New("")
+	tpl, err = tpl.Parse(JoinSQL)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	bfr := &bytes.Buffer{}
+	err = tpl.Execute(
+		bfr,
+		struct {
+			Columns   []string
+			Left      string
+			Right     string
+			Kind      string
+			On        []string
+			Predicate string
+			Sort      []string
+		}{
+			Columns:   columns,
+			Left:      t.Name(join.Left),
+			Right:     t.Name(join.Right),
+			Kind:      kind,
+			On:        on,
+			Predicate: strings.Join(predicates, " AND "),
+			Sort: []string{
+				t.PkField(lFields).Column,
+				rPk.Column,
+			},
+		})
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	cursor, err := t.db().Query(bfr.String(), params...)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	defer cursor.Close()
+	lt := reflect.TypeOf(join.Left).Elem()
+	rt := reflect.TypeOf(join.Right).Elem()
+	fields := append(append([]*Field{}, lFields...), rFields...)
+	list := []JoinRow{}
+	for cursor.Next() {
+		lv := reflect.New(lt)
+		rv := reflect.New(rt)
+		t.bind(lFields, lv.Elem())
+		t.bind(rFields, rv.Elem())
+		ptrs := []interface{}{}
+		for _, f := range fields {
+			f.string = ""
+			f.int = 0
+			ptrs = append(ptrs, f.Ptr())
+		}
+		matched := false
+		ptrs = append(ptrs, &matched)
+		err = cursor.Scan(ptrs...)
+		if err != nil {
+			return nil, liberr.Wrap(err)
+		}
+		for _, f := range fields {
+			err = f.Push()
+			if err != nil {
+				return nil, liberr.Wrap(err)
+			}
+		}
+		row := JoinRow{Left: lv.Interface().(Model)}
+		if matched {
+			row.Right = rv.Interface().(Model)
+		}
+		list = append(list, row)
+	}
+	err = cursor.Err()
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+
+	return list, nil
+}
+
+//
+// Get the model fields with columns qualified by
+// the (table) alias.
+func (t Table) joinFields(model interface{}, alias string) ([]*Field, error) {
+	if reflect.TypeOf(model).Kind() != reflect.Ptr {
+		return nil, liberr.Wrap(MustBePtrErr)
+	}
+	fields, err := t.Fields(model)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	for _, f := range fields {
+		f.Column = alias + "." + f.Column
+	}
+
+	return fields, nil
+}
+
+//
+// Get the ON expressions.
+// Explicit when specified. Else, derived from the (single)
+// FK on the right model referencing the left model or the
+// FK on the left model referencing the right model.
+func (t Table) joinOn(join Join, lFields, rFields []*Field) (on []string, err error) {
+	pair := func(lName, rName string) error {
+		lf, found := t.find(lName, lFields)
+		if !found {
+			return liberr.Wrap(JoinErr)
+		}
+		rf, found := t.find(rName, rFields)
+		if !found {
+			return liberr.Wrap(JoinErr)
+		}
+		if !lf.Comparable(rf) {
+			return liberr.Wrap(JoinErr)
+		}
+		on = append(on, lf.Column+" = "+rf.Column)
+		return nil
+	}
+	if len(join.On) > 0 {
+		for _, j := range join.On {
+			err = pair(j.Left, j.Right)
+			if err != nil {
+				return
+			}
+		}
+		return
+	}
+	kind := func(model interface{}) string {
+		return reflect.TypeOf(model).Elem().Name()
+	}
+	derived := []JoinOn{}
+	for _, f := range rFields {
+		if fk := f.Fk(); fk != nil && fk.Table == kind(join.Left) {
+			derived = append(derived, JoinOn{Left: fk.Field, Right: f.Name})
+		}
+	}
+	for _, f := range lFields {
+		if fk := f.Fk(); fk != nil && fk.Table == kind(join.Right) {
+			derived = append(derived, JoinOn{Left: f.Name, Right: fk.Field})
+		}
+	}
+	if len(derived) != 1 {
+		err = liberr.Wrap(JoinErr)
+		return
+	}
+	err = pair(derived[0].Left, derived[0].Right)
+
+	return
+}
@@ -239,6 +239,15 @@ func (r *Journal) Unstage() {
 	r.staged = []*Event{}
 }
 
+//
+// Get whether any watch matches the model (kind).
+// Callers may skip building events nobody will receive.
+func (r *Journal) Watched(model Model) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.hasWatch(model)
+}
+
 //
 // Model is being watched.
 // Determine if there a watch interested in the model.
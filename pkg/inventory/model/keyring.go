@@ -0,0 +1,90 @@
+package model
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"io"
+)
+
+//
+// Errors
+var (
+	// Encrypted field without a key.
+	KeyErr = errors.New("encrypted field requires a keyring")
+	// Decryption failed (using all keys).
+	DecryptErr = errors.New("decryption failed")
+)
+
+//
+// Keyring used for field encryption (AES-GCM).
+// The first (current) key is used to encrypt. All keys
+// are used (in order) to decrypt which supports rotation:
+// prepend the new key and keep prior keys until all rows
+// have been updated (re-encrypted).
+type Keyring struct {
+	aead []cipher.AEAD
+}
+
+//
+// Build a keyring.
+// Each key must be 16, 24 or 32 bytes to select
+// AES-128, AES-192 or AES-256.
+func NewKeyring(keys ...[]byte) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, liberr.Wrap(KeyErr)
+	}
+	k := &Keyring{}
+	for _, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, liberr.Wrap(err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, liberr.Wrap(err)
+		}
+		k.aead = append(k.aead, aead)
+	}
+
+	return k, nil
+}
+
+//
+// Encrypt using the current key.
+// Returns base64: nonce + ciphertext.
+func (k *Keyring) Encrypt(plain string) (string, error) {
+	aead := k.aead[0]
+	nonce := make([]byte, aead.NonceSize())
+	_, err := io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plain), nil)
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+//
+// Decrypt using each key (in order).
+func (k *Keyring) Decrypt(encrypted string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", liberr.Wrap(DecryptErr)
+	}
+	for _, aead := range k.aead {
+		n := aead.NonceSize()
+		if len(sealed) < n {
+			break
+		}
+		plain, err := aead.Open(nil, sealed[:n], sealed[n:], nil)
+		if err == nil {
+			return string(plain), nil
+		}
+	}
+
+	return "", liberr.Wrap(DecryptErr)
+}
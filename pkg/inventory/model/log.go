@@ -0,0 +1,70 @@
+package model
+
+import (
+	"database/sql"
+	"strings"
+)
+
+//
+// Logger.
+// Satisfied by logr.Logger and logging.Logger which
+// integrates the client with the host (controller) log.
+type Logger interface {
+	// Logs at info.
+	Info(message string, kvpair ...interface{})
+	// Logs an error.
+	Error(err error, message string, kvpair ...interface{})
+}
+
+//
+// Logger which discards all entries.
+type NopLogger struct{}
+
+//
+// Logs at info.
+func (l NopLogger) Info(string, ...interface{}) {}
+
+//
+// Logs an error.
+func (l NopLogger) Error(error, string, ...interface{}) {}
+
+//
+// Logged DB.
+// Each statement is logged (at info) with the
+// (optional) key/value pairs.
+type LogDB struct {
+	// Wrapped DB.
+	DB DBTX
+	// Logger.
+	Log Logger
+	// Key/value pairs included in each entry.
+	Values []interface{}
+}
+
+//
+// Execute a statement.
+func (d *LogDB) Exec(stmt string, args ...interface{}) (sql.Result, error) {
+	d.log(stmt)
+	return d.DB.Exec(stmt, args...)
+}
+
+//
+// Execute a query.
+func (d *LogDB) Query(stmt string, args ...interface{}) (*sql.Rows, error) {
+	d.log(stmt)
+	return d.DB.Query(stmt, args...)
+}
+
+//
+// Execute a query expected to return (at most) one row.
+func (d *LogDB) QueryRow(stmt string, args ...interface{}) *sql.Row {
+	d.log(stmt)
+	return d.DB.QueryRow(stmt, args...)
+}
+
+//
+// Log the statement.
+func (d *LogDB) log(stmt string) {
+	kvpair := append([]interface{}{"sql", strings.TrimSpace(stmt)}, d.Values...)
+	d.Log.Info("Statement.", kvpair...)
+}
@@ -0,0 +1,666 @@
+package model
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+var MapDDL = `
+CREATE TABLE IF NOT EXISTS {{ if .Schema }}{{ .Schema }}.{{ end }}{{ .Table }} (
+{{ .Parent }} {{ .ParentType }} NOT NULL,
+{{ .Key }} TEXT NOT NULL,
+{{ .Value }} TEXT NOT NULL,
+PRIMARY KEY ({{ .Parent }},{{ .Key }}),
+FOREIGN KEY ({{ .Parent }}) REFERENCES {{ .Ref }} ({{ .RefColumn }}) ON DELETE CASCADE
+);
+`
+
+var MapIndexDDL = `
+CREATE INDEX IF NOT EXISTS {{ if .Schema }}{{ .Schema }}.{{ end }}{{ .Table }}Index
+ON {{ .Table }}
+(
+{{ .Key }},{{ .Value }}
+);
+`
+
+var MapPutSQL = `
+INSERT OR REPLACE INTO {{ .Name }} (
+{{ .Parent }},{{ .Key }},{{ .Value }}
+)
+VALUES (
+:parent,:key,:value
+);
+`
+
+var MapGetSQL = `
+SELECT
+{{ .Key }},{{ .Value }}
+FROM {{ .Name }}
+WHERE
+{{ .Parent }} = :parent
+{{ if .ByKey }}AND {{ .Key }} = :key{{ end }}
+;
+`
+
+var MapListSQL = `
+SELECT
+{{ .Parent }},{{ .Key }},{{ .Value }}
+FROM {{ .Name }}
+WHERE
+{{ .Parent }} IN ({{ .In }})
+;
+`
+
+var MapDeleteSQL = `
+DELETE FROM {{ .Name }}
+WHERE
+{{ .Parent }} = :parent
+;
+`
+
+//
+// Errors
+var (
+	// Map field error.
+	MapErr = errors.New("map field must be (map[string]string) and not (pk, key, unique, fk, encrypt, compress, enum, fts)")
+	// Map field reference error.
+	MapRefErr = errors.New("map field not found")
+)
+
+//
+// Map (child) table.
+// The entries of a `map` field are stored in a (child)
+// key/value table keyed by the parent PK. The table is
+// named: <table>_<field>.
+type mapTable struct {
+	// Schema (attached database).
+	Schema string
+	// Table (unqualified) name.
+	Table string
+	// Parent column.
+	Parent string
+	// Parent (PK) column type.
+	ParentType string
+	// Key column.
+	Key string
+	// Value column.
+	Value string
+	// Referenced (parent) table.
+	Ref string
+	// Referenced (parent PK) column.
+	RefColumn string
+	// Qualified by key.
+	ByKey bool
+	// Parent (IN) list parameters.
+	In string
+}
+
+//
+// Qualified table name.
+func (m mapTable) Name() string {
+	if m.Schema != "" {
+		return m.Schema + "." + m.Table
+	}
+
+	return m.Table
+}
+
+//
+// Get the map (child) table name.
+// The `table` may be qualified by the schema.
+func mapName(namer Namer, table, field string) string {
+	return table + "_" + mapColumn(namer, field)
+}
+
+//
+// Get a map (child) table column name.
+func mapColumn(namer Namer, name string) string {
+	return namer.ColumnName(reflect.StructField{Name: name})
+}
+
+//
+// Get the `map` fields.
+// Map fields are not columns and are not included
+// in Fields().
+func (t Table) MapFields(model interface{}) ([]*Field, error) {
+	fields := []*Field{}
+	mt := reflect.TypeOf(model)
+	mv := reflect.ValueOf(model)
+	if mt.Kind() == reflect.Ptr {
+		mt = mt.Elem()
+		mv = mv.Elem()
+	} else {
+		return nil, liberr.Wrap(MustBePtrErr)
+	}
+	if mv.Kind() != reflect.Struct {
+		return nil, liberr.Wrap(MustBeObjectErr)
+	}
+	for i := 0; i < mt.NumField(); i++ {
+		ft := mt.Field(i)
+		fv := mv.Field(i)
+		if !fv.CanSet() || custom(ft.Type) {
+			continue
+		}
+		sqlTag, found := ft.Tag.Lookup(Tag)
+		switch fv.Kind() {
+		case reflect.Struct:
+			if found {
+				continue
+			}
+			nested, err := t.MapFields(fv.Addr().Interface())
+			if err != nil {
+				return nil, liberr.Wrap(err)
+			}
+			for _, f := range nested {
+				f.index = append([]int{i}, f.index...)
+			}
+			fields = append(fields, nested...)
+		case reflect.Map:
+			if !found {
+				continue
+			}
+			f := t.field(ft, &fv, sqlTag)
+			if f.Map() {
+				fields = append(fields, f)
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+//
+// Validate the `map` field.
+func (t Table) validateMap(f *Field) error {
+	if f.Value.Type() != reflect.TypeOf(map[string]string{}) {
+		return liberr.Wrap(MapErr)
+	}
+	if f.Pk() ||
+		f.Key() ||
+		len(f.Unique()) > 0 ||
+		f.Fk() != nil ||
+		f.Encrypted() ||
+		f.Compressed() ||
+		f.hasEnum() ||
+		f.Fts() {
+		return liberr.Wrap(MapErr)
+	}
+
+	return nil
+}
+
+//
+// Build the map (child) table for the model field.
+func (t Table) mapTable(model interface{}, pk, f *Field) mapTable {
+	namer := t.namer()
+	schema, name := t.split(model)
+	return mapTable{
+		Schema:     schema,
+		Table:      mapName(namer, name, f.Name),
+		Parent:     mapColumn(namer, "Parent"),
+		ParentType: pk.SqlType(),
+		Key:        mapColumn(namer, "Key"),
+		Value:      mapColumn(namer, "Value"),
+		Ref:        name,
+		RefColumn:  pk.Column,
+	}
+}
+
+//
+// Get the map (child) table DDL.
+func (t Table) mapDDL(model interface{}, fields []*Field) ([]string, error) {
+	list := []string{}
+	maps, err := t.MapFields(model)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	pk := t.PkField(fields)
+	for _, f := range maps {
+		err = t.validateMap(f)
+		if err != nil {
+			return nil, &SchemaError{
+				Field: f.Name,
+				Err:   err,
+			}
+		}
+		m := t.mapTable(model, pk, f)
+		for _, ddl := range []string{MapDDL, MapIndexDDL} {
+			stmt, err := t.mapSQL(ddl, m)
+			if err != nil {
+				return nil, liberr.Wrap(err)
+			}
+			list = append(list, stmt)
+		}
+	}
+
+	return list, nil
+}
+
+//
+// Render map (child) table SQL.
+func (t Table) mapSQL(stmt string, m mapTable) (string, error) {
+	tpl := template.New("")
+	tpl, err := tpl.Parse(stmt)
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+	bfr := &bytes.Buffer{}
+	err = tpl.Execute(bfr, m)
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+
+	return bfr.String(), nil
+}
+
+//
+// Replace the map entries of the model in the DB.
+// The `fields` are the (PK set) model fields.
+func (t Table) putMaps(model interface{}, fields []*Field) error {
+	maps, err := t.MapFields(model)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	if len(maps) == 0 {
+		return nil
+	}
+	err = t.deleteMaps(model, fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	pk := t.PkField(fields)
+	for _, f := range maps {
+		m := t.mapTable(model, pk, f)
+		stmt, err := t.mapSQL(MapPutSQL, m)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		iter := f.Value.MapRange()
+		for iter.Next() {
+			_, err = t.db().Exec(
+				stmt,
+				sql.Named("parent", pk.Value.Interface()),
+				sql.Named("key", iter.Key().String()),
+				sql.Named("value", iter.Value().String()))
+			if err != nil {
+				return liberr.Wrap(err)
+			}
+		}
+	}
+
+	return nil
+}
+
+//
+// Delete the map entries of the model in the DB.
+// The `fields` are the (PK set) model fields.
+func (t Table) deleteMaps(model interface{}, fields []*Field) error {
+	maps, err := t.MapFields(model)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	pk := t.PkField(fields)
+	for _, f := range maps {
+		m := t.mapTable(model, pk, f)
+		stmt, err := t.mapSQL(MapDeleteSQL, m)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		_, err = t.db().Exec(stmt, sql.Named("parent", pk.Value.Interface()))
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+	}
+
+	return nil
+}
+
+//
+// Fetch the map entries of the model in the DB.
+// Fields not matching the detail level are set to
+// their zero values.
+func (t Table) getMaps(model interface{}, detail int) error {
+	maps, err := t.MapFields(model)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	if len(maps) == 0 {
+		return nil
+	}
+	fields, err := t.Fields(model)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	pk := t.PkField(fields)
+	for _, f := range maps {
+		if !f.MatchDetail(detail) {
+			f.Value.Set(reflect.Zero(f.Value.Type()))
+			continue
+		}
+		entries, err := t.mapEntries(model, pk, f, nil)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		f.Value.Set(reflect.ValueOf(entries))
+	}
+
+	return nil
+}
+
+//
+// Fetch map entries.
+// Qualified by key when specified.
+func (t Table) mapEntries(model interface{}, pk, f *Field, key *string) (map[string]string, error) {
+	m := t.mapTable(model, pk, f)
+	params := []interface{}{
+		sql.Named("parent", pk.Value.Interface()),
+	}
+	if key != nil {
+		m.ByKey = true
+		params = append(params, sql.Named("key", *key))
+	}
+	stmt, err := t.mapSQL(MapGetSQL, m)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	cursor, err := t.db().Query(stmt, params...)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	defer cursor.Close()
+	entries := map[string]string{}
+	for cursor.Next() {
+		var k, v string
+		err = cursor.Scan(&k, &v)
+		if err != nil {
+			return nil, liberr.Wrap(err)
+		}
+		entries[k] = v
+	}
+	err = cursor.Err()
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+
+	return entries, nil
+}
+
+//
+// Fetch the map entries of the listed models.
+// The `mList` is a slice of models. The entries of each
+// map field are fetched for the listed models (parents)
+// in chunks (see: MaxChunk). Fields not matching the
+// detail level are set to their zero values.
+func (t Table) fetchMaps(mList reflect.Value, detail int) error {
+	if mList.Len() == 0 {
+		return nil
+	}
+	model := mList.Index(0).Addr().Interface()
+	maps, err := t.MapFields(model)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	if len(maps) == 0 {
+		return nil
+	}
+	fields, err := t.Fields(model)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	pk := t.PkField(fields)
+	if pk == nil {
+		return liberr.Wrap(MustHavePkErr)
+	}
+	parents := []interface{}{}
+	byParent := map[string][]reflect.Value{}
+	for i := 0; i < mList.Len(); i++ {
+		mv := mList.Index(i)
+		t.bind([]*Field{pk}, mv)
+		parent := fmt.Sprint(pk.Value.Interface())
+		if _, found := byParent[parent]; !found {
+			parents = append(parents, pk.Value.Interface())
+		}
+		byParent[parent] = append(byParent[parent], mv)
+	}
+	chunkSize := MaxChunk
+	if t.Limits.Variables > 0 && t.Limits.Variables < chunkSize {
+		chunkSize = t.Limits.Variables
+	}
+	for _, f := range maps {
+		matched := f.MatchDetail(detail)
+		for i := 0; i < mList.Len(); i++ {
+			fv := mList.Index(i).FieldByIndex(f.index)
+			if matched {
+				fv.Set(reflect.ValueOf(map[string]string{}))
+			} else {
+				fv.Set(reflect.Zero(fv.Type()))
+			}
+		}
+		if !matched {
+			continue
+		}
+		for start := 0; start < len(parents); start += chunkSize {
+			end := start + chunkSize
+			if end > len(parents) {
+				end = len(parents)
+			}
+			err = t.fetchMapChunk(model, pk, f, parents[start:end], byParent)
+			if err != nil {
+				return liberr.Wrap(err)
+			}
+		}
+	}
+
+	return nil
+}
+
+//
+// Fetch the map entries of a chunk of (listed) parents.
+// The entries are set in the map field of the models
+// (by parent PK).
+func (t Table) fetchMapChunk(model interface{}, pk, f *Field, parents []interface{}, byParent map[string][]reflect.Value) error {
+	m := t.mapTable(model, pk, f)
+	in := []string{}
+	params := []interface{}{}
+	for i, parent := range parents {
+		name := "p" + strconv.Itoa(i)
+		in = append(in, ":"+name)
+		params = append(params, sql.Named(name, parent))
+	}
+	m.In = strings.Join(in, ",")
+	stmt, err := t.mapSQL(MapListSQL, m)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	cursor, err := t.db().Query(stmt, params...)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	defer cursor.Close()
+	for cursor.Next() {
+		var parent, k, v string
+		err = cursor.Scan(&parent, &k, &v)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		for _, mv := range byParent[parent] {
+			mv.FieldByIndex(f.index).SetMapIndex(
+				reflect.ValueOf(k),
+				reflect.ValueOf(v))
+		}
+	}
+	err = cursor.Err()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+
+	return nil
+}
+
+//
+// Find the named map field and the (PK set) PK field.
+func (t Table) mapField(model interface{}, name string) (pk, f *Field, err error) {
+	fields, err := t.Fields(model)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	err = t.SetPk(fields)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	pk = t.PkField(fields)
+	maps, err := t.MapFields(model)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	for _, m := range maps {
+		if m.Name == name {
+			f = m
+			return
+		}
+	}
+	err = liberr.Wrap(MapRefErr)
+
+	return
+}
+
+//
+// Set the value of a key in a `map` field.
+// Only the entry is written (the model row is not updated)
+// and the model field is updated to reflect the change.
+// Expects the primary key (PK) or natural keys to be set.
+func (t Table) MapSet(model interface{}, field, key, value string) error {
+	pk, f, err := t.mapField(model, field)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	stmt, err := t.mapSQL(MapPutSQL, t.mapTable(model, pk, f))
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	_, err = t.db().Exec(
+		stmt,
+		sql.Named("parent", pk.Value.Interface()),
+		sql.Named("key", key),
+		sql.Named("value", value))
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	if f.Value.IsNil() {
+		f.Value.Set(reflect.ValueOf(map[string]string{}))
+	}
+	f.Value.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(value))
+
+	return nil
+}
+
+//
+// Get the value of a key in a `map` field.
+// Expects the primary key (PK) or natural keys to be set.
+// Returns NotFound when the model is not matched by the
+// interceptor. See: Interceptor.
+func (t Table) MapGet(model interface{}, field, key string) (value string, found bool, err error) {
+	pk, f, err := t.mapField(model, field)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	err = t.visible(model)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	entries, err := t.mapEntries(model, pk, f, &key)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	value, found = entries[key]
+
+	return
+}
+
+//
+// Map predicate.
+// Match models with a `map` field entry.
+type MapPredicate struct {
+	// Map field name.
+	Field string
+	// Entry key.
+	Key string
+	// Entry value.
+	// Any value when nil.
+	Value *string
+	// SQL expression.
+	expr string
+}
+
+//
+// Build.
+// The field must be a `map` field of the (listed) model.
+func (p *MapPredicate) Build(options *ListOptions) error {
+	if options.model != nil {
+		maps, err := Table{Namer: options.namer}.MapFields(options.model)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		found := false
+		for _, f := range maps {
+			if f.Name == p.Field {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return liberr.Wrap(PredicateRefErr)
+		}
+	}
+	var pk *Field
+	for _, f := range options.fields {
+		if f.Pk() {
+			pk = f
+			break
+		}
+	}
+	if pk == nil {
+		return liberr.Wrap(PredicateRefErr)
+	}
+	namer := Table{Namer: options.namer}.namer()
+	where := []string{
+		mapColumn(namer, "Key") + " = " + options.Param("k", p.Key),
+	}
+	if p.Value != nil {
+		where = append(
+			where,
+			mapColumn(namer, "Value")+" = "+options.Param("v", *p.Value))
+	}
+	p.expr = strings.Join(
+		[]string{
+			pk.Column,
+			"IN (SELECT",
+			mapColumn(namer, "Parent"),
+			"FROM",
+			mapName(namer, options.table, p.Field),
+			"WHERE",
+			strings.Join(where, " AND "),
+			")",
+		}, " ")
+
+	return nil
+}
+
+//
+// Render the expression.
+func (p *MapPredicate) Expr() string {
+	return p.expr
+}
@@ -0,0 +1,176 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"sort"
+	"time"
+)
+
+//
+// Migration DDL.
+var MigrationDDL = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+Version INTEGER PRIMARY KEY,
+Applied INTEGER NOT NULL
+);
+`
+
+//
+// Errors
+var (
+	// Duplicate migration version.
+	MigrationVersionErr = errors.New("migration version must be unique")
+)
+
+//
+// Versioned schema migration.
+type Migration struct {
+	// Version (order).
+	Version int
+	// Migrate the schema (up).
+	Up func(tx *Tx) error
+}
+
+//
+// Run migrations.
+// Each migration that has not already been applied is run
+// in version order within its own transaction. The version
+// is recorded in the `schema_migrations` table when the
+// migration (transaction) is committed. Stops on the first
+// migration that fails; applied migrations are not reverted.
+// The client is locked (other writes and transactions block)
+// until all migrations have been run.
+// Example:
+//   err := client.Migrate(
+//       []Migration{
+//           {
+//               Version: 1,
+//               Up: func(tx *Tx) error {
+//                   _, err := tx.Exec("ALTER TABLE Person ADD COLUMN Phone TEXT")
+//                   return err
+//               },
+//           },
+//       })
+func (r *Client) Migrate(migrations []Migration) error {
+	list := make([]Migration, len(migrations))
+	copy(list, migrations)
+	sort.Slice(
+		list,
+		func(i, j int) bool {
+			return list[i].Version < list[j].Version
+		})
+	for i := 1; i < len(list); i++ {
+		if list[i].Version == list[i-1].Version {
+			return liberr.Wrap(MigrationVersionErr)
+		}
+	}
+	if r.ReadOnly {
+		return liberr.Wrap(ReadOnlyErr)
+	}
+	r.dbMutex.Lock()
+	defer r.dbMutex.Unlock()
+	db, err := r.pool()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	_, err = db.Exec(MigrationDDL)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	for _, m := range list {
+		err = r.migrate(m)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+	}
+
+	return nil
+}
+
+//
+// Applied migration versions.
+// Empty when no migrations have been run.
+func (r *Client) Migrations() ([]int, error) {
+	list := []int{}
+	db, err := r.pool()
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	found := 0
+	row := db.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'")
+	err = row.Scan(&found)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	if found == 0 {
+		return list, nil
+	}
+	cursor, err := db.Query("SELECT Version FROM schema_migrations ORDER BY Version")
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	defer cursor.Close()
+	for cursor.Next() {
+		version := 0
+		err = cursor.Scan(&version)
+		if err != nil {
+			return nil, liberr.Wrap(err)
+		}
+		list = append(list, version)
+	}
+
+	return list, nil
+}
+
+//
+// Run the migration (when not already applied).
+// The caller must hold dbMutex.
+func (r *Client) migrate(m Migration) (err error) {
+	tx, err := r.begin(context.TODO())
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	defer tx.Rollback()
+	db := r.table(tx.real).db()
+	version := 0
+	row := db.QueryRow(
+		"SELECT Version FROM schema_migrations WHERE Version = :version",
+		sql.Named("version", m.Version))
+	err = row.Scan(&version)
+	if err == nil {
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		err = liberr.Wrap(err)
+		return
+	}
+	if m.Up != nil {
+		err = m.Up(tx)
+		if err != nil {
+			err = liberr.Wrap(err)
+			return
+		}
+	}
+	_, err = db.Exec(
+		"INSERT INTO schema_migrations (Version, Applied) VALUES (:version, :applied)",
+		sql.Named("version", m.Version),
+		sql.Named("applied", time.Now().Unix()))
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	err = tx.Commit()
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+
+	r.log().Info("Migration applied.", "version", m.Version)
+
+	return
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"database/sql"
+	liberr "github.com/konveyor/controller/pkg/error"
 	_ "github.com/mattn/go-sqlite3"
 	"reflect"
 )
@@ -36,6 +37,26 @@ type Page struct {
 	Limit int
 }
 
+//
+// Count of models grouped by field values.
+type GroupCount struct {
+	// The (text) values of the group fields.
+	Keys []string
+	// The number of models.
+	Count int64
+}
+
+//
+// Validate the page.
+// The offset must be >= 0 and the limit must be > 0.
+func (p *Page) Validate() error {
+	if p.Offset < 0 || p.Limit < 1 {
+		return liberr.Wrap(InvalidPageErr)
+	}
+
+	return nil
+}
+
 //
 // Slice the collection according to the page definition.
 // The `collection` must be a pointer to a `Slice` which is
@@ -79,6 +100,23 @@ type Model interface {
 	Labels() Labels
 }
 
+//
+// Optionally implemented by models to declare the detail
+// level used to list models when not specified (0) by
+// the list options. Replaces the core (0) level.
+type DetailDefault interface {
+	DetailDefault() int
+}
+
+//
+// Optionally implemented by models to have the table
+// created WITHOUT ROWID. Saves space and speeds PK lookup
+// for tables with a (str) PK. The model must not have
+// virtual fields (rowid).
+type WithoutRowID interface {
+	WithoutRowID() bool
+}
+
 type Base struct {
 	// Primary key (digest).
 	PK string `sql:"pk"`
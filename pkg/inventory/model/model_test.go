@@ -1,11 +1,22 @@
 package model
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/konveyor/controller/pkg/ref"
 	"github.com/onsi/gomega"
 	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -56,406 +67,6724 @@ func (m *TestObject) Labels() Labels {
 	return m.labels
 }
 
-// received event.
-type TestEvent struct {
-	action int8
-	model  *TestObject
+type TestChild struct {
+	PK     string `sql:"pk"`
+	Parent string `sql:"key,fk:TestObject(PK),doc(The parent (object), see: TestObject.)"`
+	Name   string `sql:"key"`
+	Age    int    `sql:""`
 }
 
-type TestHandler struct {
-	name    string
-	started bool
-	all     []TestEvent
-	created []int
-	updated []int
-	deleted []int
-	err     []error
-	done    bool
+func (m *TestChild) Pk() string {
+	return m.PK
 }
 
-func (w *TestHandler) Started() {
-	w.started = true
+func (m *TestChild) String() string {
+	return fmt.Sprintf(
+		"TestChild: parent: %s, name:%s",
+		m.Parent,
+		m.Name)
 }
 
-func (w *TestHandler) Created(e Event) {
-	if object, cast := e.Model.(*TestObject); cast {
-		w.all = append(w.all, TestEvent{action: e.Action, model: object})
-		w.created = append(w.created, object.ID)
-	}
+func (m *TestChild) Equals(other Model) bool {
+	return false
 }
 
-func (w *TestHandler) Updated(e Event) {
-	if object, cast := e.Model.(*TestObject); cast {
-		w.all = append(w.all, TestEvent{action: e.Action, model: object})
-		w.updated = append(w.updated, object.ID)
+func (m *TestChild) Labels() Labels {
+	return nil
+}
+
+type TestCollate struct {
+	PK   string `sql:"pk"`
+	ID   int    `sql:"key"`
+	Name string `sql:"unique(a),collate(nocase)"`
+}
+
+func (m *TestCollate) Pk() string {
+	return m.PK
+}
+
+func (m *TestCollate) String() string {
+	return m.Name
+}
+
+func (m *TestCollate) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestCollate) Labels() Labels {
+	return nil
+}
+
+type TestGenerated struct {
+	PK     string `sql:"pk"`
+	ID     int    `sql:"key"`
+	Name   string `sql:""`
+	Lower  string `sql:"generated(lower(Name), stored)"`
+	Length int    `sql:"generated(length(Name), virtual)"`
+}
+
+func (m *TestGenerated) Pk() string {
+	return m.PK
+}
+
+func (m *TestGenerated) String() string {
+	return m.Name
+}
+
+func (m *TestGenerated) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestGenerated) Labels() Labels {
+	return nil
+}
+
+type TestDeferred struct {
+	PK     string `sql:"pk"`
+	Parent string `sql:"key,fk:TestObject(PK,deferred)"`
+	Name   string `sql:"key"`
+}
+
+func (m *TestDeferred) Pk() string {
+	return m.PK
+}
+
+func (m *TestDeferred) String() string {
+	return m.Name
+}
+
+func (m *TestDeferred) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestDeferred) Labels() Labels {
+	return nil
+}
+
+type TestEnum struct {
+	PK    string `sql:"pk"`
+	ID    int    `sql:"key"`
+	Phase string `sql:"enum(Pending|Running|Failed)"`
+}
+
+func (m *TestEnum) Pk() string {
+	return m.PK
+}
+
+func (m *TestEnum) String() string {
+	return m.Phase
+}
+
+func (m *TestEnum) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestEnum) Labels() Labels {
+	return nil
+}
+
+type TestSecret struct {
+	PK    string      `sql:"pk"`
+	ID    int         `sql:"key"`
+	Token string      `sql:"encrypt"`
+	Spec  TestEncoded `sql:"encrypt"`
+}
+
+func (m *TestSecret) Pk() string {
+	return m.PK
+}
+
+func (m *TestSecret) String() string {
+	return m.PK
+}
+
+func (m *TestSecret) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestSecret) Labels() Labels {
+	return nil
+}
+
+type TestCompressed struct {
+	PK     string   `sql:"pk"`
+	ID     int      `sql:"key"`
+	Spec   []string `sql:"compress"`
+	Secret []string `sql:"compress,encrypt"`
+}
+
+func (m *TestCompressed) Pk() string {
+	return m.PK
+}
+
+func (m *TestCompressed) String() string {
+	return m.PK
+}
+
+func (m *TestCompressed) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestCompressed) Labels() Labels {
+	return nil
+}
+
+type TestUUID [4]byte
+
+func (u TestUUID) Value() (driver.Value, error) {
+	return hex.EncodeToString(u[:]), nil
+}
+
+func (u *TestUUID) Scan(v interface{}) error {
+	s, cast := v.(string)
+	if !cast {
+		return errors.New("not string")
 	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	copy(u[:], b)
+	return nil
 }
-func (w *TestHandler) Deleted(e Event) {
-	if object, cast := e.Model.(*TestObject); cast {
-		w.all = append(w.all, TestEvent{action: e.Action, model: object})
-		w.deleted = append(w.deleted, object.ID)
+
+type TestCount struct {
+	N int
+}
+
+func (c TestCount) Value() (driver.Value, error) {
+	return int64(c.N), nil
+}
+
+func (c *TestCount) Scan(v interface{}) error {
+	n, cast := v.(int64)
+	if !cast {
+		return errors.New("not int")
 	}
+	c.N = int(n)
+	return nil
 }
 
-func (w *TestHandler) Error(err error) {
-	w.err = append(w.err, err)
+type TestCustom struct {
+	PK    string         `sql:"pk"`
+	ID    int            `sql:"key"`
+	UUID  TestUUID       `sql:""`
+	Count TestCount      `sql:""`
+	Note  sql.NullString `sql:""`
 }
 
-func (w *TestHandler) End() {
+func (m *TestCustom) Pk() string {
+	return m.PK
 }
 
-type MutatingHandler struct {
-	DB
-	name    string
-	started bool
-	created []int
-	updated []int
+func (m *TestCustom) String() string {
+	return m.PK
 }
 
-func (w *MutatingHandler) Started() {
-	w.started = true
+func (m *TestCustom) Equals(other Model) bool {
+	return false
 }
 
-func (w *MutatingHandler) Created(e Event) {
-	tx, _ := w.DB.Begin()
-	tx.Get(e.Model)
-	e.Model.(*TestObject).Age++
-	tx.Update(e.Model)
-	tx.Commit()
-	w.created = append(w.created, e.Model.(*TestObject).ID)
+func (m *TestCustom) Labels() Labels {
+	return nil
 }
 
-func (w *MutatingHandler) Updated(e Event) {
-	tx, _ := w.DB.Begin()
-	tx.Get(e.Model)
-	e.Model.(*TestObject).Age++
-	tx.Update(e.Model)
-	tx.Commit()
-	w.updated = append(w.updated, e.Model.(*TestObject).ID)
+type TestVirtualKey struct {
+	PK    string `sql:"pk"`
+	RowID int64  `sql:"key,virtual"`
 }
 
-func (w *MutatingHandler) Deleted(e Event) {
+type TestNoRowID struct {
+	PK   string `sql:"pk"`
+	Name string `sql:""`
 }
 
-func (w *MutatingHandler) Error(err error) {
-	return
+func (m *TestNoRowID) Pk() string {
+	return m.PK
 }
 
-func (w *MutatingHandler) End() {
+func (m *TestNoRowID) String() string {
+	return m.PK
 }
 
-func TestCRUD(t *testing.T) {
-	var err error
-	g := gomega.NewGomegaWithT(t)
-	DB := New(
-		"/tmp/test.db",
-		&Label{},
-		&TestObject{})
-	err = DB.Open(true)
-	g.Expect(err).To(gomega.BeNil())
-	objA := &TestObject{
-		TestBase: TestBase{
-			Parent: 0,
-			Phone:  "1234",
+func (m *TestNoRowID) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestNoRowID) Labels() Labels {
+	return nil
+}
+
+func (m *TestNoRowID) WithoutRowID() bool {
+	return true
+}
+
+type TestNoRowIDInt struct {
+	ID   int    `sql:"pk"`
+	Name string `sql:""`
+}
+
+func (m *TestNoRowIDInt) WithoutRowID() bool {
+	return true
+}
+
+type TestArticle struct {
+	PK     string `sql:"pk"`
+	ID     int    `sql:"key"`
+	Title  string `sql:"fts"`
+	Body   string `sql:"fts"`
+	Author string `sql:""`
+}
+
+func (m *TestArticle) Pk() string {
+	return m.PK
+}
+
+func (m *TestArticle) String() string {
+	return m.PK
+}
+
+func (m *TestArticle) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestArticle) Labels() Labels {
+	return nil
+}
+
+type TestTally struct {
+	PK       string `sql:"pk"`
+	ID       int    `sql:"key"`
+	Children int    `sql:""`
+}
+
+func (m *TestTally) Pk() string {
+	return m.PK
+}
+
+func (m *TestTally) String() string {
+	return m.PK
+}
+
+func (m *TestTally) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestTally) Labels() Labels {
+	return nil
+}
+
+type TestTallyChild struct {
+	PK     string `sql:"pk"`
+	ID     int    `sql:"key"`
+	Parent string `sql:"fk:TestTally(PK)"`
+}
+
+func (m *TestTallyChild) Pk() string {
+	return m.PK
+}
+
+func (m *TestTallyChild) String() string {
+	return m.PK
+}
+
+func (m *TestTallyChild) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestTallyChild) Labels() Labels {
+	return nil
+}
+
+func (m *TestTallyChild) Triggers() []Trigger {
+	return []Trigger{
+		{
+			Name:  "Added",
+			Event: "insert",
+			Body: `
+UPDATE {{ table "TestTally" }}
+SET Children = Children + 1
+WHERE PK = new.Parent;`,
 		},
-		ID:     0,
-		Name:   "Elmer",
-		Age:    18,
-		Int8:   8,
-		Int16:  16,
-		Int32:  32,
-		Bool:   true,
-		Object: TestEncoded{Name: "json"},
-		Slice:  []string{"hello", "world"},
-		Map:    map[string]int{"A": 1, "B": 2},
-		labels: Labels{
-			"n1": "v1",
-			"n2": "v2",
+		{
+			Name:  "Removed",
+			Event: "DELETE",
+			When:  "old.Parent != ''",
+			Body: `
+UPDATE {{ table "TestTally" }}
+SET Children = Children - 1
+WHERE PK = old.Parent;`,
 		},
 	}
-	assertEqual := func(a, b *TestObject) {
-		g.Expect(a.PK).To(gomega.Equal(b.PK))
-		g.Expect(a.ID).To(gomega.Equal(b.ID))
-		g.Expect(a.Name).To(gomega.Equal(b.Name))
-		g.Expect(a.Age).To(gomega.Equal(b.Age))
-		g.Expect(a.Int8).To(gomega.Equal(b.Int8))
-		g.Expect(a.Int16).To(gomega.Equal(b.Int16))
-		g.Expect(a.Int32).To(gomega.Equal(b.Int32))
-		g.Expect(a.Bool).To(gomega.Equal(b.Bool))
-		g.Expect(a.Object).To(gomega.Equal(b.Object))
-		g.Expect(a.Slice).To(gomega.Equal(b.Slice))
-		g.Expect(a.Map).To(gomega.Equal(b.Map))
-		for k, v := range objA.labels {
-			l := &Label{
-				Kind:   ref.ToKind(a),
-				Parent: a.PK,
-				Name:   k,
-			}
-			g.Expect(DB.Get(l)).To(gomega.BeNil())
-			g.Expect(v).To(gomega.Equal(l.Value))
-		}
+}
+
+type TestBadTrigger struct {
+	PK string `sql:"pk"`
+}
+
+func (m *TestBadTrigger) Triggers() []Trigger {
+	return []Trigger{
+		{
+			Name:  "Bad",
+			Event: "INSERT",
+			Body:  `DELETE FROM {{ table "Unknown" }};`,
+		},
 	}
-	// Insert
-	err = DB.Insert(objA)
-	g.Expect(err).To(gomega.BeNil())
-	objB := &TestObject{ID: objA.ID}
-	// Get
-	err = DB.Get(objB)
-	g.Expect(err).To(gomega.BeNil())
-	assertEqual(objA, objB)
-	// Update
-	objA.Name = "Larry"
-	objA.Age = 21
-	objA.Bool = false
-	err = DB.Update(objA)
-	g.Expect(err).To(gomega.BeNil())
-	// Get
-	objB = &TestObject{ID: objA.ID}
-	err = DB.Get(objB)
-	g.Expect(err).To(gomega.BeNil())
-	assertEqual(objA, objB)
-	// Delete
-	objA = &TestObject{ID: objA.ID}
-	err = DB.Delete(objA)
+}
+
+type TestSummary struct {
+	PK    string   `sql:"pk"`
+	ID    int      `sql:"key"`
+	Name  string   `sql:""`
+	Notes string   `sql:"d4"`
+	Spec  []string `sql:""`
+}
+
+func (m *TestSummary) Pk() string {
+	return m.PK
+}
+
+func (m *TestSummary) String() string {
+	return m.PK
+}
+
+func (m *TestSummary) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestSummary) Labels() Labels {
+	return nil
+}
+
+func (m *TestSummary) DetailDefault() int {
+	return 2
+}
+
+type TestBadDetail struct {
+	PK   string `sql:"pk"`
+	Name string `sql:"d12"`
+}
+
+type TestTwoDetail struct {
+	PK   string `sql:"pk"`
+	Name string `sql:"d2,d5"`
+}
+
+type TestSite struct {
+	Region string `sql:"unique(location)"`
+	Zone   string `sql:"unique( location )"`
+}
+
+type TestRack struct {
+	Row    int    `sql:"unique(location)"`
+	Number int    `sql:"unique(location)"`
+	Serial string `sql:"unique(serial),unique(serial)"`
+}
+
+type TestServer struct {
+	TestSite
+	TestRack
+	PK    string `sql:"pk"`
+	Name  string `sql:"key"`
+	RowID int64  `sql:"virtual,unique(serial)"`
+}
+
+func (m *TestServer) Pk() string {
+	return m.PK
+}
+
+func (m *TestServer) String() string {
+	return m.PK
+}
+
+func (m *TestServer) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestServer) Labels() Labels {
+	return nil
+}
+
+type TestSeen struct {
+	PK       string `sql:"pk"`
+	ID       int    `sql:"key"`
+	Name     string `sql:""`
+	LastSeen int64  `sql:"touch"`
+	labels   Labels
+}
+
+func (m *TestSeen) Pk() string {
+	return m.PK
+}
+
+func (m *TestSeen) String() string {
+	return m.Name
+}
+
+func (m *TestSeen) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestSeen) Labels() Labels {
+	return m.labels
+}
+
+type TestSeenHandler struct {
+	StubEventHandler
+	deleted chan int
+}
+
+func (w *TestSeenHandler) Deleted(e Event) {
+	w.deleted <- e.Model.(*TestSeen).ID
+}
+
+type TestBadTouch struct {
+	PK       string `sql:"pk"`
+	LastSeen string `sql:"touch"`
+}
+
+func (m *TestBadTouch) Pk() string {
+	return m.PK
+}
+
+type TestTextBool struct {
+	PK   string `sql:"pk,generated(id)"`
+	ID   int    `sql:"key"`
+	Text bool   `sql:"text"`
+	Int  bool   `sql:""`
+}
+
+func (m *TestTextBool) Pk() string {
+	return m.PK
+}
+
+func (m *TestTextBool) String() string {
+	return m.PK
+}
+
+func (m *TestTextBool) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestTextBool) Labels() Labels {
+	return nil
+}
+
+type TestMapped struct {
+	PK   string            `sql:"pk,generated(id)"`
+	ID   int               `sql:"key"`
+	Tags map[string]string `sql:"map"`
+	Data map[string]string `sql:""`
+}
+
+func (m *TestMapped) Pk() string {
+	return m.PK
+}
+
+func (m *TestMapped) String() string {
+	return m.PK
+}
+
+func (m *TestMapped) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestMapped) Labels() Labels {
+	return nil
+}
+
+type TestEncoder struct {
+	JSONEncoder
+	count int
+}
+
+func (e *TestEncoder) Encode(object interface{}) ([]byte, error) {
+	e.count++
+	return e.JSONEncoder.Encode(object)
+}
+
+type TestDecoder struct {
+	JSONDecoder
+	count int
+}
+
+func (d *TestDecoder) Decode(encoded []byte, object interface{}) error {
+	d.count++
+	return d.JSONDecoder.Decode(encoded, object)
+}
+
+type TestAny struct {
+	PK   string                 `sql:"pk,generated(id)"`
+	ID   int                    `sql:"key"`
+	Data map[string]interface{} `sql:""`
+}
+
+func (m *TestAny) Pk() string {
+	return m.PK
+}
+
+func (m *TestAny) String() string {
+	return m.PK
+}
+
+func (m *TestAny) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestAny) Labels() Labels {
+	return nil
+}
+
+type TestRecorder struct {
+	DB   DBTX
+	args []interface{}
+}
+
+func (r *TestRecorder) Exec(stmt string, args ...interface{}) (sql.Result, error) {
+	r.args = append(r.args, args...)
+	return r.DB.Exec(stmt, args...)
+}
+
+func (r *TestRecorder) Query(stmt string, args ...interface{}) (*sql.Rows, error) {
+	r.args = append(r.args, args...)
+	return r.DB.Query(stmt, args...)
+}
+
+func (r *TestRecorder) QueryRow(stmt string, args ...interface{}) *sql.Row {
+	r.args = append(r.args, args...)
+	return r.DB.QueryRow(stmt, args...)
+}
+
+type TestInvalid struct {
+	ID     int    `sql:""`
+	Color  bool   `sql:"enum(red|blue)"`
+	Parent string `sql:"fk:TestObject(Missing)"`
+	Rate   string `sql:"collate(bogus)"`
+}
+
+type TestLogger struct {
+	mutex   sync.Mutex
+	entries []string
+	values  [][]interface{}
+}
+
+func (l *TestLogger) Info(message string, kvpair ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.entries = append(l.entries, message)
+	l.values = append(l.values, kvpair)
+}
+
+func (l *TestLogger) Error(err error, message string, kvpair ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.entries = append(l.entries, message+" "+err.Error())
+}
+
+type TestTransformed struct {
+	PK    string `sql:"pk"`
+	Name  string `sql:"key,transform(lower)"`
+	Code  string `sql:"transform(trim|upper)"`
+	Alias string `sql:"transform(reverse)"`
+}
+
+func (m *TestTransformed) Pk() string {
+	return m.PK
+}
+
+func (m *TestTransformed) String() string {
+	return m.PK
+}
+
+func (m *TestTransformed) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestTransformed) Labels() Labels {
+	return nil
+}
+
+type TestRowID struct {
+	Serial int64  `sql:"rowid"`
+	PK     string `sql:"pk"`
+	Name   string `sql:""`
+}
+
+func (m *TestRowID) Pk() string {
+	return m.PK
+}
+
+func (m *TestRowID) String() string {
+	return m.PK
+}
+
+func (m *TestRowID) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestRowID) Labels() Labels {
+	return nil
+}
+
+type TestBadRowID struct {
+	Serial int32  `sql:"rowid"`
+	PK     string `sql:"pk"`
+}
+
+type TestAutoIncrement struct {
+	ID   int64  `sql:"pk,autoincrement"`
+	Name string `sql:"key"`
+}
+
+func (m *TestAutoIncrement) Pk() string {
+	return fmt.Sprintf("%d", m.ID)
+}
+
+func (m *TestAutoIncrement) String() string {
+	return m.Name
+}
+
+func (m *TestAutoIncrement) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestAutoIncrement) Labels() Labels {
+	return nil
+}
+
+type TestBadAutoIncrement struct {
+	ID   string `sql:"pk,autoincrement"`
+	Name string `sql:""`
+}
+
+type TestWriteOnce struct {
+	ID      int    `sql:"pk"`
+	Created string `sql:"const"`
+	Owner   string `sql:"writeonce"`
+	Name    string `sql:""`
+}
+
+func (m *TestWriteOnce) Pk() string {
+	return fmt.Sprintf("%d", m.ID)
+}
+
+func (m *TestWriteOnce) String() string {
+	return m.Name
+}
+
+func (m *TestWriteOnce) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestWriteOnce) Labels() Labels {
+	return nil
+}
+
+type TestNestedMeta struct {
+	Namespace string `sql:""`
+	Revision  int    `sql:""`
+}
+
+type TestNestedSpec struct {
+	Replicas int      `sql:""`
+	Images   []string `sql:""`
+}
+
+type TestNested struct {
+	Name string `sql:"pk"`
+	TestNestedMeta
+	Status string         `sql:""`
+	Spec   TestNestedSpec `sql:""`
+	Owner  struct {
+		Kind string `sql:""`
+		Age  int    `sql:""`
+	}
+}
+
+func (m *TestNested) Pk() string {
+	return m.Name
+}
+
+func (m *TestNested) String() string {
+	return m.Name
+}
+
+func (m *TestNested) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestNested) Labels() Labels {
+	return nil
+}
+
+type TestShape interface {
+	Area() int
+}
+
+type TestSquare struct {
+	Side int
+}
+
+func (s TestSquare) Area() int {
+	return s.Side * s.Side
+}
+
+type TestCircle struct {
+	Radius int
+}
+
+func (c *TestCircle) Area() int {
+	return 3 * c.Radius * c.Radius
+}
+
+type TestTriangle struct {
+	Base int
+}
+
+func (t TestTriangle) Area() int {
+	return t.Base
+}
+
+type TestDrawing struct {
+	ID    int       `sql:"pk"`
+	Shape TestShape `sql:""`
+	Other TestShape `sql:""`
+}
+
+func (m *TestDrawing) Pk() string {
+	return fmt.Sprintf("%d", m.ID)
+}
+
+func (m *TestDrawing) String() string {
+	return m.Pk()
+}
+
+func (m *TestDrawing) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestDrawing) Labels() Labels {
+	return nil
+}
+
+type TestOrdinal struct {
+	ID    int `sql:"pk"`
+	Phase int `sql:"enum(Pending|Running|Done)"`
+}
+
+func (m *TestOrdinal) Pk() string {
+	return fmt.Sprintf("%d", m.ID)
+}
+
+func (m *TestOrdinal) String() string {
+	return m.Pk()
+}
+
+func (m *TestOrdinal) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestOrdinal) Labels() Labels {
+	return nil
+}
+
+type TestTenant struct {
+	ID     int               `sql:"pk"`
+	Tenant string            `sql:""`
+	Name   string            `sql:""`
+	Tags   map[string]string `sql:"map"`
+}
+
+func (m *TestTenant) Pk() string {
+	return fmt.Sprintf("%d", m.ID)
+}
+
+func (m *TestTenant) String() string {
+	return m.Pk()
+}
+
+func (m *TestTenant) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestTenant) Labels() Labels {
+	return nil
+}
+
+type TestFailing struct{}
+
+func (f TestFailing) Value() (driver.Value, error) {
+	return nil, errors.New("value failed")
+}
+
+func (f *TestFailing) Scan(v interface{}) error {
+	return nil
+}
+
+type TestBadKey struct {
+	PK  string      `sql:"pk"`
+	Key TestFailing `sql:"key"`
+}
+
+func (m *TestBadKey) Pk() string {
+	return m.PK
+}
+
+func (m *TestBadKey) String() string {
+	return m.PK
+}
+
+func (m *TestBadKey) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestBadKey) Labels() Labels {
+	return nil
+}
+
+type TestBadEnum struct {
+	ID    int  `sql:"pk"`
+	Phase bool `sql:"enum(On|Off)"`
+}
+
+func (m *TestBadEnum) Pk() string {
+	return fmt.Sprintf("%d", m.ID)
+}
+
+func (m *TestBadEnum) String() string {
+	return m.Pk()
+}
+
+func (m *TestBadEnum) Equals(other Model) bool {
+	return false
+}
+
+func (m *TestBadEnum) Labels() Labels {
+	return nil
+}
+
+// received event.
+type TestEvent struct {
+	action int8
+	model  *TestObject
+}
+
+type TestHandler struct {
+	name    string
+	started bool
+	all     []TestEvent
+	created []int
+	updated []int
+	deleted []int
+	err     []error
+	done    bool
+}
+
+func (w *TestHandler) Started() {
+	w.started = true
+}
+
+func (w *TestHandler) Created(e Event) {
+	if object, cast := e.Model.(*TestObject); cast {
+		w.all = append(w.all, TestEvent{action: e.Action, model: object})
+		w.created = append(w.created, object.ID)
+	}
+}
+
+func (w *TestHandler) Updated(e Event) {
+	if object, cast := e.Model.(*TestObject); cast {
+		w.all = append(w.all, TestEvent{action: e.Action, model: object})
+		w.updated = append(w.updated, object.ID)
+	}
+}
+func (w *TestHandler) Deleted(e Event) {
+	if object, cast := e.Model.(*TestObject); cast {
+		w.all = append(w.all, TestEvent{action: e.Action, model: object})
+		w.deleted = append(w.deleted, object.ID)
+	}
+}
+
+func (w *TestHandler) Error(err error) {
+	w.err = append(w.err, err)
+}
+
+func (w *TestHandler) End() {
+}
+
+type MutatingHandler struct {
+	DB
+	name    string
+	started bool
+	created []int
+	updated []int
+}
+
+func (w *MutatingHandler) Started() {
+	w.started = true
+}
+
+func (w *MutatingHandler) Created(e Event) {
+	tx, _ := w.DB.Begin()
+	tx.Get(e.Model)
+	e.Model.(*TestObject).Age++
+	tx.Update(e.Model)
+	tx.Commit()
+	w.created = append(w.created, e.Model.(*TestObject).ID)
+}
+
+func (w *MutatingHandler) Updated(e Event) {
+	tx, _ := w.DB.Begin()
+	tx.Get(e.Model)
+	e.Model.(*TestObject).Age++
+	tx.Update(e.Model)
+	tx.Commit()
+	w.updated = append(w.updated, e.Model.(*TestObject).ID)
+}
+
+func (w *MutatingHandler) Deleted(e Event) {
+}
+
+func (w *MutatingHandler) Error(err error) {
+	return
+}
+
+func (w *MutatingHandler) End() {
+}
+
+func TestCRUD(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&Label{},
+		&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	objA := &TestObject{
+		TestBase: TestBase{
+			Parent: 0,
+			Phone:  "1234",
+		},
+		ID:     0,
+		Name:   "Elmer",
+		Age:    18,
+		Int8:   8,
+		Int16:  16,
+		Int32:  32,
+		Bool:   true,
+		Object: TestEncoded{Name: "json"},
+		Slice:  []string{"hello", "world"},
+		Map:    map[string]int{"A": 1, "B": 2},
+		labels: Labels{
+			"n1": "v1",
+			"n2": "v2",
+		},
+	}
+	assertEqual := func(a, b *TestObject) {
+		g.Expect(a.PK).To(gomega.Equal(b.PK))
+		g.Expect(a.ID).To(gomega.Equal(b.ID))
+		g.Expect(a.Name).To(gomega.Equal(b.Name))
+		g.Expect(a.Age).To(gomega.Equal(b.Age))
+		g.Expect(a.Int8).To(gomega.Equal(b.Int8))
+		g.Expect(a.Int16).To(gomega.Equal(b.Int16))
+		g.Expect(a.Int32).To(gomega.Equal(b.Int32))
+		g.Expect(a.Bool).To(gomega.Equal(b.Bool))
+		g.Expect(a.Object).To(gomega.Equal(b.Object))
+		g.Expect(a.Slice).To(gomega.Equal(b.Slice))
+		g.Expect(a.Map).To(gomega.Equal(b.Map))
+		for k, v := range objA.labels {
+			l := &Label{
+				Kind:   ref.ToKind(a),
+				Parent: a.PK,
+				Name:   k,
+			}
+			g.Expect(DB.Get(l)).To(gomega.BeNil())
+			g.Expect(v).To(gomega.Equal(l.Value))
+		}
+	}
+	// Insert
+	err = DB.Insert(objA)
+	g.Expect(err).To(gomega.BeNil())
+	objB := &TestObject{ID: objA.ID}
+	// Get
+	err = DB.Get(objB)
+	g.Expect(err).To(gomega.BeNil())
+	assertEqual(objA, objB)
+	// Update
+	objA.Name = "Larry"
+	objA.Age = 21
+	objA.Bool = false
+	err = DB.Update(objA)
+	g.Expect(err).To(gomega.BeNil())
+	// Get
+	objB = &TestObject{ID: objA.ID}
+	err = DB.Get(objB)
+	g.Expect(err).To(gomega.BeNil())
+	assertEqual(objA, objB)
+	// Delete
+	objA = &TestObject{ID: objA.ID}
+	err = DB.Delete(objA)
+	g.Expect(err).To(gomega.BeNil())
+	// Get (not found)
+	objB = &TestObject{ID: objA.ID}
+	err = DB.Get(objB)
+	g.Expect(errors.Is(err, NotFound)).To(gomega.BeTrue())
+}
+
+func TestTransactions(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&Label{},
+		&TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	// Begin
+	tx, err := DB.Begin()
+	defer tx.End()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(tx.dbMutex).To(gomega.Equal(&DB.(*Client).dbMutex))
+	g.Expect(tx.journal).To(gomega.Equal(&DB.(*Client).journal))
+	object := &TestObject{
+		ID:   0,
+		Name: "Elmer",
+	}
+	err = tx.Insert(object)
+	g.Expect(err).To(gomega.BeNil())
+	// Get (not found)
+	object = &TestObject{ID: object.ID}
+	err = DB.Get(object)
+	g.Expect(errors.Is(err, NotFound)).To(gomega.BeTrue())
+	tx.Commit()
+	// Get (found)
+	object = &TestObject{ID: object.ID}
+	err = DB.Get(object)
+	g.Expect(err).To(gomega.BeNil())
+}
+
+func TestList(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&Label{},
+		&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	N := 10
+	for i := 0; i < N; i++ {
+		object := &TestObject{
+			ID:     i,
+			Name:   "Elmer",
+			Age:    18,
+			Int8:   8,
+			Int16:  16,
+			Int32:  32,
+			Bool:   true,
+			Object: TestEncoded{Name: "json"},
+			Slice:  []string{"hello", "world"},
+			Map:    map[string]int{"A": 1, "B": 2},
+			D4:     "d-4",
+			labels: Labels{
+				"id": fmt.Sprintf("v%d", i),
+			},
+		}
+		err = DB.Insert(object)
+		g.Expect(err).To(gomega.BeNil())
+	}
+	// List all; detail level=0
+	list := []TestObject{}
+	err = DB.List(&list, ListOptions{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(10))
+	g.Expect(list[0].Name).To(gomega.Equal(""))
+	g.Expect(list[0].D4).To(gomega.Equal(""))
+	// List detail level=1 (all)
+	list = []TestObject{}
+	err = DB.List(&list, ListOptions{Detail: 1})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(10))
+	g.Expect(list[0].Name).To(gomega.Equal("Elmer"))
+	g.Expect(list[0].D4).To(gomega.Equal("d-4"))
+	// List detail level=2
+	list = []TestObject{}
+	err = DB.List(&list, ListOptions{Detail: 2})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(10))
+	g.Expect(list[0].Name).To(gomega.Equal("Elmer"))
+	g.Expect(list[0].Slice).To(gomega.BeNil())
+	// List detail level=3
+	list = []TestObject{}
+	err = DB.List(&list, ListOptions{Detail: 3})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(10))
+	g.Expect(list[0].Name).To(gomega.Equal("Elmer"))
+	g.Expect(len(list[0].Slice)).To(gomega.Equal(2))
+	g.Expect(list[0].D4).To(gomega.Equal(""))
+	// List detail level=4
+	list = []TestObject{}
+	err = DB.List(&list, ListOptions{Detail: 4})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(10))
+	g.Expect(list[0].Name).To(gomega.Equal("Elmer"))
+	g.Expect(len(list[0].Slice)).To(gomega.Equal(2))
+	g.Expect(list[0].D4).To(gomega.Equal("d-4"))
+	// List = (single).
+	list = []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Predicate: Eq("ID", 0),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	g.Expect(list[0].ID).To(gomega.Equal(0))
+	// List != AND
+	list = []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Detail: 2,
+			Predicate: And( // Even only.
+				Neq("ID", 1),
+				Neq("ID", 3),
+				Neq("ID", 5),
+				Neq("ID", 7),
+				Neq("ID", 9)),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(5))
+	g.Expect(list[0].ID).To(gomega.Equal(0))
+	g.Expect(list[1].ID).To(gomega.Equal(2))
+	g.Expect(list[2].ID).To(gomega.Equal(4))
+	g.Expect(list[3].ID).To(gomega.Equal(6))
+	g.Expect(list[4].ID).To(gomega.Equal(8))
+	// List OR =.
+	list = []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Predicate: Or(
+				Eq("ID", 0),
+				Eq("ID", 6)),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	g.Expect(list[0].ID).To(gomega.Equal(0))
+	g.Expect(list[1].ID).To(gomega.Equal(6))
+	// List < (lt).
+	list = []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Predicate: Lt("ID", 2),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	g.Expect(list[0].ID).To(gomega.Equal(0))
+	g.Expect(list[1].ID).To(gomega.Equal(1))
+	// List > (gt).
+	list = []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Predicate: Gt("ID", 7),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	g.Expect(list[0].ID).To(gomega.Equal(8))
+	g.Expect(list[1].ID).To(gomega.Equal(9))
+	// List > (gt) virtual.
+	list = []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Predicate: Gt("RowID", N/2),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(N / 2))
+	g.Expect(list[0].RowID).To(gomega.Equal(int64(N/2) + 1))
+	// List (Eq) Field values.
+	list = []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Predicate: Eq("RowID", Field{Name: "int8"}),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	g.Expect(list[0].RowID).To(gomega.Equal(int64(8)))
+	// List (nEq) Field values.
+	list = []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Predicate: Neq("RowID", Field{Name: "int8"}),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(N - 1))
+	// List (Lt) Field values.
+	list = []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Predicate: Lt("int8", Field{Name: "int16"}),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(N))
+	// List (Gt) Field values.
+	list = []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Predicate: Gt("RowID", Field{Name: "int8"}),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	// By label.
+	list = []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Sort: []int{2},
+			Predicate: Or(
+				Match(Labels{"id": "v4"}),
+				Eq("ID", 8)),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	g.Expect(list[0].ID).To(gomega.Equal(4))
+	g.Expect(list[1].ID).To(gomega.Equal(8))
+	// Test count all.
+	count, err := DB.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(count).To(gomega.Equal(int64(10)))
+	// Test count with predicate.
+	count, err = DB.Count(&TestObject{}, Gt("ID", 0))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(count).To(gomega.Equal(int64(9)))
+}
+
+func TestNamer(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	namer := SnakeNamer{Plural: true}
+	// Names.
+	objectType := reflect.TypeOf(TestObject{})
+	g.Expect(namer.TableName(objectType)).To(gomega.Equal("test_objects"))
+	g.Expect(namer.TableName(reflect.TypeOf(Label{}))).To(gomega.Equal("labels"))
+	field, _ := objectType.FieldByName("Int8")
+	g.Expect(namer.ColumnName(field)).To(gomega.Equal("int8"))
+	g.Expect(namer.snake("VMHost")).To(gomega.Equal("vm_host"))
+	g.Expect(namer.plural("policy")).To(gomega.Equal("policies"))
+	g.Expect(namer.plural("class")).To(gomega.Equal("classes"))
+	// DDL.
+	ddl, err := Table{Namer: namer}.DDL(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("CREATE TABLE IF NOT EXISTS test_objects"))
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("phone TEXT"))
+	// CRUD.
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{},
+		&TestChild{})
+	DB.(*Client).Namer = namer
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	N := 10
+	for i := 0; i < N; i++ {
+		object := &TestObject{
+			ID:   i,
+			Name: "Elmer",
+			labels: Labels{
+				"id": fmt.Sprintf("v%d", i),
+			},
+		}
+		err = DB.Insert(object)
+		g.Expect(err).To(gomega.BeNil())
+		child := &TestChild{
+			Parent: object.PK,
+			Name:   "Bugs",
+		}
+		err = DB.Insert(child)
+		g.Expect(err).To(gomega.BeNil())
+	}
+	object := &TestObject{ID: 4}
+	err = DB.Get(object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(object.Name).To(gomega.Equal("Elmer"))
+	list := []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Predicate: Or(
+				Match(Labels{"id": "v4"}),
+				Eq("RowID", 8)),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	g.Expect(list[0].ID).To(gomega.Equal(4))
+	g.Expect(list[1].ID).To(gomega.Equal(7))
+	object.Age = 10
+	err = DB.Update(object)
+	g.Expect(err).To(gomega.BeNil())
+	// FK (cascade delete).
+	err = DB.Delete(object)
+	g.Expect(err).To(gomega.BeNil())
+	count, err := DB.Count(&TestChild{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(count).To(gomega.Equal(int64(N - 1)))
+}
+
+func TestListFilter(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	N := 10
+	for i := 0; i < N; i++ {
+		object := &TestObject{
+			ID:   i,
+			Name: "Elmer",
+			Age:  i % 2,
+			Bool: i%2 == 0,
+		}
+		if i > 5 {
+			object.Name = "Fudd"
+		}
+		err = DB.Insert(object)
+		g.Expect(err).To(gomega.BeNil())
+	}
+	// Filter.
+	list := []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Filter: &TestObject{Name: "Elmer", Age: 1},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(3))
+	g.Expect(list[0].ID).To(gomega.Equal(1))
+	g.Expect(list[1].ID).To(gomega.Equal(3))
+	g.Expect(list[2].ID).To(gomega.Equal(5))
+	// Zero values ignored.
+	list = []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Filter: &TestObject{Name: "Fudd", Bool: false},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(4))
+	// Filter AND predicate.
+	list = []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Filter: &TestObject{Name: "Fudd"},
+			Predicate: Or(
+				Eq("ID", 2),
+				Eq("ID", 7),
+				Eq("ID", 9)),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	g.Expect(list[0].ID).To(gomega.Equal(7))
+	g.Expect(list[1].ID).To(gomega.Equal(9))
+	// Filter AND predicate (explicit).
+	list = []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Filter:    &TestObject{Name: "Fudd"},
+			Predicate: Lt("ID", 8),
+			Combine:   CombineAnd,
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	g.Expect(list[0].ID).To(gomega.Equal(6))
+	g.Expect(list[1].ID).To(gomega.Equal(7))
+	// Filter OR predicate.
+	list = []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Filter:    &TestObject{Name: "Fudd"},
+			Predicate: Lt("ID", 2),
+			Combine:   CombineOr,
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(6))
+	g.Expect(list[0].ID).To(gomega.Equal(0))
+	g.Expect(list[1].ID).To(gomega.Equal(1))
+	g.Expect(list[2].ID).To(gomega.Equal(6))
+	// Filter (multiple fields) OR compound predicate.
+	list = []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Filter: &TestObject{Name: "Elmer", Age: 1},
+			Predicate: And(
+				Gt("ID", 6),
+				Neq("ID", 8)),
+			Combine: CombineOr,
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(5))
+	g.Expect(list[0].ID).To(gomega.Equal(1))
+	g.Expect(list[1].ID).To(gomega.Equal(3))
+	g.Expect(list[2].ID).To(gomega.Equal(5))
+	g.Expect(list[3].ID).To(gomega.Equal(7))
+	g.Expect(list[4].ID).To(gomega.Equal(9))
+	// Filter OR without predicate.
+	list = []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Filter:  &TestObject{Name: "Fudd"},
+			Combine: CombineOr,
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(4))
+	// Wrong type.
+	list = []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Filter: &Label{Name: "Fudd"},
+		})
+	g.Expect(errors.Is(err, FilterTypeErr)).To(gomega.BeTrue())
+}
+
+func TestCollation(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	// DDL.
+	ddl, err := Table{}.DDL(&TestCollate{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("Name TEXT NOT NULL COLLATE NOCASE"))
+	// Invalid.
+	type Invalid struct {
+		PK   string `sql:"pk"`
+		Name string `sql:"collate(french)"`
+	}
+	_, err = Table{}.DDL(&Invalid{})
+	g.Expect(errors.Is(err, CollationErr)).To(gomega.BeTrue())
+	type InvalidKind struct {
+		PK  string `sql:"pk"`
+		Age int    `sql:"collate(nocase)"`
+	}
+	_, err = Table{}.DDL(&InvalidKind{})
+	g.Expect(errors.Is(err, CollationErr)).To(gomega.BeTrue())
+	// Unique.
+	DB := New(
+		"/tmp/test.db",
+		&TestCollate{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&TestCollate{ID: 0, Name: "Foo"})
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&TestCollate{ID: 1, Name: "foo"})
+	g.Expect(err).ToNot(gomega.BeNil())
+	err = DB.Insert(&TestCollate{ID: 2, Name: "bar"})
+	g.Expect(err).To(gomega.BeNil())
+	count, err := DB.Count(&TestCollate{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(count).To(gomega.Equal(int64(2)))
+	// Predicate.
+	list := []TestCollate{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Predicate: Eq("Name", "FOO"),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	g.Expect(list[0].ID).To(gomega.Equal(0))
+}
+
+func TestGeneratedColumn(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	// DDL.
+	ddl, err := Table{}.DDL(&TestGenerated{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ddl[0]).To(gomega.ContainSubstring(
+		"Lower TEXT GENERATED ALWAYS AS (lower(Name)) STORED"))
+	g.Expect(ddl[0]).To(gomega.ContainSubstring(
+		"Length INTEGER GENERATED ALWAYS AS (length(Name)) VIRTUAL"))
+	// Invalid.
+	type Invalid struct {
+		PK  string `sql:"pk"`
+		Key string `sql:"key,generated(1, stored)"`
+	}
+	_, err = Table{}.DDL(&Invalid{})
+	g.Expect(errors.Is(err, GeneratedErr)).To(gomega.BeTrue())
+	// CRUD.
+	DB := New(
+		"/tmp/test.db",
+		&TestGenerated{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	object := &TestGenerated{
+		ID:     0,
+		Name:   "Elmer",
+		Lower:  "ignored",
+		Length: 100,
+	}
+	err = DB.Insert(object)
+	g.Expect(err).To(gomega.BeNil())
+	object = &TestGenerated{ID: 0}
+	err = DB.Get(object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(object.Lower).To(gomega.Equal("elmer"))
+	g.Expect(object.Length).To(gomega.Equal(5))
+	object.Name = "Bugs Bunny"
+	object.Lower = "ignored"
+	err = DB.Update(object)
+	g.Expect(err).To(gomega.BeNil())
+	list := []TestGenerated{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Detail:    1,
+			Predicate: Eq("Lower", "bugs bunny"),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	g.Expect(list[0].Name).To(gomega.Equal("Bugs Bunny"))
+	g.Expect(list[0].Length).To(gomega.Equal(10))
+}
+
+func TestScanColumns(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	object := &TestObject{
+		ID:   4,
+		Name: "Elmer",
+		Age:  18,
+	}
+	err = DB.Insert(object)
+	g.Expect(err).To(gomega.BeNil())
+	// Column added out of band.
+	db := DB.(*Client).db
+	_, err = db.Exec("ALTER TABLE TestObject ADD COLUMN Extra TEXT DEFAULT 'x'")
+	g.Expect(err).To(gomega.BeNil())
+	object = &TestObject{ID: 4}
+	err = DB.Get(object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(object.Name).To(gomega.Equal("Elmer"))
+	// Columns (extra, missing) not in field order.
+	cursor, err := db.Query("SELECT Extra, Age, ID, Name FROM TestObject")
+	g.Expect(err).To(gomega.BeNil())
+	defer cursor.Close()
+	g.Expect(cursor.Next()).To(gomega.BeTrue())
+	object = &TestObject{Int8: 8}
+	table := Table{}
+	fields, err := table.Fields(object)
+	g.Expect(err).To(gomega.BeNil())
+	err = table.scan(cursor, fields)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(object.ID).To(gomega.Equal(4))
+	g.Expect(object.Name).To(gomega.Equal("Elmer"))
+	g.Expect(object.Age).To(gomega.Equal(18))
+	g.Expect(object.Int8).To(gomega.Equal(int8(8)))
+	// Not found.
+	object = &TestObject{ID: 5}
+	err = DB.Get(object)
+	g.Expect(errors.Is(err, NotFound)).To(gomega.BeTrue())
+}
+
+func TestValidate(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	// No fields.
+	type NoFields struct {
+		PK   string
+		Name string
+	}
+	_, err = Table{}.DDL(&NoFields{})
+	g.Expect(errors.Is(err, NoFieldsErr)).To(gomega.BeTrue())
+	// No PK.
+	type NoPk struct {
+		Name string `sql:""`
+	}
+	_, err = Table{}.DDL(&NoPk{})
+	g.Expect(errors.Is(err, MustHavePkErr)).To(gomega.BeTrue())
+}
+
+func TestUniqueKey(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&TestChild{},
+		&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	object := &TestObject{ID: 0}
+	err = DB.Insert(object)
+	g.Expect(err).To(gomega.BeNil())
+	// Unique.
+	ddl, err := Table{}.DDL(&TestChild{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(ddl)).To(gomega.Equal(3))
+	g.Expect(ddl[2]).To(gomega.ContainSubstring(
+		"CREATE UNIQUE INDEX IF NOT EXISTS TestChildKeyIndex"))
+	err = DB.Insert(
+		&TestChild{
+			PK:     "A",
+			Parent: object.PK,
+			Name:   "Bugs",
+		})
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(
+		&TestChild{
+			PK:     "B",
+			Parent: object.PK,
+			Name:   "Bugs",
+		})
+	g.Expect(err).ToNot(gomega.BeNil())
+	// Not unique.
+	type NonUnique struct {
+		PK   string `sql:"pk"`
+		Name string `sql:"key,nonunique"`
+	}
+	ddl, err = Table{}.DDL(&NonUnique{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(ddl)).To(gomega.Equal(2))
+	table := Table{DB: DB.(*Client).db}
+	for _, stmt := range ddl {
+		_, err = table.DB.Exec(stmt)
+		g.Expect(err).To(gomega.BeNil())
+	}
+	err = table.Insert(&NonUnique{PK: "A", Name: "Bugs"})
+	g.Expect(err).To(gomega.BeNil())
+	err = table.Insert(&NonUnique{PK: "B", Name: "Bugs"})
+	g.Expect(err).To(gomega.BeNil())
+}
+
+func TestExists(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{},
+		&TestChild{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	N := 10
+	for i := 0; i < N; i++ {
+		object := &TestObject{
+			ID:   i,
+			Name: "Elmer",
+		}
+		err = DB.Insert(object)
+		g.Expect(err).To(gomega.BeNil())
+		for n := 0; n < i%3; n++ {
+			child := &TestChild{
+				Parent: object.PK,
+				Name:   fmt.Sprintf("c%d", n),
+				Age:    i,
+			}
+			err = DB.Insert(child)
+			g.Expect(err).To(gomega.BeNil())
+		}
+	}
+	// Has children.
+	list := []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Predicate: Exists(&TestChild{}, "Parent", nil),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(6))
+	// Has matching children.
+	list = []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Predicate: And(
+				Lt("ID", 8),
+				Exists(
+					&TestChild{},
+					"Parent",
+					And(
+						Gt("Age", 3),
+						Eq("Name", "c1")))),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	g.Expect(list[0].ID).To(gomega.Equal(5))
+	// Invalid field.
+	list = []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Predicate: Exists(&TestChild{}, "Unknown", nil),
+		})
+	g.Expect(errors.Is(err, PredicateRefErr)).To(gomega.BeTrue())
+}
+
+func TestForeignKeys(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{},
+		&TestChild{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	// Enforced.
+	err = DB.Insert(&TestChild{Parent: "none", Name: "A"})
+	g.Expect(err).ToNot(gomega.BeNil())
+	// Disabled.
+	err = DB.SetForeignKeys(false)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&TestChild{Parent: "none", Name: "B"})
+	g.Expect(err).To(gomega.BeNil())
+	// Enabled.
+	err = DB.SetForeignKeys(true)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&TestChild{Parent: "none", Name: "C"})
+	g.Expect(err).ToNot(gomega.BeNil())
+	// Connection in use (stale) is discarded when returned.
+	db, err := DB.(*Client).pool()
+	g.Expect(err).To(gomega.BeNil())
+	conn, err := db.Conn(context.TODO())
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.SetForeignKeys(false)
+	g.Expect(err).To(gomega.BeNil())
+	_ = conn.Close()
+	g.Expect(db.Stats().Idle).To(gomega.Equal(0))
+	err = DB.Insert(&TestChild{Parent: "none", Name: "E"})
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.SetForeignKeys(true)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Close(false)
+	g.Expect(err).To(gomega.BeNil())
+	// Disabled on open.
+	DB = New(
+		"/tmp/test.db",
+		&TestObject{},
+		&TestChild{})
+	DB.(*Client).DisableForeignKeys = true
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&TestChild{Parent: "none", Name: "D"})
+	g.Expect(err).To(gomega.BeNil())
+}
+
+func TestDeferredForeignKeys(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	ddl, err := Table{}.DDL(&TestDeferred{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ddl[0]).To(gomega.ContainSubstring(
+		"REFERENCES TestObject (PK) ON DELETE CASCADE DEFERRABLE INITIALLY DEFERRED"))
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{},
+		&TestDeferred{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	// Child inserted before parent.
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Insert(&TestDeferred{Parent: "P1", Name: "A"})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Insert(&TestObject{PK: "P1", ID: 1})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+	// Violated on commit.
+	tx, err = DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Insert(&TestDeferred{Parent: "P2", Name: "B"})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Commit()
+	g.Expect(err).ToNot(gomega.BeNil())
+	count, err := DB.Count(&TestDeferred{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(count).To(gomega.Equal(int64(1)))
+	// Client still usable.
+	err = DB.Insert(&TestDeferred{Parent: "P1", Name: "C"})
+	g.Expect(err).To(gomega.BeNil())
+	tx, err = DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Insert(&TestObject{PK: "P2", ID: 2})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+	count, err = DB.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(count).To(gomega.Equal(int64(2)))
+}
+
+func TestAnalyze(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{},
+		&TestChild{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	for i := 0; i < 10; i++ {
+		err = DB.Insert(&TestObject{ID: i})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	stats, err := DB.PlannerStats(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(stats)).To(gomega.Equal(0))
+	err = DB.Analyze(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	stats, err = DB.PlannerStats(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	indexes := map[string]string{}
+	for _, stat := range stats {
+		indexes[stat.Index] = stat.Stat
+	}
+	g.Expect(indexes["TestObjectIndex"]).To(gomega.Equal("10 1"))
+	err = DB.AnalyzeAll()
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Reindex(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+}
+
+func TestPage(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{})
+	DB.(*Client).MaxPageLimit = 3
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	for i := 0; i < 10; i++ {
+		err = DB.Insert(&TestObject{ID: i})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	// Valid.
+	list := []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Page: &Page{Offset: 2, Limit: 2},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	g.Expect(list[0].ID).To(gomega.Equal(2))
+	// Capped.
+	page := &Page{Limit: 100}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Page: page,
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(3))
+	g.Expect(page.Limit).To(gomega.Equal(100))
+	// Invalid.
+	for _, page := range []*Page{
+		{Offset: -1, Limit: 10},
+		{Offset: 0, Limit: -1},
+		{Offset: 0, Limit: 0},
+	} {
+		err = DB.List(
+			&list,
+			ListOptions{
+				Page: page,
+			})
+		g.Expect(errors.Is(err, InvalidPageErr)).To(gomega.BeTrue())
+	}
+}
+
+func TestPageParams(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	options := &ListOptions{
+		Page:      &Page{Offset: 4, Limit: 2},
+		Predicate: Eq("ID", 1),
+	}
+	table := Table{}
+	fields, err := table.Fields(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	stmt, err := table.listSQL("TestObject", fields, options)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(stmt).To(gomega.ContainSubstring("LIMIT :limit0 OFFSET :offset1"))
+	params := options.Params()
+	g.Expect(len(params)).To(gomega.Equal(3))
+	g.Expect(params[0]).To(gomega.Equal(sql.Named("limit0", 2)))
+	g.Expect(params[1]).To(gomega.Equal(sql.Named("offset1", 4)))
+}
+
+func TestEnumField(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	// DDL.
+	ddl, err := Table{}.DDL(&TestEnum{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ddl[0]).To(gomega.ContainSubstring(
+		"CHECK (Phase IN ('Pending','Running','Failed'))"))
+	type Invalid struct {
+		PK    string `sql:"pk"`
+		Ready bool   `sql:"enum(1|2)"`
+	}
+	_, err = Table{}.DDL(&Invalid{})
+	g.Expect(errors.Is(err, EnumErr)).To(gomega.BeTrue())
+	type Empty struct {
+		PK    string `sql:"pk"`
+		Phase string `sql:"enum()"`
+	}
+	_, err = Table{}.DDL(&Empty{})
+	g.Expect(errors.Is(err, EnumErr)).To(gomega.BeTrue())
+	// Insert/Update.
+	DB := New(
+		"/tmp/test.db",
+		&TestEnum{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	object := &TestEnum{ID: 0, Phase: "Pending"}
+	err = DB.Insert(object)
+	g.Expect(err).To(gomega.BeNil())
+	object.Phase = "Running"
+	err = DB.Update(object)
+	g.Expect(err).To(gomega.BeNil())
+	object.Phase = "Unknown"
+	err = DB.Update(object)
+	enumErr := &EnumError{}
+	g.Expect(errors.As(err, &enumErr)).To(gomega.BeTrue())
+	g.Expect(enumErr.Field).To(gomega.Equal("Phase"))
+	g.Expect(enumErr.Value).To(gomega.Equal("Unknown"))
+	err = DB.Insert(&TestEnum{ID: 1, Phase: "Unknown"})
+	g.Expect(errors.As(err, &enumErr)).To(gomega.BeTrue())
+	// Checked by the DB.
+	db := DB.(*Client).db
+	_, err = db.Exec("UPDATE TestEnum SET Phase = 'Unknown'")
+	g.Expect(err).ToNot(gomega.BeNil())
+}
+
+func TestListMaps(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	for i := 0; i < 3; i++ {
+		object := &TestObject{
+			ID:     i,
+			Name:   "Elmer",
+			Bool:   true,
+			Object: TestEncoded{Name: "json"},
+			Slice:  []string{"hello", "world"},
+			Map:    map[string]int{"A": 1},
+		}
+		err = DB.Insert(object)
+		g.Expect(err).To(gomega.BeNil())
+	}
+	list, err := DB.ListMaps(
+		&TestObject{},
+		ListOptions{
+			Detail:    1,
+			Predicate: Gt("ID", 0),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	m := list[0]
+	g.Expect(m["ID"]).To(gomega.Equal(1))
+	g.Expect(m["Name"]).To(gomega.Equal("Elmer"))
+	g.Expect(m["Bool"]).To(gomega.Equal(true))
+	g.Expect(m["Object"]).To(gomega.Equal(map[string]interface{}{"Name": "json"}))
+	g.Expect(m["Slice"]).To(gomega.Equal([]interface{}{"hello", "world"}))
+	g.Expect(m["Map"]).To(gomega.Equal(map[string]interface{}{"A": float64(1)}))
+	// Detail.
+	list, err = DB.ListMaps(&TestObject{}, ListOptions{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(3))
+	_, found := list[0]["Name"]
+	g.Expect(found).To(gomega.BeFalse())
+}
+
+func TestExportImportJSON(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	for i := 0; i < 3; i++ {
+		object := &TestObject{
+			ID:     i,
+			Name:   "Elmer",
+			Bool:   true,
+			Object: TestEncoded{Name: "json"},
+			Slice:  []string{"hello", "world"},
+			Map:    map[string]int{"A": 1},
+			D4:     "d-4",
+		}
+		err = DB.Insert(object)
+		g.Expect(err).To(gomega.BeNil())
+	}
+	// Export.
+	bfr := &bytes.Buffer{}
+	err = DB.ExportJSON(
+		&TestObject{},
+		bfr,
+		ListOptions{
+			Detail:    4,
+			Predicate: Gt("ID", 0),
+			Sort:      []int{5},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	exported := []map[string]interface{}{}
+	err = json.Unmarshal(bfr.Bytes(), &exported)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(exported)).To(gomega.Equal(2))
+	g.Expect(exported[0]["Object"]).To(
+		gomega.Equal(map[string]interface{}{"Name": "json"}))
+	// Import (round trip).
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.ImportJSON(&TestObject{}, bytes.NewReader(bfr.Bytes()))
+	g.Expect(err).To(gomega.BeNil())
+	list := []TestObject{}
+	err = DB.List(&list, ListOptions{Detail: 4})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	g.Expect(list[0].ID).To(gomega.Equal(1))
+	g.Expect(list[0].Name).To(gomega.Equal("Elmer"))
+	g.Expect(list[0].Bool).To(gomega.BeTrue())
+	g.Expect(list[0].Object.Name).To(gomega.Equal("json"))
+	g.Expect(list[0].Slice).To(gomega.Equal([]string{"hello", "world"}))
+	g.Expect(list[0].Map).To(gomega.Equal(map[string]int{"A": 1}))
+	g.Expect(list[0].D4).To(gomega.Equal("d-4"))
+	// Import (upsert).
+	err = DB.ImportJSON(
+		&TestObject{},
+		strings.NewReader(`[{"ID": 1, "Name": "Bugs"}]`))
+	g.Expect(err).To(gomega.BeNil())
+	list = []TestObject{}
+	err = DB.List(&list, ListOptions{Detail: 1})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	g.Expect(list[0].Name).To(gomega.Equal("Bugs"))
+	// All fields (default).
+	bfr = &bytes.Buffer{}
+	err = DB.ExportJSON(&TestObject{}, bfr, ListOptions{})
+	g.Expect(err).To(gomega.BeNil())
+	exported = []map[string]interface{}{}
+	err = json.Unmarshal(bfr.Bytes(), &exported)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(exported)).To(gomega.Equal(2))
+	g.Expect(exported[0]["Name"]).To(gomega.Equal("Bugs"))
+	g.Expect(exported[1]["D4"]).To(gomega.Equal("d-4"))
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.ImportJSON(&TestObject{}, bytes.NewReader(bfr.Bytes()))
+	g.Expect(err).To(gomega.BeNil())
+	list = []TestObject{}
+	err = DB.List(&list, ListOptions{Detail: 4, Sort: []int{5}})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	g.Expect(list[0].Name).To(gomega.Equal("Bugs"))
+	g.Expect(list[1].Object.Name).To(gomega.Equal("json"))
+	g.Expect(list[1].D4).To(gomega.Equal("d-4"))
+	// Not an array.
+	err = DB.ImportJSON(&TestObject{}, strings.NewReader(`{}`))
+	g.Expect(err).ToNot(gomega.BeNil())
+}
+
+func TestExportImportCSV(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	for i := 0; i < 3; i++ {
+		object := &TestObject{
+			ID:     i,
+			Name:   "Elmer, \"Fudd\"",
+			Bool:   true,
+			Int8:   -8,
+			Object: TestEncoded{Name: "json"},
+			Slice:  []string{"hello", "world"},
+			Map:    map[string]int{"A": 1},
+			D4:     "d-4",
+		}
+		err = DB.Insert(object)
+		g.Expect(err).To(gomega.BeNil())
+	}
+	// Export.
+	bfr := &bytes.Buffer{}
+	err = DB.ExportCSV(
+		&TestObject{},
+		bfr,
+		ListOptions{
+			Detail:    4,
+			Predicate: Gt("ID", 0),
+			Sort:      []int{5},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	lines := strings.Split(strings.TrimSpace(bfr.String()), "\n")
+	g.Expect(len(lines)).To(gomega.Equal(3))
+	g.Expect(lines[0]).To(gomega.HavePrefix("Parent,Phone,RowID,PK,ID,Name"))
+	g.Expect(lines[1]).To(gomega.ContainSubstring(`"Elmer, ""Fudd"""`))
+	g.Expect(lines[1]).To(gomega.ContainSubstring(`"{""Name"":""json""}"`))
+	// Import (round trip).
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.ImportCSV(&TestObject{}, bytes.NewReader(bfr.Bytes()))
+	g.Expect(err).To(gomega.BeNil())
+	list := []TestObject{}
+	err = DB.List(&list, ListOptions{Detail: 4})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	g.Expect(list[0].ID).To(gomega.Equal(1))
+	g.Expect(list[0].Name).To(gomega.Equal("Elmer, \"Fudd\""))
+	g.Expect(list[0].Bool).To(gomega.BeTrue())
+	g.Expect(list[0].Int8).To(gomega.Equal(int8(-8)))
+	g.Expect(list[0].Object.Name).To(gomega.Equal("json"))
+	g.Expect(list[0].Slice).To(gomega.Equal([]string{"hello", "world"}))
+	g.Expect(list[0].Map).To(gomega.Equal(map[string]int{"A": 1}))
+	g.Expect(list[0].D4).To(gomega.Equal("d-4"))
+	// All fields (default).
+	bfr = &bytes.Buffer{}
+	err = DB.ExportCSV(&TestObject{}, bfr, ListOptions{})
+	g.Expect(err).To(gomega.BeNil())
+	lines = strings.Split(strings.TrimSpace(bfr.String()), "\n")
+	g.Expect(len(lines)).To(gomega.Equal(3))
+	g.Expect(lines[0]).To(gomega.HavePrefix("Parent,Phone,RowID,PK,ID,Name"))
+	g.Expect(lines[0]).To(gomega.HaveSuffix("D4"))
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.ImportCSV(&TestObject{}, bytes.NewReader(bfr.Bytes()))
+	g.Expect(err).To(gomega.BeNil())
+	list = []TestObject{}
+	err = DB.List(&list, ListOptions{Detail: 4, Sort: []int{5}})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	g.Expect(list[0].Name).To(gomega.Equal("Elmer, \"Fudd\""))
+	g.Expect(list[0].Object.Name).To(gomega.Equal("json"))
+	g.Expect(list[0].D4).To(gomega.Equal("d-4"))
+	// Invalid value.
+	err = DB.ImportCSV(
+		&TestObject{},
+		strings.NewReader("ID,Bool\n1,maybe\n"))
+	g.Expect(err).ToNot(gomega.BeNil())
+}
+
+func TestDescribe(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{},
+		&TestChild{})
+	DB.(*Client).Namer = SnakeNamer{Plural: true}
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	list, err := DB.Describe()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(3))
+	child := list[1]
+	g.Expect(child.Name).To(gomega.Equal("test_childs"))
+	g.Expect(child.Model).To(gomega.Equal("TestChild"))
+	g.Expect(len(child.Columns)).To(gomega.Equal(4))
+	pk := child.Columns[0]
+	g.Expect(pk.Name).To(gomega.Equal("pk"))
+	g.Expect(pk.Pk).To(gomega.BeTrue())
+	g.Expect(pk.Type).To(gomega.Equal("TEXT"))
+	parent := child.Columns[1]
+	g.Expect(parent.Field).To(gomega.Equal("Parent"))
+	g.Expect(parent.Key).To(gomega.BeTrue())
+	g.Expect(parent.Fk).To(gomega.Equal(&FK{Table: "test_objects", Field: "pk"}))
+	g.Expect(parent.Doc).To(gomega.Equal("The parent (object), see: TestObject."))
+	age := child.Columns[3]
+	g.Expect(age.Type).To(gomega.Equal("INTEGER"))
+	g.Expect(age.Fk).To(gomega.BeNil())
+	g.Expect(age.Doc).To(gomega.Equal(""))
+	// Serializable.
+	_, err = json.Marshal(list)
+	g.Expect(err).To(gomega.BeNil())
+}
+
+func TestClose(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{})
+	// Not opened.
+	err = DB.Get(&TestObject{ID: 0})
+	g.Expect(errors.Is(err, ClosedErr)).To(gomega.BeTrue())
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	// Concurrent writes and close.
+	done := make(chan error, 20)
+	for i := 0; i < 10; i++ {
+		go func(id int) {
+			done <- DB.Insert(&TestObject{ID: id})
+		}(i)
+		go func() {
+			done <- DB.Close(false)
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		err = <-done
+		if err != nil {
+			g.Expect(errors.Is(err, ClosedErr)).To(gomega.BeTrue())
+		}
+	}
+	// Transaction open.
+	err = DB.Open(false)
+	g.Expect(err).To(gomega.BeNil())
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Close(false)
+	g.Expect(errors.Is(err, TxOpenErr)).To(gomega.BeTrue())
+	err = tx.Rollback()
+	g.Expect(err).To(gomega.BeNil())
+	// Closed.
+	err = DB.Close(false)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&TestObject{ID: 0})
+	g.Expect(errors.Is(err, ClosedErr)).To(gomega.BeTrue())
+	err = DB.Update(&TestObject{ID: 0})
+	g.Expect(errors.Is(err, ClosedErr)).To(gomega.BeTrue())
+	err = DB.Delete(&TestObject{ID: 0})
+	g.Expect(errors.Is(err, ClosedErr)).To(gomega.BeTrue())
+	err = DB.Get(&TestObject{ID: 0})
+	g.Expect(errors.Is(err, ClosedErr)).To(gomega.BeTrue())
+	err = DB.List(&[]TestObject{}, ListOptions{})
+	g.Expect(errors.Is(err, ClosedErr)).To(gomega.BeTrue())
+	_, err = DB.Count(&TestObject{}, nil)
+	g.Expect(errors.Is(err, ClosedErr)).To(gomega.BeTrue())
+	_, err = DB.Begin()
+	g.Expect(errors.Is(err, ClosedErr)).To(gomega.BeTrue())
+	_, err = DB.Watch(&TestObject{}, &TestHandler{})
+	g.Expect(errors.Is(err, ClosedErr)).To(gomega.BeTrue())
+	// Reopen.
+	err = DB.Open(false)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&TestObject{ID: 100})
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Close(true)
+	g.Expect(err).To(gomega.BeNil())
+}
+
+func TestTxRollback(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Insert(&TestObject{ID: 0})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Rollback()
+	g.Expect(err).To(gomega.BeNil())
+	// Already ended.
+	err = tx.Rollback()
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.End()
+	g.Expect(err).To(gomega.BeNil())
+	// Discarded.
+	n, err := DB.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(0)))
+	// Not deadlocked.
+	err = DB.Insert(&TestObject{ID: 1})
+	g.Expect(err).To(gomega.BeNil())
+}
+
+func TestTxEnd(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	// Double commit.
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Insert(&TestObject{ID: 0})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Commit()
+	g.Expect(errors.Is(err, TxEndedErr)).To(gomega.BeTrue())
+	err = tx.Rollback()
+	g.Expect(err).To(gomega.BeNil())
+	// Commit after rollback.
+	tx, err = DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Insert(&TestObject{ID: 1})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Rollback()
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Commit()
+	g.Expect(errors.Is(err, TxEndedErr)).To(gomega.BeTrue())
+	n, err := DB.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(1)))
+	// Concurrent commit and rollback.
+	tx, err = DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	done := make(chan error, 4)
+	for i := 0; i < 2; i++ {
+		go func() {
+			done <- tx.Commit()
+		}()
+		go func() {
+			done <- tx.Rollback()
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+	// Lock released (once).
+	err = DB.Insert(&TestObject{ID: 2})
+	g.Expect(err).To(gomega.BeNil())
+	tx, err = DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+}
+
+func TestTxContext(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	// Deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	tx, err := DB.BeginTx(ctx)
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Insert(&TestObject{ID: 0})
+	g.Expect(err).To(gomega.BeNil())
+	<-ctx.Done()
+	// Client (mutex) freed by the automatic rollback.
+	err = DB.Insert(&TestObject{ID: 1})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Commit()
+	g.Expect(errors.Is(err, TxEndedErr)).To(gomega.BeTrue())
+	n, err := DB.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(1)))
+	// Committed before cancelled.
+	ctx, cancel = context.WithCancel(context.Background())
+	tx, err = DB.BeginTx(ctx)
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Insert(&TestObject{ID: 2})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+	cancel()
+	n, err = DB.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(2)))
+	// Already cancelled.
+	_, err = DB.BeginTx(ctx)
+	g.Expect(err).ToNot(gomega.BeNil())
+	err = DB.Insert(&TestObject{ID: 3})
+	g.Expect(err).To(gomega.BeNil())
+}
+
+func TestAttach(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	_ = os.Remove("/tmp/archive.db")
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Attach("archive.x", "/tmp/archive.db")
+	g.Expect(errors.Is(err, AliasErr)).To(gomega.BeTrue())
+	// Connection in use (stale) is discarded when returned.
+	db, err := DB.(*Client).pool()
+	g.Expect(err).To(gomega.BeNil())
+	conn, err := db.Conn(context.TODO())
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Attach("archive", "/tmp/archive.db", &TestCollate{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(DB.(*Client).table(nil).Name(&TestCollate{})).To(
+		gomega.Equal("archive.TestCollate"))
+	_ = conn.Close()
+	conns := []*sql.Conn{}
+	for i := 0; i < 2; i++ {
+		conn, err = db.Conn(context.TODO())
+		g.Expect(err).To(gomega.BeNil())
+		conns = append(conns, conn)
+		attached := 0
+		err = conn.QueryRowContext(
+			context.TODO(),
+			"SELECT COUNT(*) FROM pragma_database_list WHERE name = 'archive'").Scan(&attached)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(attached).To(gomega.Equal(1))
+	}
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	for i := 0; i < 3; i++ {
+		err = DB.Insert(&TestObject{PK: fmt.Sprintf("P%d", i), ID: i})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	for i := 1; i < 3; i++ {
+		err = DB.Insert(&TestCollate{PK: fmt.Sprintf("P%d", i), ID: i, Name: fmt.Sprintf("N%d", i)})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	err = DB.Update(&TestCollate{PK: "P1", ID: 1, Name: "Elmer"})
+	g.Expect(err).To(gomega.BeNil())
+	// List/Count (attached).
+	list := []TestCollate{}
+	err = DB.List(&list, ListOptions{Detail: 1, Predicate: Eq("Name", "elmer")})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	n, err := DB.Count(&TestCollate{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(2)))
+	// Across databases.
+	objects := []TestObject{}
+	err = DB.List(
+		&objects,
+		ListOptions{
+			Predicate: Exists(&TestCollate{}, "PK", Gt("ID", 1)),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(objects)).To(gomega.Equal(1))
+	g.Expect(objects[0].PK).To(gomega.Equal("P2"))
+	// Transaction.
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Delete(&TestCollate{PK: "P2"})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+	// Detach.
+	err = DB.Detach("archive")
+	g.Expect(err).To(gomega.BeNil())
+	_, err = DB.Count(&TestCollate{}, nil)
+	g.Expect(err).ToNot(gomega.BeNil())
+	// Stored in the attached database.
+	archive := New("/tmp/archive.db", &TestCollate{})
+	err = archive.Open(false)
+	g.Expect(err).To(gomega.BeNil())
+	n, err = archive.Count(&TestCollate{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(1)))
+	_ = archive.Close(true)
+}
+
+func TestMigrate(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	// None run; no side effect.
+	versions, err := DB.Migrations()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(versions).To(gomega.Equal([]int{}))
+	tables := 0
+	err = DB.(*Client).db.QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE name = 'schema_migrations'").Scan(&tables)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(tables).To(gomega.Equal(0))
+	applied := []int{}
+	migrations := []Migration{
+		{
+			Version: 2,
+			Up: func(tx *Tx) error {
+				applied = append(applied, 2)
+				return tx.Insert(&TestObject{ID: 2})
+			},
+		},
+		{
+			Version: 1,
+			Up: func(tx *Tx) error {
+				applied = append(applied, 1)
+				_, err := tx.Exec("ALTER TABLE TestObject ADD COLUMN Extra TEXT")
+				return err
+			},
+		},
+	}
+	err = DB.Migrate(migrations)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(applied).To(gomega.Equal([]int{1, 2}))
+	versions, err = DB.Migrations()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(versions).To(gomega.Equal([]int{1, 2}))
+	// Already applied.
+	err = DB.Close(false)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Open(false)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Migrate(migrations)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(applied).To(gomega.Equal([]int{1, 2}))
+	n, err := DB.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(1)))
+	// Failed.
+	failed := errors.New("failed")
+	err = DB.Migrate(
+		append(
+			migrations,
+			Migration{
+				Version: 3,
+				Up: func(tx *Tx) error {
+					err := tx.Insert(&TestObject{ID: 3})
+					if err != nil {
+						return err
+					}
+					return failed
+				},
+			}))
+	g.Expect(errors.Is(err, failed)).To(gomega.BeTrue())
+	versions, err = DB.Migrations()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(versions).To(gomega.Equal([]int{1, 2}))
+	n, err = DB.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(1)))
+	// Duplicate version.
+	err = DB.Migrate(append(migrations, Migration{Version: 1}))
+	g.Expect(errors.Is(err, MigrationVersionErr)).To(gomega.BeTrue())
+}
+
+func TestEncrypt(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	k1 := []byte("0123456789abcdef")
+	k2 := []byte("fedcba9876543210fedcba9876543210")
+	// DDL.
+	type Invalid struct {
+		PK  string `sql:"pk"`
+		Age int    `sql:"encrypt"`
+	}
+	_, err = Table{}.DDL(&Invalid{})
+	g.Expect(errors.Is(err, EncryptErr)).To(gomega.BeTrue())
+	type InvalidKey struct {
+		PK   string `sql:"pk"`
+		Name string `sql:"key,encrypt"`
+	}
+	_, err = Table{}.DDL(&InvalidKey{})
+	g.Expect(errors.Is(err, EncryptErr)).To(gomega.BeTrue())
+	_, err = NewKeyring([]byte("short"))
+	g.Expect(err).ToNot(gomega.BeNil())
+	// Missing keyring.
+	DB := New(
+		"/tmp/test.db",
+		&TestSecret{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&TestSecret{ID: 1, Token: "secret"})
+	g.Expect(errors.Is(err, KeyErr)).To(gomega.BeTrue())
+	err = DB.Get(&TestSecret{ID: 1})
+	g.Expect(errors.Is(err, KeyErr)).To(gomega.BeTrue())
+	err = DB.List(&[]TestSecret{}, ListOptions{})
+	g.Expect(errors.Is(err, KeyErr)).To(gomega.BeTrue())
+	// Round trip.
+	keyring, err := NewKeyring(k1)
+	g.Expect(err).To(gomega.BeNil())
+	DB.SetKeyring(keyring)
+	object := &TestSecret{
+		ID:    1,
+		Token: "secret",
+		Spec:  TestEncoded{Name: "hidden"},
+	}
+	err = DB.Insert(object)
+	g.Expect(err).To(gomega.BeNil())
+	object = &TestSecret{ID: 1}
+	err = DB.Get(object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(object.Token).To(gomega.Equal("secret"))
+	g.Expect(object.Spec.Name).To(gomega.Equal("hidden"))
+	list := []TestSecret{}
+	err = DB.List(&list, ListOptions{Detail: 1})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	g.Expect(list[0].Token).To(gomega.Equal("secret"))
+	g.Expect(list[0].Spec.Name).To(gomega.Equal("hidden"))
+	maps, err := DB.ListMaps(&TestSecret{}, ListOptions{Detail: 1})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(maps[0]["Token"]).To(gomega.Equal("secret"))
+	g.Expect(maps[0]["Spec"]).To(gomega.Equal(map[string]interface{}{"Name": "hidden"}))
+	// Stored encrypted.
+	raw := ""
+	row := DB.(*Client).db.QueryRow("SELECT Token FROM TestSecret")
+	err = row.Scan(&raw)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(raw).ToNot(gomega.BeEmpty())
+	g.Expect(raw).ToNot(gomega.ContainSubstring("secret"))
+	// Predicate.
+	err = DB.List(&list, ListOptions{Predicate: Eq("Token", "secret")})
+	g.Expect(errors.Is(err, PredicateEncryptedErr)).To(gomega.BeTrue())
+	// Rotation.
+	keyring, err = NewKeyring(k2, k1)
+	g.Expect(err).To(gomega.BeNil())
+	DB.SetKeyring(keyring)
+	object = &TestSecret{ID: 1}
+	err = DB.Get(object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(object.Token).To(gomega.Equal("secret"))
+	err = DB.Update(object)
+	g.Expect(err).To(gomega.BeNil())
+	keyring, err = NewKeyring(k2)
+	g.Expect(err).To(gomega.BeNil())
+	DB.SetKeyring(keyring)
+	object = &TestSecret{ID: 1}
+	err = DB.Get(object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(object.Token).To(gomega.Equal("secret"))
+	// Wrong key.
+	keyring, err = NewKeyring(k1)
+	g.Expect(err).To(gomega.BeNil())
+	DB.SetKeyring(keyring)
+	object = &TestSecret{ID: 1}
+	err = DB.Get(object)
+	g.Expect(errors.Is(err, DecryptErr)).To(gomega.BeTrue())
+	codecErr := &CodecError{}
+	g.Expect(errors.As(err, &codecErr)).To(gomega.BeTrue())
+	g.Expect(codecErr.Field).To(gomega.Equal("Token"))
+	err = DB.List(&list, ListOptions{Detail: 1})
+	g.Expect(errors.Is(err, DecryptErr)).To(gomega.BeTrue())
+	// Not overwritten.
+	row = DB.(*Client).db.QueryRow("SELECT Token FROM TestSecret")
+	stored := ""
+	err = row.Scan(&stored)
+	g.Expect(err).To(gomega.BeNil())
+	keyring, err = NewKeyring(k2)
+	g.Expect(err).To(gomega.BeNil())
+	DB.SetKeyring(keyring)
+	object = &TestSecret{ID: 1}
+	err = DB.Get(object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(object.Token).To(gomega.Equal("secret"))
+	g.Expect(stored).ToNot(gomega.BeEmpty())
+}
+
+func TestCompress(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	// DDL.
+	ddl, err := Table{}.DDL(&TestCompressed{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("Spec BLOB"))
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("Secret TEXT"))
+	type Invalid struct {
+		PK   string `sql:"pk"`
+		Name string `sql:"compress"`
+	}
+	_, err = Table{}.DDL(&Invalid{})
+	g.Expect(errors.Is(err, CompressErr)).To(gomega.BeTrue())
+	// Round trip.
+	DB := New(
+		"/tmp/test.db",
+		&TestCompressed{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	keyring, err := NewKeyring([]byte("0123456789abcdef"))
+	g.Expect(err).To(gomega.BeNil())
+	DB.SetKeyring(keyring)
+	spec := []string{}
+	for i := 0; i < 100; i++ {
+		spec = append(spec, "repeated")
+	}
+	err = DB.Insert(&TestCompressed{ID: 1, Spec: spec, Secret: spec})
+	g.Expect(err).To(gomega.BeNil())
+	object := &TestCompressed{ID: 1}
+	err = DB.Get(object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(object.Spec).To(gomega.Equal(spec))
+	g.Expect(object.Secret).To(gomega.Equal(spec))
+	// Stored compressed.
+	size := 0
+	row := DB.(*Client).db.QueryRow("SELECT LENGTH(Spec) FROM TestCompressed")
+	err = row.Scan(&size)
+	g.Expect(err).To(gomega.BeNil())
+	plain, _ := json.Marshal(spec)
+	g.Expect(size < len(plain)/4).To(gomega.BeTrue())
+	// Legacy (uncompressed).
+	_, err = DB.(*Client).db.Exec(
+		"UPDATE TestCompressed SET Spec = :spec",
+		sql.Named("spec", `["legacy"]`))
+	g.Expect(err).To(gomega.BeNil())
+	object = &TestCompressed{ID: 1}
+	err = DB.Get(object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(object.Spec).To(gomega.Equal([]string{"legacy"}))
+	// Corrupt (gzip magic bytes but invalid).
+	_, err = DB.(*Client).db.Exec(
+		"UPDATE TestCompressed SET Spec = :spec",
+		sql.Named("spec", []byte{0x1f, 0x8b, 0x00, 0x01}))
+	g.Expect(err).To(gomega.BeNil())
+	object = &TestCompressed{ID: 1}
+	err = DB.Get(object)
+	codecErr := &CodecError{}
+	g.Expect(errors.As(err, &codecErr)).To(gomega.BeTrue())
+	g.Expect(codecErr.Field).To(gomega.Equal("Spec"))
+}
+
+func BenchmarkCompress(b *testing.B) {
+	spec := map[string]string{}
+	for i := 0; i < 1000; i++ {
+		spec[fmt.Sprintf("key-%d", i)] = "repeated-value"
+	}
+	plain, _ := json.Marshal(spec)
+	compressed := ""
+	b.Run("compress", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			compressed, _ = compress(string(plain))
+		}
+		b.ReportMetric(float64(len(compressed))/float64(len(plain)), "ratio")
+	})
+	b.Run("decompress", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _ = decompress(compressed)
+		}
+	})
+}
+
+func TestCustomField(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	// DDL.
+	ddl, err := Table{}.DDL(&TestCustom{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("UUID TEXT"))
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("Count INTEGER"))
+	g.Expect(ddl[0]).ToNot(gomega.ContainSubstring("Note TEXT NOT NULL"))
+	type Nullable struct {
+		PK    string          `sql:"pk"`
+		Int   sql.NullInt64   `sql:""`
+		Int32 sql.NullInt32   `sql:""`
+		Float sql.NullFloat64 `sql:""`
+		Bool  sql.NullBool    `sql:""`
+		Time  sql.NullTime    `sql:""`
+	}
+	ddl, err = Table{}.DDL(&Nullable{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("Int INTEGER"))
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("Int32 INTEGER"))
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("Float REAL"))
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("Bool INTEGER"))
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("Time TEXT"))
+	type Invalid struct {
+		PK   string   `sql:"pk"`
+		UUID TestUUID `sql:"encrypt"`
+	}
+	_, err = Table{}.DDL(&Invalid{})
+	g.Expect(errors.Is(err, CustomErr)).To(gomega.BeTrue())
+	// Round trip.
+	DB := New(
+		"/tmp/test.db",
+		&TestCustom{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(
+		&TestCustom{
+			ID:    1,
+			UUID:  TestUUID{0xde, 0xad, 0xbe, 0xef},
+			Count: TestCount{N: 3},
+			Note:  sql.NullString{String: "hello", Valid: true},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&TestCustom{ID: 2})
+	g.Expect(err).To(gomega.BeNil())
+	object := &TestCustom{ID: 1}
+	err = DB.Get(object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(object.UUID).To(gomega.Equal(TestUUID{0xde, 0xad, 0xbe, 0xef}))
+	g.Expect(object.Count.N).To(gomega.Equal(3))
+	g.Expect(object.Note).To(gomega.Equal(sql.NullString{String: "hello", Valid: true}))
+	object = &TestCustom{ID: 2}
+	err = DB.Get(object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(object.Note.Valid).To(gomega.BeFalse())
+	// Stored using the valuer.
+	raw := ""
+	row := DB.(*Client).db.QueryRow("SELECT UUID FROM TestCustom WHERE ID = 1")
+	err = row.Scan(&raw)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(raw).To(gomega.Equal("deadbeef"))
+	// Predicate.
+	list := []TestCustom{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Predicate: Eq("UUID", TestUUID{0xde, 0xad, 0xbe, 0xef}),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	list = []TestCustom{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Predicate: Eq("Count", int64(3)),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	g.Expect(list[0].ID).To(gomega.Equal(1))
+}
+
+func TestGetDetail(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(
+		&TestObject{
+			ID:     1,
+			Name:   "Elmer",
+			Object: TestEncoded{Name: "json"},
+			Slice:  []string{"hello"},
+			D4:     "d-4",
+		})
+	g.Expect(err).To(gomega.BeNil())
+	// Core.
+	object := &TestObject{ID: 1, Name: "stale", D4: "stale"}
+	err = DB.GetDetail(object, 0)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(object.PK).ToNot(gomega.BeEmpty())
+	g.Expect(object.ID).To(gomega.Equal(1))
+	g.Expect(object.Name).To(gomega.Equal(""))
+	g.Expect(object.Object.Name).To(gomega.Equal(""))
+	g.Expect(object.Slice).To(gomega.BeNil())
+	g.Expect(object.D4).To(gomega.Equal(""))
+	// Plain.
+	object = &TestObject{ID: 1}
+	err = DB.GetDetail(object, 2)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(object.Name).To(gomega.Equal("Elmer"))
+	g.Expect(object.Object.Name).To(gomega.Equal(""))
+	// All.
+	object = &TestObject{ID: 1}
+	err = DB.GetDetail(object, 1)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(object.Name).To(gomega.Equal("Elmer"))
+	g.Expect(object.Object.Name).To(gomega.Equal("json"))
+	g.Expect(object.D4).To(gomega.Equal("d-4"))
+	// Not found.
+	err = DB.GetDetail(&TestObject{ID: 2}, 0)
+	g.Expect(errors.Is(err, NotFound)).To(gomega.BeTrue())
+}
+
+func TestSortTieBreak(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	for i := 0; i < 10; i++ {
+		err = DB.Insert(&TestObject{ID: i, Name: "Elmer"})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	// Sorted by Name (6) then PK.
+	all := []TestObject{}
+	for offset := 0; offset < 10; offset += 3 {
+		page := []TestObject{}
+		err = DB.List(
+			&page,
+			ListOptions{
+				Detail: 1,
+				Sort:   []int{6},
+				Page:   &Page{Offset: offset, Limit: 3},
+			})
+		g.Expect(err).To(gomega.BeNil())
+		all = append(all, page...)
+	}
+	g.Expect(len(all)).To(gomega.Equal(10))
+	for i := 1; i < len(all); i++ {
+		g.Expect(all[i-1].PK < all[i].PK).To(gomega.BeTrue())
+	}
+	// SQL.
+	table := Table{}
+	fields, _ := table.Fields(&TestObject{})
+	options := &ListOptions{Detail: 1, Sort: []int{6}}
+	stmt, err := table.listSQL("TestObject", fields, options)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(stmt).To(gomega.ContainSubstring("ORDER BY\n6\n,PK\n"))
+	// Already sorted by PK (4).
+	options = &ListOptions{Detail: 1, Sort: []int{4, 6}}
+	stmt, err = table.listSQL("TestObject", fields, options)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(stmt).To(gomega.ContainSubstring("ORDER BY\n4\n,6\n;"))
+	// Disabled.
+	options = &ListOptions{Detail: 1, Sort: []int{6}, DisableTieBreak: true}
+	stmt, err = table.listSQL("TestObject", fields, options)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(stmt).To(gomega.ContainSubstring("ORDER BY\n6\n;"))
+	// Not sorted.
+	options = &ListOptions{Detail: 1}
+	stmt, err = table.listSQL("TestObject", fields, options)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(stmt).ToNot(gomega.ContainSubstring("ORDER BY"))
+}
+
+func TestGetMany(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	pks := []string{}
+	for i := 0; i < MaxChunk+10; i++ {
+		object := &TestObject{ID: i, Name: fmt.Sprintf("N%d", i)}
+		err = DB.Insert(object)
+		g.Expect(err).To(gomega.BeNil())
+		pks = append(pks, object.PK)
+	}
+	// Requested order.
+	list := []TestObject{}
+	err = DB.GetMany(
+		&TestObject{},
+		[]string{pks[3], "missing", pks[1], pks[3], pks[2]},
+		&list)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(3))
+	g.Expect(list[0].ID).To(gomega.Equal(3))
+	g.Expect(list[0].Name).To(gomega.Equal("N3"))
+	g.Expect(list[1].ID).To(gomega.Equal(1))
+	g.Expect(list[2].ID).To(gomega.Equal(2))
+	// Chunked.
+	reversed := []string{}
+	for i := len(pks) - 1; i >= 0; i-- {
+		reversed = append(reversed, pks[i])
+	}
+	err = DB.GetMany(&TestObject{}, reversed, &list)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(len(pks)))
+	g.Expect(list[0].ID).To(gomega.Equal(len(pks) - 1))
+	g.Expect(list[len(pks)-1].ID).To(gomega.Equal(0))
+	// Empty.
+	err = DB.GetMany(&TestObject{}, []string{}, &list)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(0))
+	// Wrong list type.
+	err = DB.GetMany(&TestObject{}, pks, &[]TestChild{})
+	g.Expect(errors.Is(err, MustBeSlicePtrErr)).To(gomega.BeTrue())
+}
+
+func TestDisableLabels(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{})
+	DB.(*Client).DisableLabels = true
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	object := &TestObject{
+		ID:     1,
+		labels: Labels{"n1": "v1"},
+	}
+	err = DB.Insert(object)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Update(object)
+	g.Expect(err).To(gomega.BeNil())
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Insert(&TestObject{ID: 2, labels: Labels{"n1": "v1"}})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Delete(object)
+	g.Expect(err).To(gomega.BeNil())
+	// No Label table.
+	_, err = DB.Count(&Label{}, nil)
+	g.Expect(err).ToNot(gomega.BeNil())
+	schema, err := DB.Describe()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(schema)).To(gomega.Equal(1))
+}
+
+func TestReopen(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-reopen.db",
+		&Label{},
+		&TestObject{},
+		&TestObject{})
+	client := DB.(*Client)
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	models := len(client.models)
+	first, err := client.ddl(nil, client.schema())
+	g.Expect(err).To(gomega.BeNil())
+	found := map[string]bool{}
+	for _, ddl := range first {
+		g.Expect(found[ddl]).To(gomega.BeFalse())
+		found[ddl] = true
+	}
+	err = DB.Close(false)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Open(false)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	g.Expect(len(client.models)).To(gomega.Equal(models))
+	second, err := client.ddl(nil, client.schema())
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(second).To(gomega.Equal(first))
+	err = DB.Open(false)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(client.models)).To(gomega.Equal(models))
+	err = DB.Insert(&TestObject{ID: 1, Name: "a"})
+	g.Expect(err).To(gomega.BeNil())
+}
+
+func TestHasher(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	object := &TestObject{
+		ID:   0,
+		Name: "Elmer",
+	}
+	table := Table{}
+	fields, err := table.Fields(object)
+	g.Expect(err).To(gomega.BeNil())
+	table.SetPk(fields)
+	g.Expect(len(object.PK)).To(gomega.Equal(40))
+	sha1PK := object.PK
+	// SHA-256 (hex).
+	object.PK = ""
+	table.Hasher = SHA256Hasher{}
+	fields, _ = table.Fields(object)
+	table.SetPk(fields)
+	g.Expect(len(object.PK)).To(gomega.Equal(64))
+	g.Expect(object.PK).ToNot(gomega.Equal(sha1PK))
+	sha256PK := object.PK
+	// Stable.
+	object.PK = ""
+	fields, _ = table.Fields(object)
+	table.SetPk(fields)
+	g.Expect(object.PK).To(gomega.Equal(sha256PK))
+	// SHA-256 (base32).
+	object.PK = ""
+	table.Hasher = SHA256Hasher{Base32: true}
+	fields, _ = table.Fields(object)
+	table.SetPk(fields)
+	g.Expect(len(object.PK)).To(gomega.Equal(52))
+	g.Expect(object.PK).To(gomega.Equal(strings.ToLower(object.PK)))
+	// SHA-256 (base32, truncated).
+	object.PK = ""
+	table.Hasher = SHA256Hasher{Base32: true, Length: 10}
+	fields, _ = table.Fields(object)
+	table.SetPk(fields)
+	g.Expect(len(object.PK)).To(gomega.Equal(16))
+	// Client.
+	DB := New(
+		"/tmp/test-hasher.db",
+		&TestObject{})
+	DB.(*Client).Hasher = SHA256Hasher{}
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	object = &TestObject{ID: 1, Name: "Elmer"}
+	err = DB.Insert(object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(object.PK)).To(gomega.Equal(64))
+	err = DB.Get(&TestObject{ID: 1, Name: "Elmer"})
+	g.Expect(err).To(gomega.BeNil())
+}
+
+func TestConstKey(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	table := Table{}
+	// Virtual natural key.
+	fields, err := table.Fields(&TestVirtualKey{})
+	g.Expect(err).To(gomega.BeNil())
+	err = table.Validate(fields)
+	g.Expect(errors.Is(err, MutableKeyErr)).To(gomega.BeTrue())
+	// Natural keys are const.
+	fields, err = table.Fields(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	for _, f := range table.KeyFields(fields) {
+		g.Expect(f.Const()).To(gomega.BeTrue())
+		g.Expect(f.Mutable()).To(gomega.BeFalse())
+	}
+	// Not updated.
+	DB := New(
+		"/tmp/test-const-key.db",
+		&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	object := &TestObject{ID: 1, Name: "Elmer"}
+	err = DB.Insert(object)
+	g.Expect(err).To(gomega.BeNil())
+	pk := object.PK
+	object.ID = 2
+	err = DB.Update(object)
+	g.Expect(err).To(gomega.BeNil())
+	object = &TestObject{PK: pk}
+	err = DB.Get(object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(object.ID).To(gomega.Equal(1))
+}
+
+func TestCountBy(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-count-by.db",
+		&TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	for i := 0; i < 10; i++ {
+		err = DB.Insert(
+			&TestObject{
+				ID:   i,
+				Name: []string{"a", "b", "c"}[i%3],
+				Age:  i % 2,
+			})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	// Single field.
+	counts, err := DB.CountBy(&TestObject{}, "Name", nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(counts).To(gomega.Equal(map[string]int64{"a": 4, "b": 3, "c": 3}))
+	// Predicate.
+	counts, err = DB.CountBy(&TestObject{}, "name", Gt("ID", 5))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(counts).To(gomega.Equal(map[string]int64{"a": 2, "b": 1, "c": 1}))
+	// Multiple fields.
+	groups, err := DB.CountByGroup(&TestObject{}, []string{"Name", "Age"}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(groups).To(gomega.Equal(
+		[]GroupCount{
+			{Keys: []string{"a", "0"}, Count: 2},
+			{Keys: []string{"a", "1"}, Count: 2},
+			{Keys: []string{"b", "0"}, Count: 1},
+			{Keys: []string{"b", "1"}, Count: 2},
+			{Keys: []string{"c", "0"}, Count: 2},
+			{Keys: []string{"c", "1"}, Count: 1},
+		}))
+	// Invalid.
+	_, err = DB.CountBy(&TestObject{}, "Unknown", nil)
+	g.Expect(errors.Is(err, GroupFieldErr)).To(gomega.BeTrue())
+	_, err = DB.CountByGroup(&TestObject{}, []string{}, nil)
+	g.Expect(errors.Is(err, GroupFieldErr)).To(gomega.BeTrue())
+}
+
+func TestReadOnly(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	path := "/tmp/test read-only#1%.db"
+	DB := New(path, &TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	err = DB.Insert(&TestObject{ID: 1, Name: "a"})
+	g.Expect(err).To(gomega.BeNil())
+	// Replica.
+	replica := New(path, &TestObject{})
+	replica.(*Client).ReadOnly = true
+	err = replica.Open(true)
+	g.Expect(errors.Is(err, ReadOnlyErr)).To(gomega.BeTrue())
+	err = replica.Open(false)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = replica.Close(false)
+	}()
+	// Reads.
+	object := &TestObject{ID: 1, Name: "a"}
+	err = replica.Get(object)
+	g.Expect(err).To(gomega.BeNil())
+	list := []TestObject{}
+	err = replica.List(&list, ListOptions{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	count, err := replica.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(count).To(gomega.Equal(int64(1)))
+	// Writes.
+	err = replica.Insert(&TestObject{ID: 2})
+	g.Expect(errors.Is(err, ReadOnlyErr)).To(gomega.BeTrue())
+	err = replica.Update(object)
+	g.Expect(errors.Is(err, ReadOnlyErr)).To(gomega.BeTrue())
+	err = replica.Delete(object)
+	g.Expect(errors.Is(err, ReadOnlyErr)).To(gomega.BeTrue())
+	_, err = replica.Begin()
+	g.Expect(errors.Is(err, ReadOnlyErr)).To(gomega.BeTrue())
+	err = replica.Migrate([]Migration{})
+	g.Expect(errors.Is(err, ReadOnlyErr)).To(gomega.BeTrue())
+	versions, err := replica.Migrations()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(versions).To(gomega.Equal([]int{}))
+	// Maintenance.
+	err = replica.Analyze(&TestObject{})
+	g.Expect(errors.Is(err, ReadOnlyErr)).To(gomega.BeTrue())
+	err = replica.AnalyzeAll()
+	g.Expect(errors.Is(err, ReadOnlyErr)).To(gomega.BeTrue())
+	err = replica.Reindex(&TestObject{})
+	g.Expect(errors.Is(err, ReadOnlyErr)).To(gomega.BeTrue())
+	err = replica.Attach("archive", "/tmp/archive.db", &TestCollate{})
+	g.Expect(errors.Is(err, ReadOnlyErr)).To(gomega.BeTrue())
+	err = replica.SetPragma("cache_size", "-4000")
+	g.Expect(errors.Is(err, ReadOnlyErr)).To(gomega.BeTrue())
+	// File opened read-only.
+	db, err := replica.(*Client).pool()
+	g.Expect(err).To(gomega.BeNil())
+	_, err = db.Exec("DELETE FROM TestObject")
+	g.Expect(err).ToNot(gomega.BeNil())
+	// Writes by the primary are visible.
+	err = DB.Insert(&TestObject{ID: 2, Name: "b"})
+	g.Expect(err).To(gomega.BeNil())
+	count, err = replica.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(count).To(gomega.Equal(int64(2)))
+}
+
+func TestWithoutRowID(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	table := Table{}
+	ddl, err := table.DDL(&TestNoRowID{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ddl[0]).To(gomega.ContainSubstring(") WITHOUT ROWID;"))
+	ddl, err = table.DDL(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ddl[0]).ToNot(gomega.ContainSubstring("WITHOUT ROWID"))
+	_, err = table.DDL(&TestNoRowIDInt{})
+	g.Expect(errors.Is(err, WithoutRowIDErr)).To(gomega.BeTrue())
+	// Accepted.
+	DB := New(
+		"/tmp/test-without-rowid.db",
+		&TestNoRowID{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	err = DB.Insert(&TestNoRowID{PK: "a", Name: "Elmer"})
+	g.Expect(err).To(gomega.BeNil())
+	object := &TestNoRowID{PK: "a"}
+	err = DB.Get(object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(object.Name).To(gomega.Equal("Elmer"))
+}
+
+func TestPragma(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-pragma.db",
+		&TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	err = DB.SetPragma("cache_size", "-4000")
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.SetPragma("temp_store", "MEMORY")
+	g.Expect(err).To(gomega.BeNil())
+	// Applied to each connection.
+	db, err := DB.(*Client).pool()
+	g.Expect(err).To(gomega.BeNil())
+	conns := []*sql.Conn{}
+	for i := 0; i < 3; i++ {
+		conn, err := db.Conn(context.TODO())
+		g.Expect(err).To(gomega.BeNil())
+		conns = append(conns, conn)
+		value := ""
+		err = conn.QueryRowContext(context.TODO(), "PRAGMA cache_size").Scan(&value)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(value).To(gomega.Equal("-4000"))
+	}
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	value, err := DB.GetPragma("cache_size")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(value).To(gomega.Equal("-4000"))
+	value, err = DB.GetPragma("temp_store")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(value).To(gomega.Equal("2"))
+	// Connection in use (stale) is discarded when returned.
+	conn, err := db.Conn(context.TODO())
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.SetPragma("cache_size", "-5000")
+	g.Expect(err).To(gomega.BeNil())
+	_ = conn.Close()
+	value, err = DB.GetPragma("cache_size")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(value).To(gomega.Equal("-5000"))
+	// Invalid.
+	err = DB.SetPragma("cache_size; DROP TABLE TestObject", "1")
+	g.Expect(errors.Is(err, PragmaErr)).To(gomega.BeTrue())
+	err = DB.SetPragma("cache_size", "1; DROP TABLE TestObject")
+	g.Expect(errors.Is(err, PragmaErr)).To(gomega.BeTrue())
+	_, err = DB.GetPragma("")
+	g.Expect(errors.Is(err, PragmaErr)).To(gomega.BeTrue())
+}
+
+func TestFuncPredicate(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-func-predicate.db",
+		&TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	names := []string{"Elmer", "bugs", "Daffy Duck", "TWEETY"}
+	for i, name := range names {
+		err = DB.Insert(
+			&TestObject{
+				ID:   i,
+				Name: name,
+				Age:  i - 2,
+			})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	list := []TestObject{}
+	err = DB.List(&list, ListOptions{Predicate: Length("Name").Gt(5)})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	g.Expect(list[0].ID).To(gomega.Equal(2))
+	g.Expect(list[1].ID).To(gomega.Equal(3))
+	list = []TestObject{}
+	err = DB.List(&list, ListOptions{Predicate: Lower("Name").Eq("tweety")})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	g.Expect(list[0].ID).To(gomega.Equal(3))
+	list = []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Predicate: And(
+				Func("abs", "Age").Lt(2),
+				Upper("Name").Neq("BUGS")),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	g.Expect(list[0].ID).To(gomega.Equal(2))
+	g.Expect(list[1].ID).To(gomega.Equal(3))
+	// Invalid.
+	err = DB.List(&list, ListOptions{Predicate: Func("RANDOM", "Name").Eq(1)})
+	g.Expect(errors.Is(err, FunctionErr)).To(gomega.BeTrue())
+	err = DB.List(&list, ListOptions{Predicate: Lower("Unknown").Eq("a")})
+	g.Expect(errors.Is(err, PredicateRefErr)).To(gomega.BeTrue())
+	err = DB.List(&list, ListOptions{Predicate: Lower("Age").Eq("a")})
+	g.Expect(errors.Is(err, PredicateTypeErr)).To(gomega.BeTrue())
+	err = DB.List(&list, ListOptions{Predicate: Length("Name").Eq("a")})
+	g.Expect(errors.Is(err, PredicateValueErr)).To(gomega.BeTrue())
+}
+
+func TestSearch(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-search.db",
+		&TestArticle{},
+		&TestObject{})
+	err := DB.Open(true)
+	if err != nil && strings.Contains(err.Error(), "no such module: fts5") {
+		t.Skip("sqlite3 built without fts5 (tag: sqlite_fts5).")
+	}
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	articles := []*TestArticle{
+		{ID: 0, Title: "Migration", Body: "Migrate virtual machines.", Author: "elmer"},
+		{ID: 1, Title: "Storage", Body: "Storage mapping for the migration plan.", Author: "bugs"},
+		{ID: 2, Title: "Network", Body: "Network mapping.", Author: "elmer"},
+	}
+	for _, m := range articles {
+		err = DB.Insert(m)
+		g.Expect(err).To(gomega.BeNil())
+	}
+	// Ranked.
+	list := []TestArticle{}
+	err = DB.Search(&list, "migration", ListOptions{Detail: 1})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	g.Expect(list[0].ID).To(gomega.Equal(0))
+	g.Expect(list[1].ID).To(gomega.Equal(1))
+	g.Expect(list[0].Author).To(gomega.Equal("elmer"))
+	// Qualified.
+	list = []TestArticle{}
+	err = DB.Search(&list, "mapping", ListOptions{Predicate: Eq("Author", "elmer")})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	g.Expect(list[0].ID).To(gomega.Equal(2))
+	// Updated.
+	articles[2].Body = "Network migration."
+	err = DB.Update(articles[2])
+	g.Expect(err).To(gomega.BeNil())
+	list = []TestArticle{}
+	err = DB.Search(&list, "mapping", ListOptions{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	g.Expect(list[0].ID).To(gomega.Equal(1))
+	// Deleted.
+	err = DB.Delete(articles[0])
+	g.Expect(err).To(gomega.BeNil())
+	list = []TestArticle{}
+	err = DB.Search(&list, "migrat*", ListOptions{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	// Invalid.
+	err = DB.Search(&list, "", ListOptions{})
+	g.Expect(errors.Is(err, PredicateValueErr)).To(gomega.BeTrue())
+	objects := []TestObject{}
+	err = DB.Search(&objects, "elmer", ListOptions{})
+	g.Expect(errors.Is(err, FtsErr)).To(gomega.BeTrue())
+}
+
+func TestTrigger(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-trigger.db",
+		&TestTally{},
+		&TestTallyChild{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	parent := &TestTally{ID: 1}
+	err = DB.Insert(parent)
+	g.Expect(err).To(gomega.BeNil())
+	children := []*TestTallyChild{}
+	for i := 0; i < 3; i++ {
+		child := &TestTallyChild{ID: i, Parent: parent.PK}
+		err = DB.Insert(child)
+		g.Expect(err).To(gomega.BeNil())
+		children = append(children, child)
+	}
+	err = DB.Get(parent)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(parent.Children).To(gomega.Equal(3))
+	err = DB.Delete(children[0])
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Get(parent)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(parent.Children).To(gomega.Equal(2))
+	// DDL.
+	ddl, err := DB.(*Client).table(nil).TriggerDDL(&TestTallyChild{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(ddl)).To(gomega.Equal(2))
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("TestTallyChildAdded\nAFTER INSERT ON TestTallyChild"))
+	g.Expect(ddl[1]).To(gomega.ContainSubstring("WHEN old.Parent != ''"))
+	// Invalid.
+	_, err = Table{}.TriggerDDL(&TestBadTrigger{})
+	g.Expect(errors.Is(err, TriggerErr)).To(gomega.BeTrue())
+	bad := Trigger{Name: "A B", Event: "INSERT", Body: "SELECT 1;"}
+	g.Expect(errors.Is(bad.Validate(), TriggerErr)).To(gomega.BeTrue())
+	bad = Trigger{Name: "A", Event: "SELECT", Body: "SELECT 1;"}
+	g.Expect(errors.Is(bad.Validate(), TriggerErr)).To(gomega.BeTrue())
+	bad = Trigger{Name: "A", Event: "INSERT"}
+	g.Expect(errors.Is(bad.Validate(), TriggerErr)).To(gomega.BeTrue())
+}
+
+func TestDetailDefault(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-detail-default.db",
+		&TestSummary{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	err = DB.Insert(
+		&TestSummary{
+			ID:    1,
+			Name:  "Elmer",
+			Notes: "Hunter",
+			Spec:  []string{"a"},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	// Model default (plain).
+	list := []TestSummary{}
+	err = DB.List(&list, ListOptions{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	g.Expect(list[0].Name).To(gomega.Equal("Elmer"))
+	g.Expect(list[0].Notes).To(gomega.Equal(""))
+	g.Expect(list[0].Spec).To(gomega.BeNil())
+	maps, err := DB.ListMaps(&TestSummary{}, ListOptions{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(maps[0]).To(gomega.HaveKey("Name"))
+	g.Expect(maps[0]).ToNot(gomega.HaveKey("Spec"))
+	// Encoded.
+	list = []TestSummary{}
+	err = DB.List(&list, ListOptions{Detail: 3})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(list[0].Spec).To(gomega.Equal([]string{"a"}))
+	g.Expect(list[0].Notes).To(gomega.Equal(""))
+	// Custom (d4).
+	list = []TestSummary{}
+	err = DB.List(&list, ListOptions{Detail: 4})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(list[0].Notes).To(gomega.Equal("Hunter"))
+	// All.
+	list = []TestSummary{}
+	err = DB.List(&list, ListOptions{Detail: 1})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(list[0].Notes).To(gomega.Equal("Hunter"))
+	g.Expect(list[0].Spec).To(gomega.Equal([]string{"a"}))
+}
+
+func TestDetailLevel(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	table := Table{}
+	// Validated.
+	for _, m := range []interface{}{&TestBadDetail{}, &TestTwoDetail{}} {
+		fields, err := table.Fields(m)
+		g.Expect(err).To(gomega.BeNil())
+		err = table.Validate(fields)
+		g.Expect(errors.Is(err, DetailErr)).To(gomega.BeTrue())
+	}
+	// Levels.
+	fields, err := table.Fields(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(table.Validate(fields)).To(gomega.BeNil())
+	levels := map[string]int{}
+	for _, f := range fields {
+		levels[f.Name] = f.Detail()
+	}
+	g.Expect(levels["PK"]).To(gomega.Equal(DetailCore))
+	g.Expect(levels["Name"]).To(gomega.Equal(DetailPlain))
+	g.Expect(levels["Object"]).To(gomega.Equal(DetailEncoded))
+	g.Expect(levels["D4"]).To(gomega.Equal(4))
+	// Custom (d4) level selected only when level >= 4 (or all).
+	for _, f := range fields {
+		if f.Name != "D4" {
+			continue
+		}
+		g.Expect(f.MatchDetail(DetailCore)).To(gomega.BeFalse())
+		g.Expect(f.MatchDetail(DetailPlain)).To(gomega.BeFalse())
+		g.Expect(f.MatchDetail(DetailEncoded)).To(gomega.BeFalse())
+		g.Expect(f.MatchDetail(4)).To(gomega.BeTrue())
+		g.Expect(f.MatchDetail(DetailMax)).To(gomega.BeTrue())
+		g.Expect(f.MatchDetail(DetailAll)).To(gomega.BeTrue())
+	}
+}
+
+func TestListOptionsClone(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	table := Table{}
+	fields, _ := table.Fields(&TestObject{})
+	options := ListOptions{
+		Detail:    1,
+		Sort:      []int{6},
+		Page:      &Page{Limit: 2},
+		Predicate: And(Eq("Name", "a"), Gt("Age", 1)),
+	}
+	// Same (cloned) options built twice.
+	first := options.Clone()
+	stmt, err := table.listSQL("TestObject", fields, &first)
+	g.Expect(err).To(gomega.BeNil())
+	second := options.Clone()
+	stmt2, err := table.listSQL("TestObject", fields, &second)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(stmt2).To(gomega.Equal(stmt))
+	g.Expect(second.Params()).To(gomega.Equal(first.Params()))
+	g.Expect(len(second.Params())).To(gomega.Equal(4))
+	// Original not mutated.
+	g.Expect(options.Params()).To(gomega.BeNil())
+	g.Expect(options.fields).To(gomega.BeNil())
+	// Clone is independent.
+	first.Sort[0] = 2
+	first.Page.Limit = 10
+	g.Expect(options.Sort[0]).To(gomega.Equal(6))
+	g.Expect(options.Page.Limit).To(gomega.Equal(2))
+	// Reused (after built) with List.
+	DB := New(
+		"/tmp/test-options-clone.db",
+		&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	for i := 0; i < 5; i++ {
+		err = DB.Insert(&TestObject{ID: i, Name: "a", Age: i})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	for i := 0; i < 2; i++ {
+		list := []TestObject{}
+		err = DB.List(&list, first)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(len(list)).To(gomega.Equal(3))
+		g.Expect(first.Params()).To(gomega.Equal(second.Params()))
+	}
+}
+
+func TestListOptionsRebuild(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	table := Table{}
+	fields, _ := table.Fields(&TestObject{})
+	predicate := Or(Eq("Name", "a"), Gt("Age", 2))
+	options := &ListOptions{
+		Page:      &Page{Limit: 10},
+		Predicate: predicate,
+	}
+	stmt, err := table.listSQL("TestObject", fields, options)
+	g.Expect(err).To(gomega.BeNil())
+	params := options.Params()
+	g.Expect(len(params)).To(gomega.Equal(4))
+	stmt2, err := table.listSQL("TestObject", fields, options)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(stmt2).To(gomega.Equal(stmt))
+	g.Expect(options.Params()).To(gomega.Equal(params))
+	// Count then list (same predicate).
+	DB := New(
+		"/tmp/test-options-rebuild.db",
+		&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	for i := 0; i < 5; i++ {
+		err = DB.Insert(&TestObject{ID: i, Name: "b", Age: i})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	for i := 0; i < 2; i++ {
+		count, err := DB.Count(&TestObject{}, predicate)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(count).To(gomega.Equal(int64(2)))
+		list := []TestObject{}
+		err = DB.List(&list, *options)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(len(list)).To(gomega.Equal(2))
+	}
+	g.Expect(options.Params()).To(gomega.Equal(params))
+}
+
+func TestListMore(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-list-more.db",
+		&TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	for i := 0; i < 5; i++ {
+		err = DB.Insert(&TestObject{ID: i})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	list := []TestObject{}
+	options := ListOptions{Page: &Page{Limit: 2}}
+	hasMore, err := DB.ListMore(&list, options)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(hasMore).To(gomega.BeTrue())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	g.Expect(options.Page.Limit).To(gomega.Equal(2))
+	// Last page.
+	list = []TestObject{}
+	hasMore, err = DB.ListMore(&list, ListOptions{Page: &Page{Offset: 3, Limit: 2}})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(hasMore).To(gomega.BeFalse())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	// Exact.
+	list = []TestObject{}
+	hasMore, err = DB.ListMore(&list, ListOptions{Page: &Page{Limit: 5}})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(hasMore).To(gomega.BeFalse())
+	g.Expect(len(list)).To(gomega.Equal(5))
+	// Unlimited (max int).
+	list = []TestObject{}
+	hasMore, err = DB.ListMore(&list, ListOptions{Page: &Page{Limit: int(^uint(0) >> 1)}})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(hasMore).To(gomega.BeFalse())
+	g.Expect(len(list)).To(gomega.Equal(5))
+	// Capped.
+	DB.(*Client).MaxPageLimit = 3
+	list = []TestObject{}
+	hasMore, err = DB.ListMore(&list, ListOptions{Page: &Page{Limit: 10}})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(hasMore).To(gomega.BeTrue())
+	g.Expect(len(list)).To(gomega.Equal(3))
+	// Not paged.
+	list = []TestObject{}
+	hasMore, err = DB.ListMore(&list, ListOptions{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(hasMore).To(gomega.BeFalse())
+	g.Expect(len(list)).To(gomega.Equal(5))
+	// Invalid.
+	_, err = DB.ListMore(&list, ListOptions{Page: &Page{Limit: 0}})
+	g.Expect(errors.Is(err, InvalidPageErr)).To(gomega.BeTrue())
+}
+
+func TestSortNulls(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-sort-nulls.db",
+		&TestCustom{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	notes := []sql.NullString{
+		{String: "b", Valid: true},
+		{},
+		{String: "a", Valid: true},
+		{},
+	}
+	for i, note := range notes {
+		err = DB.Insert(&TestCustom{ID: i, Note: note})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	ids := func(list []TestCustom) (ids []int) {
+		for _, m := range list {
+			ids = append(ids, m.ID)
+		}
+		return
+	}
+	// Default (first).
+	list := []TestCustom{}
+	err = DB.List(&list, ListOptions{Detail: 1, Sort: []int{5}})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(list[0].Note.Valid).To(gomega.BeFalse())
+	g.Expect(list[1].Note.Valid).To(gomega.BeFalse())
+	g.Expect(ids(list)[2:]).To(gomega.Equal([]int{2, 0}))
+	// Last.
+	list = []TestCustom{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Detail: 1,
+			Sort:   []int{5},
+			Nulls:  map[int]Nulls{5: NullsLast},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ids(list)[:2]).To(gomega.Equal([]int{2, 0}))
+	g.Expect(list[2].Note.Valid).To(gomega.BeFalse())
+	g.Expect(list[3].Note.Valid).To(gomega.BeFalse())
+	// First.
+	list = []TestCustom{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Detail: 1,
+			Sort:   []int{5},
+			Nulls:  map[int]Nulls{5: NullsFirst},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(list[0].Note.Valid).To(gomega.BeFalse())
+	g.Expect(ids(list)[2:]).To(gomega.Equal([]int{2, 0}))
+	// SQL.
+	table := Table{}
+	fields, _ := table.Fields(&TestCustom{})
+	options := &ListOptions{
+		Detail: 1,
+		Sort:   []int{5},
+		Nulls:  map[int]Nulls{5: NullsLast, 2: NullsFirst},
+	}
+	stmt, err := table.listSQL("TestCustom", fields, options)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(stmt).To(gomega.ContainSubstring(
+		"ORDER BY\nCASE WHEN Note IS NULL THEN 1 ELSE 0 END\n,5\n,PK\n"))
+}
+
+func TestBeforeDDL(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-before-ddl.db",
+		&TestObject{})
+	client := DB.(*Client)
+	observed := []string{}
+	client.BeforeDDL = func(statements []string) ([]string, error) {
+		observed = append(observed, statements...)
+		statements = append(
+			statements,
+			"CREATE TABLE IF NOT EXISTS Extra (ID INTEGER PRIMARY KEY);")
+		return statements, nil
+	}
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	g.Expect(len(observed) > 0).To(gomega.BeTrue())
+	g.Expect(observed[0]).To(gomega.ContainSubstring("CREATE TABLE IF NOT EXISTS TestObject"))
+	db, err := client.pool()
+	g.Expect(err).To(gomega.BeNil())
+	_, err = db.Exec("INSERT INTO Extra (ID) VALUES (1);")
+	g.Expect(err).To(gomega.BeNil())
+	// Failed.
+	DB2 := New(
+		"/tmp/test-before-ddl-2.db",
+		&TestObject{})
+	hookErr := errors.New("rejected")
+	DB2.(*Client).BeforeDDL = func(statements []string) ([]string, error) {
+		return nil, hookErr
+	}
+	err = DB2.Open(true)
+	g.Expect(errors.Is(err, hookErr)).To(gomega.BeTrue())
+	err = DB2.Insert(&TestObject{})
+	g.Expect(errors.Is(err, ClosedErr)).To(gomega.BeTrue())
+	_ = os.Remove("/tmp/test-before-ddl-2.db")
+}
+
+func TestUniqueEmbedded(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	table := Table{Namer: SnakeNamer{}}
+	fields, err := table.Fields(&TestServer{})
+	g.Expect(err).To(gomega.BeNil())
+	constraints := table.Constraints(fields)
+	g.Expect(constraints).To(gomega.ContainElement("UNIQUE (number,region,row,zone)"))
+	g.Expect(constraints).To(gomega.ContainElement("UNIQUE (serial)"))
+	// Enforced.
+	DB := New(
+		"/tmp/test-unique-embedded.db",
+		&TestServer{})
+	DB.(*Client).Namer = SnakeNamer{}
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	server := &TestServer{
+		Name:     "a",
+		TestSite: TestSite{Region: "east", Zone: "1"},
+		TestRack: TestRack{Row: 1, Number: 1, Serial: "A"},
+	}
+	err = DB.Insert(server)
+	g.Expect(err).To(gomega.BeNil())
+	server = &TestServer{
+		Name:     "b",
+		TestSite: TestSite{Region: "east", Zone: "1"},
+		TestRack: TestRack{Row: 1, Number: 2, Serial: "B"},
+	}
+	err = DB.Insert(server)
+	g.Expect(err).To(gomega.BeNil())
+	server = &TestServer{
+		Name:     "c",
+		TestSite: TestSite{Region: "east", Zone: "1"},
+		TestRack: TestRack{Row: 1, Number: 2, Serial: "C"},
+	}
+	err = DB.Insert(server)
+	g.Expect(err).ToNot(gomega.BeNil())
+}
+
+func TestStableDDL(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	table := Table{Namer: SnakeNamer{}}
+	first, err := table.DDL(&TestServer{})
+	g.Expect(err).To(gomega.BeNil())
+	for i := 0; i < 20; i++ {
+		ddl, err := table.DDL(&TestServer{})
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(ddl).To(gomega.Equal(first))
+	}
+	fields, _ := table.Fields(&TestServer{})
+	g.Expect(table.Constraints(fields)).To(gomega.Equal(
+		[]string{
+			"UNIQUE (number,region,row,zone)",
+			"UNIQUE (serial)",
+		}))
+}
+
+func TestInMemory(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := NewInMemory(&TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	DB2 := NewInMemory(&TestObject{})
+	err = DB2.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB2.Close(true)
+	}()
+	for i := 0; i < 3; i++ {
+		err = DB.Insert(&TestObject{ID: i})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Insert(&TestObject{ID: 3})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+	// Survives (pooled) connections being closed.
+	err = DB.SetForeignKeys(false)
+	g.Expect(err).To(gomega.BeNil())
+	count, err := DB.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(count).To(gomega.Equal(int64(4)))
+	db, err := DB.(*Client).pool()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(db.Stats().MaxOpenConnections).To(gomega.Equal(1))
+	// Isolated.
+	count, err = DB2.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(count).To(gomega.Equal(int64(0)))
+	// Destroyed on close.
+	err = DB.Close(true)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(DB.(*Client).keep).To(gomega.BeNil())
+	err = DB.Open(false)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	count, err = DB.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(count).To(gomega.Equal(int64(0)))
+}
+
+func TestSeed(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test.db",
+		&TestObject{},
+		&TestChild{},
+		&TestEnum{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	object := &TestObject{ID: 1, Name: "Elmer"}
+	fields, _ := Table{}.Fields(object)
+	_ = Table{}.SetPk(fields)
+	// Ordered by FK dependency.
+	err = DB.Seed(
+		&TestChild{PK: "c1", Parent: object.PK, Name: "Bugs"},
+		object,
+		&TestEnum{PK: "e1", ID: 1, Phase: "Running"})
+	g.Expect(err).To(gomega.BeNil())
+	count, err := DB.Count(&TestChild{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(count).To(gomega.Equal(int64(1)))
+	// Re-seed (upsert).
+	err = DB.Seed(
+		&TestObject{ID: 1, Name: "Bugs"},
+		&TestChild{PK: "c1", Parent: object.PK, Name: "Bugs", Age: 2})
+	g.Expect(err).To(gomega.BeNil())
+	count, err = DB.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(count).To(gomega.Equal(int64(1)))
+	stored := &TestObject{PK: object.PK}
+	err = DB.Get(stored)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(stored.Name).To(gomega.Equal("Bugs"))
+	child := &TestChild{PK: "c1"}
+	err = DB.Get(child)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(child.Age).To(gomega.Equal(2))
+	// Failed models reported; nothing committed.
+	err = DB.Seed(
+		&TestEnum{PK: "e2", ID: 2, Phase: "Running"},
+		&TestEnum{PK: "e3", ID: 3, Phase: "Sleeping"})
+	g.Expect(err).ToNot(gomega.BeNil())
+	seedErr, cast := err.(*SeedError)
+	g.Expect(cast).To(gomega.BeTrue())
+	g.Expect(len(seedErr.Failed)).To(gomega.Equal(1))
+	g.Expect(seedErr.Failed[0].Kind).To(gomega.Equal("TestEnum"))
+	g.Expect(seedErr.Failed[0].Index).To(gomega.Equal(1))
+	g.Expect(err.Error()).To(gomega.ContainSubstring("TestEnum[1]"))
+	count, err = DB.Count(&TestEnum{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(count).To(gomega.Equal(int64(1)))
+	// JSON.
+	err = DB.SeedFromJSON(strings.NewReader(`{
+		"TestEnum": [
+			{"PK": "e4", "ID": 4, "Phase": "Failed"},
+			{"PK": "e5", "ID": 5, "Phase": "Pending"}
+		]}`))
+	g.Expect(err).To(gomega.BeNil())
+	count, err = DB.Count(&TestEnum{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(count).To(gomega.Equal(int64(3)))
+	err = DB.SeedFromJSON(strings.NewReader(`{"Unknown": [{}]}`))
+	seedErr, cast = err.(*SeedError)
+	g.Expect(cast).To(gomega.BeTrue())
+	g.Expect(errors.Is(seedErr.Failed[0].Err, KindErr)).To(gomega.BeTrue())
+}
+
+func TestOrFlatten(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-or.db",
+		&TestObject{},
+		&TestCustom{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	for i := 0; i < 20; i++ {
+		err = DB.Insert(&TestObject{ID: i, Name: "Elmer"})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	// 1000 terms (nested and duplicated).
+	var predicate Predicate = Or()
+	terms := []Predicate{}
+	for i := 0; i < 1000; i++ {
+		terms = append(terms, Eq("ID", i%500))
+		if i%100 == 0 {
+			predicate = Or(predicate, Or(terms...))
+			terms = []Predicate{}
+		}
+	}
+	predicate = Or(predicate, Or(terms...), Gt("ID", 18), Gt("ID", 18))
+	fields, err := Table{}.Fields(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	options := ListOptions{Predicate: predicate}
+	err = options.Build("TestObject", fields)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(predicate.Expr()).To(gomega.HavePrefix("(ID IN (:ID0,:ID1,"))
+	g.Expect(strings.Count(predicate.Expr(), "(")).To(gomega.Equal(2))
+	g.Expect(strings.Count(predicate.Expr(), "OR")).To(gomega.Equal(1))
+	g.Expect(len(options.Params())).To(gomega.Equal(501))
+	list := []TestObject{}
+	err = DB.List(&list, ListOptions{Predicate: predicate})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(20))
+	// OneOf.
+	notes := []sql.NullString{
+		{String: "a", Valid: true},
+		{String: "b", Valid: true},
+		{},
+	}
+	for i, note := range notes {
+		err = DB.Insert(&TestCustom{ID: i, Note: note})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	ids := func(list []TestCustom) (ids []int) {
+		for _, m := range list {
+			ids = append(ids, m.ID)
+		}
+		return
+	}
+	custom := []TestCustom{}
+	err = DB.List(&custom, ListOptions{Predicate: OneOf("Note", "a", nil)})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ids(custom)).To(gomega.ConsistOf(0, 2))
+	err = DB.List(&custom, ListOptions{Predicate: OneOf("Note", "a", "b")})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ids(custom)).To(gomega.ConsistOf(0, 1))
+	err = DB.List(&custom, ListOptions{Predicate: OneOf("Note", nil)})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ids(custom)).To(gomega.ConsistOf(2))
+}
+
+func TestLimits(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-limits.db",
+		&TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	limits, err := DB.Limits()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(limits.Variables > 0).To(gomega.BeTrue())
+	g.Expect(limits.ExprDepth > 0).To(gomega.BeTrue())
+	pks := []string{}
+	for i := 0; i < 10; i++ {
+		object := &TestObject{ID: i}
+		err = DB.Insert(object)
+		g.Expect(err).To(gomega.BeNil())
+		pks = append(pks, object.PK)
+	}
+	// Expression depth.
+	terms := []Predicate{}
+	for i := 0; i < limits.ExprDepth+1; i++ {
+		terms = append(terms, Neq("ID", i))
+	}
+	list := []TestObject{}
+	err = DB.List(&list, ListOptions{Predicate: And(terms...)})
+	g.Expect(errors.Is(err, ExprDepthErr)).To(gomega.BeTrue())
+	_, err = DB.Count(&TestObject{}, Or(terms...))
+	g.Expect(errors.Is(err, ExprDepthErr)).To(gomega.BeTrue())
+	// Parameters.
+	db, err := DB.(*Client).pool()
+	g.Expect(err).To(gomega.BeNil())
+	table := Table{DB: db, Limits: Limits{Variables: 3}}
+	values := []interface{}{}
+	for i := 0; i < 4; i++ {
+		values = append(values, i)
+	}
+	err = table.List(&list, ListOptions{Predicate: In("ID", values...)})
+	g.Expect(errors.Is(err, ParamLimitErr)).To(gomega.BeTrue())
+	// Chunked.
+	err = table.GetMany(&TestObject{}, pks, &list)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(10))
+}
+
+func TestDistinctValues(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-distinct.db",
+		&TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	for i := 0; i < 10; i++ {
+		err = DB.Insert(
+			&TestObject{
+				ID:   i,
+				Name: []string{"c", "b", "a"}[i%3],
+				Age:  i % 2,
+				Bool: i%2 == 0,
+			})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	probe := &TestObject{Name: "probe"}
+	values, err := DB.DistinctValues(probe, "Name", nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(values).To(gomega.Equal([]interface{}{"a", "b", "c"}))
+	g.Expect(probe.Name).To(gomega.Equal("probe"))
+	values, err = DB.DistinctValues(&TestObject{}, "age", Gt("ID", 5))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(values).To(gomega.Equal([]interface{}{0, 1}))
+	values, err = DB.DistinctValues(&TestObject{}, "Bool", Eq("Age", 1))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(values).To(gomega.Equal([]interface{}{false}))
+	// Invalid field.
+	_, err = DB.DistinctValues(&TestObject{}, "Unknown", nil)
+	g.Expect(errors.Is(err, GroupFieldErr)).To(gomega.BeTrue())
+}
+
+func TestTouch(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-touch.db",
+		&TestObject{},
+		&TestSeen{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	// Touched on insert.
+	model := &TestSeen{ID: 1, Name: "Elmer"}
+	err = DB.Insert(model)
+	g.Expect(err).To(gomega.BeNil())
+	inserted := model.LastSeen
+	g.Expect(inserted > 0).To(gomega.BeTrue())
+	// Only the touch field updated.
+	model.Name = "Bugs"
+	err = DB.Touch(model)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(model.LastSeen > inserted).To(gomega.BeTrue())
+	touched := model.LastSeen
+	model = &TestSeen{ID: 1}
+	err = DB.Get(model)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(model.Name).To(gomega.Equal("Elmer"))
+	g.Expect(model.LastSeen).To(gomega.Equal(touched))
+	// Touched on update.
+	err = DB.Update(model)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(model.LastSeen > touched).To(gomega.BeTrue())
+	// Not found.
+	err = DB.Touch(&TestSeen{ID: 2})
+	g.Expect(errors.Is(err, NotFound)).To(gomega.BeTrue())
+	// No touch field.
+	err = DB.Touch(&TestObject{ID: 1})
+	g.Expect(errors.Is(err, TouchErr)).To(gomega.BeTrue())
+	_, err = Table{}.DDL(&TestBadTouch{})
+	g.Expect(errors.Is(err, TouchErr)).To(gomega.BeTrue())
+}
+
+func TestDeleteStale(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-delete-stale.db",
+		&TestObject{},
+		&TestSeen{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	handler := &TestSeenHandler{deleted: make(chan int, 10)}
+	watch, err := DB.Watch(&TestSeen{}, handler)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.EndWatch(watch)
+	models := []*TestSeen{}
+	for i := 0; i < 4; i++ {
+		m := &TestSeen{
+			ID:     i,
+			labels: Labels{"id": fmt.Sprintf("%d", i)},
+		}
+		err = DB.Insert(m)
+		g.Expect(err).To(gomega.BeNil())
+		models = append(models, m)
+	}
+	// Sync: 0 touched, 1 updated, 2 inserted again.
+	cutoff := time.Now()
+	err = DB.Touch(models[0])
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Update(models[1])
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(models[2])
+	g.Expect(err).To(gomega.BeNil())
+	n, err := DB.DeleteStale(&TestSeen{}, cutoff)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(1)))
+	list := []TestSeen{}
+	err = DB.List(&list, ListOptions{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(3))
+	labels := []Label{}
+	err = DB.List(&labels, ListOptions{Detail: DetailAll})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(labels)).To(gomega.Equal(3))
+	for _, l := range labels {
+		g.Expect(l.Value).ToNot(gomega.Equal("3"))
+	}
+	select {
+	case id := <-handler.deleted:
+		g.Expect(id).To(gomega.Equal(3))
+	case <-time.After(time.Second):
+		t.Fatal("delete event not delivered")
+	}
+	// No touch field.
+	_, err = DB.DeleteStale(&TestObject{}, cutoff)
+	g.Expect(errors.Is(err, TouchErr)).To(gomega.BeTrue())
+}
+
+func TestAggregate(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-aggregate.db",
+		&TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	for i := 0; i < 5; i++ {
+		err = DB.Insert(&TestObject{ID: i, Age: i * 10})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	value, err := DB.Aggregate(&TestObject{}, "SUM", "Age", nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(value).To(gomega.Equal(float64(100)))
+	value, err = DB.Aggregate(&TestObject{}, "avg", "Age", Gt("ID", 2))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(value).To(gomega.Equal(float64(35)))
+	value, err = DB.Aggregate(&TestObject{}, "MIN", "Age", Gt("ID", 0))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(value).To(gomega.Equal(float64(10)))
+	value, err = DB.Aggregate(&TestObject{}, "MAX", "Age", nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(value).To(gomega.Equal(float64(40)))
+	// Empty.
+	value, err = DB.Aggregate(&TestObject{}, "MAX", "Age", Gt("ID", 10))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(value).To(gomega.Equal(float64(0)))
+	// Invalid.
+	_, err = DB.Aggregate(&TestObject{}, "TOTAL", "Age", nil)
+	g.Expect(errors.Is(err, AggregateErr)).To(gomega.BeTrue())
+	_, err = DB.Aggregate(&TestObject{}, "SUM", "Name", nil)
+	g.Expect(errors.Is(err, AggregateErr)).To(gomega.BeTrue())
+	_, err = DB.Aggregate(&TestObject{}, "SUM", "Unknown", nil)
+	g.Expect(errors.Is(err, AggregateErr)).To(gomega.BeTrue())
+}
+
+func TestPredicateIntParse(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-int-parse.db",
+		&TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	err = DB.Insert(&TestObject{ID: 0})
+	g.Expect(err).To(gomega.BeNil())
+	list := []TestObject{}
+	err = DB.List(&list, ListOptions{Predicate: Eq("Age", "abc")})
+	g.Expect(err).ToNot(gomega.BeNil())
+	g.Expect(errors.Is(err, strconv.ErrSyntax)).To(gomega.BeTrue())
+	g.Expect(len(list)).To(gomega.Equal(0))
+	err = DB.List(&list, ListOptions{Predicate: Eq("Age", "0")})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+}
+
+func TestAsValue(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	fields, err := Table{}.Fields(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	field := func(name string) *Field {
+		f, _ := Table{}.find(name, fields)
+		return f
+	}
+	cases := []struct {
+		field string
+		in    interface{}
+		out   interface{}
+		err   bool
+	}{
+		// int field.
+		{field: "Age", in: "-42", out: int64(-42)},
+		{field: "Age", in: "010", out: int64(10)},
+		{field: "Age", in: "9223372036854775807", out: int64(math.MaxInt64)},
+		{field: "Age", in: "-9223372036854775808", out: int64(math.MinInt64)},
+		{field: "Age", in: "9223372036854775808", err: true},
+		{field: "Age", in: "0x1F", err: true},
+		{field: "Age", in: "abc", err: true},
+		{field: "Age", in: int8(-42), out: int64(-42)},
+		// str field.
+		{field: "Name", in: -42, out: "-42"},
+		{field: "Name", in: int64(math.MaxInt64), out: "9223372036854775807"},
+		{field: "Name", in: int8(-8), out: "-8"},
+		{field: "Name", in: "0x1F", out: "0x1F"},
+		// bool field.
+		{field: "Bool", in: -1, out: true},
+		{field: "Bool", in: int64(math.MinInt64), out: true},
+		{field: "Bool", in: 0, out: false},
+		{field: "Bool", in: "true", out: true},
+		{field: "Bool", in: "0x1", err: true},
+	}
+	for _, c := range cases {
+		value, err := field(c.field).AsValue(c.in)
+		if c.err {
+			g.Expect(err).ToNot(gomega.BeNil(), "%s: %v", c.field, c.in)
+			continue
+		}
+		g.Expect(err).To(gomega.BeNil(), "%s: %v", c.field, c.in)
+		g.Expect(value).To(gomega.Equal(c.out), "%s: %v", c.field, c.in)
+	}
+}
+
+func TestFieldRef(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-field-ref.db",
+		&TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	for i := 0; i < 5; i++ {
+		err = DB.Insert(
+			&TestObject{
+				TestBase: TestBase{Phone: "1"},
+				ID:       i,
+				Age:      i % 2,
+				Name:     fmt.Sprintf("%d", i),
+			})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	ids := func(list []TestObject) (ids []int) {
+		for _, m := range list {
+			ids = append(ids, m.ID)
+		}
+		return
+	}
+	list := []TestObject{}
+	err = DB.List(&list, ListOptions{Predicate: Neq("Age", FieldRef("ID"))})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ids(list)).To(gomega.ConsistOf(2, 3, 4))
+	err = DB.List(&list, ListOptions{Predicate: Eq("Name", FieldRef("Phone"))})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ids(list)).To(gomega.ConsistOf(1))
+	err = DB.List(&list, ListOptions{Predicate: Gt("ID", FieldRef("Age"))})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ids(list)).To(gomega.ConsistOf(2, 3, 4))
+	// Incompatible.
+	err = DB.List(&list, ListOptions{Predicate: Eq("Name", FieldRef("Age"))})
+	g.Expect(errors.Is(err, PredicateTypeErr)).To(gomega.BeTrue())
+	err = DB.List(&list, ListOptions{Predicate: Eq("Name", FieldRef("Object"))})
+	g.Expect(errors.Is(err, PredicateTypeErr)).To(gomega.BeTrue())
+	// Unknown.
+	err = DB.List(&list, ListOptions{Predicate: Eq("Name", FieldRef("Unknown"))})
+	g.Expect(errors.Is(err, PredicateRefErr)).To(gomega.BeTrue())
+}
+
+func TestJoin(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-join.db",
+		&TestObject{},
+		&TestChild{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	objects := []*TestObject{}
+	for i := 0; i < 3; i++ {
+		object := &TestObject{ID: i, Name: fmt.Sprintf("object-%d", i)}
+		err = DB.Insert(object)
+		g.Expect(err).To(gomega.BeNil())
+		objects = append(objects, object)
+	}
+	// object 0 has 2 children; object 1 has 1; object 2 has none.
+	for i, parent := range []int{0, 0, 1} {
+		err = DB.Insert(
+			&TestChild{
+				PK:     fmt.Sprintf("c%d", i),
+				Parent: objects[parent].PK,
+				Name:   fmt.Sprintf("child-%d", i),
+				Age:    i,
+			})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	names := func(rows []JoinRow) (names []string) {
+		for _, row := range rows {
+			name := row.Left.(*TestObject).Name + "/"
+			if row.Right != nil {
+				name += row.Right.(*TestChild).Name
+			}
+			names = append(names, name)
+		}
+		return
+	}
+	// Inner (derived from FK).
+	rows, err := DB.Join(
+		Join{
+			Left:  &TestObject{},
+			Right: &TestChild{},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(names(rows)).To(gomega.ConsistOf(
+		"object-0/child-0",
+		"object-0/child-1",
+		"object-1/child-2"))
+	// Left.
+	rows, err = DB.Join(
+		Join{
+			Kind:  JoinLeft,
+			Left:  &TestObject{},
+			Right: &TestChild{},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(names(rows)).To(gomega.ConsistOf(
+		"object-0/child-0",
+		"object-0/child-1",
+		"object-1/child-2",
+		"object-2/"))
+	// Predicates (both sides) with the same field names.
+	rows, err = DB.Join(
+		Join{
+			Left:           &TestChild{},
+			Right:          &TestObject{},
+			LeftPredicate:  Gt("Age", 0),
+			RightPredicate: Eq("Name", "object-0"),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(rows)).To(gomega.Equal(1))
+	g.Expect(rows[0].Left.(*TestChild).Name).To(gomega.Equal("child-1"))
+	g.Expect(rows[0].Right.(*TestObject).ID).To(gomega.Equal(0))
+	// Explicit.
+	rows, err = DB.Join(
+		Join{
+			Left:  &TestObject{},
+			Right: &TestChild{},
+			On:    []JoinOn{{Left: "Age", Right: "Age"}},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(rows)).To(gomega.Equal(3))
+	// Invalid.
+	_, err = DB.Join(
+		Join{
+			Left:  &TestObject{},
+			Right: &TestObject{},
+		})
+	g.Expect(errors.Is(err, JoinErr)).To(gomega.BeTrue())
+	_, err = DB.Join(
+		Join{
+			Left:  &TestObject{},
+			Right: &TestChild{},
+			On:    []JoinOn{{Left: "Name", Right: "Age"}},
+		})
+	g.Expect(errors.Is(err, JoinErr)).To(gomega.BeTrue())
+}
+
+func TestListCapacity(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-list-capacity.db",
+		&TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	for i := 0; i < 3; i++ {
+		err = DB.Insert(&TestObject{ID: i})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	list := []TestObject{}
+	err = DB.List(&list, ListOptions{Capacity: 10})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(3))
+	g.Expect(cap(list)).To(gomega.Equal(10))
+	err = DB.List(&list, ListOptions{Page: &Page{Limit: 5}})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(3))
+	g.Expect(cap(list)).To(gomega.Equal(5))
+	err = DB.List(&list, ListOptions{Page: &Page{Limit: MaxPageCapacity + 1}})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(cap(list)).To(gomega.Equal(MaxPageCapacity))
+}
+
+func BenchmarkListCapacity(b *testing.B) {
+	DB := New(
+		"/tmp/bench-list-capacity.db",
+		&TestObject{})
+	err := DB.Open(true)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	N := 100000
+	tx, err := DB.Begin()
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < N; i++ {
+		err = tx.Insert(&TestObject{ID: i})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		b.Fatal(err)
+	}
+	for _, capacity := range []int{0, N} {
+		b.Run(fmt.Sprintf("capacity=%d", capacity), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				list := []TestObject{}
+				err = DB.List(&list, ListOptions{Capacity: capacity})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkList(b *testing.B) {
+	DB := New(
+		"/tmp/bench-list.db",
+		&TestObject{})
+	err := DB.Open(true)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	N := 5000
+	tx, err := DB.Begin()
+	if err != nil {
+		b.Fatal(err)
+	}
+	for i := 0; i < N; i++ {
+		err = tx.Insert(
+			&TestObject{
+				ID:    i,
+				Name:  fmt.Sprintf("object-%d", i),
+				Slice: []string{"a", "b"},
+			})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		list := []TestObject{}
+		err = DB.List(&list, ListOptions{Detail: DetailAll})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/test-compile.db",
+		&TestObject{})
+	err := DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = DB.Close(true)
+	}()
+	for i := 0; i < 10; i++ {
+		err = DB.Insert(&TestObject{ID: i, Age: i % 3})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	q, err := DB.Compile(
+		&TestObject{},
+		ListOptions{
+			Detail:    DetailAll,
+			Predicate: And(Eq("Age", 0), Gt("ID", 0)),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = q.Close()
+	}()
+	g.Expect(q.Names()).To(gomega.Equal([]string{"Age0", "ID1"}))
+	ids := func(list []TestObject) (ids []int) {
+		for _, m := range list {
+			ids = append(ids, m.ID)
+		}
+		return
+	}
+	// Compiled values.
+	list := []TestObject{}
+	err = q.Run(&list)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ids(list)).To(gomega.Equal([]int{3, 6, 9}))
+	// Values replaced.
+	for age := 0; age < 3; age++ {
+		err = q.Run(&list, age, 4)
+		g.Expect(err).To(gomega.BeNil())
+		for _, m := range list {
+			g.Expect(m.Age).To(gomega.Equal(age))
+			g.Expect(m.ID > 4).To(gomega.BeTrue())
+		}
+	}
+	// Invalid.
+	err = q.Run(&list, 1)
+	g.Expect(errors.Is(err, QueryParamErr)).To(gomega.BeTrue())
+	err = q.Run(&[]TestChild{})
+	g.Expect(errors.Is(err, MustBeSlicePtrErr)).To(gomega.BeTrue())
+	// Within a transaction.
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	err = tx.Insert(&TestObject{ID: 12})
+	g.Expect(err).To(gomega.BeNil())
+	txq, err := tx.Compile(&TestObject{}, ListOptions{Predicate: Eq("ID", 12)})
+	g.Expect(err).To(gomega.BeNil())
+	err = txq.Run(&list)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	g.Expect(txq.Close()).To(gomega.BeNil())
+}
+
+func TestSchemaError(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	// Validate names the field.
+	table := Table{}
+	fields, err := table.Fields(&TestVirtualKey{})
+	g.Expect(err).To(gomega.BeNil())
+	err = table.Validate(fields)
+	schemaErr := &SchemaError{}
+	g.Expect(errors.As(err, &schemaErr)).To(gomega.BeTrue())
+	g.Expect(schemaErr.Field).ToNot(gomega.BeEmpty())
+	g.Expect(errors.Is(err, MutableKeyErr)).To(gomega.BeTrue())
+	// DDL names the model.
+	_, err = table.DDL(&TestBadTouch{})
+	g.Expect(errors.As(err, &schemaErr)).To(gomega.BeTrue())
+	g.Expect(schemaErr.Model).To(gomega.Equal("TestBadTouch"))
+	g.Expect(errors.Is(err, TouchErr)).To(gomega.BeTrue())
+	g.Expect(err.Error()).To(gomega.HavePrefix("model: TestBadTouch "))
+	// Open returns (not panic) the error.
+	DB := NewInMemory(
+		&Label{},
+		&TestObject{},
+		&TestBadTouch{})
+	err = DB.Open(true)
+	g.Expect(errors.As(err, &schemaErr)).To(gomega.BeTrue())
+	g.Expect(schemaErr.Model).To(gomega.Equal("TestBadTouch"))
+	g.Expect(errors.Is(err, TouchErr)).To(gomega.BeTrue())
+}
+
+func TestStrictBool(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	// DDL.
+	table := Table{StrictBool: true}
+	ddl, err := table.DDL(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("Bool INTEGER NOT NULL DEFAULT 0"))
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("CHECK (Bool IN (0,1))"))
+	ddl, err = Table{}.DDL(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ddl[0]).ToNot(gomega.ContainSubstring("DEFAULT 0"))
+	g.Expect(ddl[0]).ToNot(gomega.ContainSubstring("CHECK (Bool"))
+	// Round-trip.
+	DB := NewInMemory(
+		&Label{},
+		&TestObject{})
+	DB.(*Client).StrictBool = true
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	for i, b := range []bool{true, false} {
+		err = DB.Insert(&TestObject{ID: i, Bool: b})
+		g.Expect(err).To(gomega.BeNil())
+		got := &TestObject{ID: i}
+		err = DB.Get(got)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(got.Bool).To(gomega.Equal(b))
+	}
+	// Predicates coerced to (0,1).
+	list := []TestObject{}
+	err = DB.List(&list, ListOptions{Detail: DetailAll, Predicate: Eq("Bool", 2)})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	g.Expect(list[0].Bool).To(gomega.BeTrue())
+	err = DB.List(&list, ListOptions{Detail: DetailAll, Predicate: Eq("Bool", "false")})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	g.Expect(list[0].Bool).To(gomega.BeFalse())
+	// Rejected by the DB.
+	db := DB.(*Client).db
+	_, err = db.Exec("UPDATE TestObject SET Bool = 2")
+	g.Expect(err).ToNot(gomega.BeNil())
+}
+
+func TestBoolText(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	// DDL.
+	ddl, err := Table{}.DDL(&TestTextBool{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("Text TEXT NOT NULL"))
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("Int INTEGER NOT NULL"))
+	ddl, err = Table{BoolText: true, StrictBool: true}.DDL(&TestTextBool{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("Int TEXT NOT NULL DEFAULT 'false'"))
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("CHECK (Int IN ('true','false'))"))
+	type Invalid struct {
+		PK  string `sql:"pk"`
+		Age int    `sql:"text"`
+	}
+	_, err = Table{}.DDL(&Invalid{})
+	g.Expect(errors.Is(err, BoolTextErr)).To(gomega.BeTrue())
+	// Round-trip (both storage modes).
+	for _, boolText := range []bool{false, true} {
+		DB := NewInMemory(
+			&Label{},
+			&TestTextBool{})
+		DB.(*Client).BoolText = boolText
+		err = DB.Open(true)
+		g.Expect(err).To(gomega.BeNil())
+		for i, b := range []bool{true, false} {
+			err = DB.Insert(&TestTextBool{ID: i, Text: b, Int: b})
+			g.Expect(err).To(gomega.BeNil())
+			got := &TestTextBool{ID: i}
+			err = DB.Get(got)
+			g.Expect(err).To(gomega.BeNil())
+			g.Expect(got.Text).To(gomega.Equal(b))
+			g.Expect(got.Int).To(gomega.Equal(b))
+		}
+		list := []TestTextBool{}
+		err = DB.List(
+			&list,
+			ListOptions{
+				Detail:    DetailAll,
+				Predicate: And(Eq("Text", true), Eq("Int", 1)),
+			})
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(len(list)).To(gomega.Equal(1))
+		g.Expect(list[0].ID).To(gomega.Equal(0))
+		// Stored.
+		db := DB.(*Client).db
+		text := ""
+		err = db.QueryRow("SELECT Text FROM TestTextBool WHERE ID = 0").Scan(&text)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(text).To(gomega.Equal("true"))
+		var stored interface{}
+		err = db.QueryRow("SELECT Int FROM TestTextBool WHERE ID = 0").Scan(&stored)
+		g.Expect(err).To(gomega.BeNil())
+		if boolText {
+			g.Expect(stored).To(gomega.Equal("true"))
+		} else {
+			g.Expect(stored).To(gomega.Equal(int64(1)))
+		}
+		_ = DB.Close(true)
+	}
+}
+
+func TestMapField(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	// DDL.
+	ddl, err := Table{}.DDL(&TestMapped{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ddl[0]).ToNot(gomega.ContainSubstring("Tags"))
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("Data TEXT NOT NULL"))
+	g.Expect(ddl[1]).To(gomega.ContainSubstring("CREATE TABLE IF NOT EXISTS TestMapped_Tags"))
+	g.Expect(ddl[1]).To(gomega.ContainSubstring("REFERENCES TestMapped (PK) ON DELETE CASCADE"))
+	type Invalid struct {
+		PK   string         `sql:"pk"`
+		Tags map[string]int `sql:"map"`
+	}
+	_, err = Table{}.DDL(&Invalid{})
+	g.Expect(errors.Is(err, MapErr)).To(gomega.BeTrue())
+	type InvalidKind struct {
+		PK   string `sql:"pk"`
+		Tags string `sql:"map"`
+	}
+	_, err = Table{}.DDL(&InvalidKind{})
+	g.Expect(errors.Is(err, MapErr)).To(gomega.BeTrue())
+	// Round-trip.
+	DB := NewInMemory(
+		&Label{},
+		&TestMapped{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	for i := 0; i < 3; i++ {
+		m := &TestMapped{
+			ID: i,
+			Tags: map[string]string{
+				"id":   strconv.Itoa(i),
+				"even": strconv.FormatBool(i%2 == 0),
+			},
+			Data: map[string]string{"a": "b"},
+		}
+		err = DB.Insert(m)
+		g.Expect(err).To(gomega.BeNil())
+	}
+	got := &TestMapped{ID: 1}
+	err = DB.Get(got)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(got.Tags).To(gomega.Equal(map[string]string{"id": "1", "even": "false"}))
+	g.Expect(got.Data).To(gomega.Equal(map[string]string{"a": "b"}))
+	// Query by key.
+	list := []TestMapped{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Detail:    DetailAll,
+			Predicate: MapEq("Tags", "even", "true"),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	g.Expect(list[0].Tags["even"]).To(gomega.Equal("true"))
+	g.Expect(list[1].Tags["even"]).To(gomega.Equal("true"))
+	count, err := DB.Count(&TestMapped{}, MapHas("Tags", "id"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(count).To(gomega.Equal(int64(3)))
+	_, err = DB.Count(&TestMapped{}, MapHas("Data", "a"))
+	g.Expect(errors.Is(err, PredicateRefErr)).To(gomega.BeTrue())
+	err = DB.List(&list, ListOptions{Predicate: MapHas("Unknown", "a")})
+	g.Expect(errors.Is(err, PredicateRefErr)).To(gomega.BeTrue())
+	// Fetched in chunks.
+	table := Table{DB: DB.(*Client).db, Limits: Limits{Variables: 2}}
+	err = table.List(&list, ListOptions{Detail: DetailAll})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(3))
+	for _, m := range list {
+		g.Expect(m.Tags["id"]).To(gomega.Equal(strconv.Itoa(m.ID)))
+		g.Expect(len(m.Tags)).To(gomega.Equal(2))
+	}
+	// Not fetched by detail.
+	err = DB.List(&list, ListOptions{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(list[0].Tags).To(gomega.BeNil())
+	// Update replaces.
+	got.Tags = map[string]string{"x": "y"}
+	err = DB.Update(got)
+	g.Expect(err).To(gomega.BeNil())
+	got = &TestMapped{ID: 1}
+	err = DB.Get(got)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(got.Tags).To(gomega.Equal(map[string]string{"x": "y"}))
+	// Set/Get.
+	err = DB.MapSet(got, "Tags", "role", "hunter")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(got.Tags["role"]).To(gomega.Equal("hunter"))
+	value, found, err := DB.MapGet(&TestMapped{ID: 1}, "Tags", "role")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(found).To(gomega.BeTrue())
+	g.Expect(value).To(gomega.Equal("hunter"))
+	_, found, err = DB.MapGet(&TestMapped{ID: 1}, "Tags", "none")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(found).To(gomega.BeFalse())
+	_, _, err = DB.MapGet(&TestMapped{ID: 1}, "Data", "a")
+	g.Expect(errors.Is(err, MapRefErr)).To(gomega.BeTrue())
+	// Delete.
+	err = DB.Delete(&TestMapped{ID: 1})
+	g.Expect(err).To(gomega.BeNil())
+	n := 0
+	db := DB.(*Client).db
+	err = db.QueryRow("SELECT COUNT(*) FROM TestMapped_Tags").Scan(&n)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(4))
+}
+
+func TestCodec(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	// JSON.
+	object := map[string]string{"url": "http://a?b=1&c=<2>"}
+	b, err := JSONEncoder{}.Encode(object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(string(b)).To(gomega.Equal(`{"url":"http://a?b=1\u0026c=\u003c2\u003e"}`))
+	b, err = JSONEncoder{DisableEscapeHTML: true}.Encode(object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(string(b)).To(gomega.Equal(`{"url":"http://a?b=1&c=<2>"}`))
+	b, err = JSONEncoder{Indent: "  "}.Encode(object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(string(b)).To(gomega.Equal("{\n  \"url\": \"http://a?b=1\\u0026c=\\u003c2\\u003e\"\n}"))
+	// Client.
+	encoder := &TestEncoder{JSONEncoder: JSONEncoder{DisableEscapeHTML: true}}
+	decoder := &TestDecoder{}
+	DB := NewInMemory(
+		&Label{},
+		&TestObject{})
+	DB.(*Client).Encoder = encoder
+	DB.(*Client).Decoder = decoder
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	err = DB.Insert(
+		&TestObject{
+			ID:  1,
+			Map: map[string]int{"<a&b>": 1},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(encoder.count > 0).To(gomega.BeTrue())
+	stored := ""
+	db := DB.(*Client).db
+	err = db.QueryRow("SELECT Map FROM TestObject WHERE ID = 1").Scan(&stored)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(stored).To(gomega.Equal(`{"<a&b>":1}`))
+	got := &TestObject{ID: 1}
+	err = DB.Get(got)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(decoder.count > 0).To(gomega.BeTrue())
+	g.Expect(got.Map).To(gomega.Equal(map[string]int{"<a&b>": 1}))
+}
+
+func TestPushCodecError(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := NewInMemory(
+		&Label{},
+		&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	err = DB.Insert(&TestObject{ID: 1, Map: map[string]int{"a": 1}})
+	g.Expect(err).To(gomega.BeNil())
+	db := DB.(*Client).db
+	_, err = db.Exec("UPDATE TestObject SET Map = '{corrupt' WHERE ID = 1")
+	g.Expect(err).To(gomega.BeNil())
+	// Get.
+	err = DB.Get(&TestObject{ID: 1})
+	codecErr := &CodecError{}
+	g.Expect(errors.As(err, &codecErr)).To(gomega.BeTrue())
+	g.Expect(codecErr.Field).To(gomega.Equal("Map"))
+	g.Expect(err.Error()).To(gomega.ContainSubstring("Map"))
+	// List.
+	list := []TestObject{}
+	err = DB.List(&list, ListOptions{Detail: DetailAll})
+	g.Expect(errors.As(err, &codecErr)).To(gomega.BeTrue())
+	// Not encoded (detail) fields are not decoded.
+	err = DB.List(&list, ListOptions{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+}
+
+func TestPullCodecError(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := NewInMemory(
+		&Label{},
+		&TestAny{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	// Insert.
+	err = DB.Insert(
+		&TestAny{
+			ID:   1,
+			Data: map[string]interface{}{"ch": make(chan int)},
+		})
+	codecErr := &CodecError{}
+	g.Expect(errors.As(err, &codecErr)).To(gomega.BeTrue())
+	g.Expect(codecErr.Field).To(gomega.Equal("Data"))
+	n, err := DB.Count(&TestAny{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(0)))
+	// Update.
+	m := &TestAny{
+		ID:   1,
+		Data: map[string]interface{}{"a": "b"},
+	}
+	err = DB.Insert(m)
+	g.Expect(err).To(gomega.BeNil())
+	m.Data["ch"] = make(chan int)
+	err = DB.Update(m)
+	g.Expect(errors.As(err, &codecErr)).To(gomega.BeTrue())
+	got := &TestAny{ID: 1}
+	err = DB.Get(got)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(got.Data).To(gomega.Equal(map[string]interface{}{"a": "b"}))
+}
+
+func TestPositional(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	// Rewrite.
+	stmt, args, err := Positional(
+		"SELECT ':a', \"b:c\" FROM T -- :d\nWHERE A = :a /* :e */ AND B = :b OR A = :a;",
+		[]interface{}{
+			sql.Named("a", 1),
+			sql.Named("b", "x"),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(stmt).To(gomega.Equal(
+		"SELECT ':a', \"b:c\" FROM T -- :d\nWHERE A = ? /* :e */ AND B = ? OR A = ?;"))
+	g.Expect(args).To(gomega.Equal([]interface{}{1, "x", 1}))
+	_, _, err = Positional("SELECT :a;", []interface{}{sql.Named("b", 1)})
+	g.Expect(errors.Is(err, ParamErr)).To(gomega.BeTrue())
+	stmt, args, err = Positional("SELECT ?;", []interface{}{1})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(stmt).To(gomega.Equal("SELECT ?;"))
+	g.Expect(args).To(gomega.Equal([]interface{}{1}))
+	// Client.
+	DB := NewInMemory(
+		&Label{},
+		&TestObject{})
+	DB.(*Client).Positional = true
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	for i := 0; i < 5; i++ {
+		err = DB.Insert(&TestObject{ID: i, Name: "Elmer", Age: i})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	m := &TestObject{ID: 2}
+	err = DB.Get(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.Age).To(gomega.Equal(2))
+	m.Name = "Bugs"
+	err = DB.Update(m)
+	g.Expect(err).To(gomega.BeNil())
+	list := []TestObject{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Predicate: And(Eq("Name", "Elmer"), Gt("Age", 0)),
+			Page:      &Page{Limit: 2},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	q, err := DB.Compile(&TestObject{}, ListOptions{Predicate: Eq("Age", 0)})
+	g.Expect(err).To(gomega.BeNil())
+	defer q.Close()
+	g.Expect(q.SQL).ToNot(gomega.ContainSubstring(":"))
+	err = q.Run(&list, 3)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	g.Expect(list[0].ID).To(gomega.Equal(3))
+	err = DB.Delete(m)
+	g.Expect(err).To(gomega.BeNil())
+	n, err := DB.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(4)))
+	// Bound by position.
+	recorder := &TestRecorder{DB: DB.(*Client).db}
+	table := Table{DB: recorder, Positional: true}
+	err = table.List(&list, ListOptions{Predicate: Eq("Name", "Elmer")})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(recorder.args)).To(gomega.Equal(1))
+	g.Expect(recorder.args[0]).To(gomega.Equal("Elmer"))
+}
+
+func TestDropAll(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/drop.db",
+		&TestChild{},
+		&Label{},
+		&TestObject{},
+		&TestMapped{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	object := &TestObject{ID: 1, Name: "Elmer"}
+	err = DB.Insert(object)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&TestChild{Parent: object.PK, Name: "Bugs"})
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&TestMapped{ID: 1, Tags: map[string]string{"a": "b"}})
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.SetPragma("cache_size", "-4000")
+	g.Expect(err).To(gomega.BeNil())
+	// Drop.
+	err = DB.DropAll()
+	g.Expect(err).To(gomega.BeNil())
+	tables := func() (n int) {
+		db := DB.(*Client).db
+		err := db.QueryRow(
+			"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'").Scan(&n)
+		g.Expect(err).To(gomega.BeNil())
+		return
+	}
+	g.Expect(tables()).To(gomega.Equal(0))
+	_, err = os.Stat("/tmp/drop.db")
+	g.Expect(err).To(gomega.BeNil())
+	pragma, err := DB.GetPragma("cache_size")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(pragma).To(gomega.Equal("-4000"))
+	// Rebuilt by Open.
+	err = DB.Open(false)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(tables()).To(gomega.Equal(5))
+	n, err := DB.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(0)))
+	err = DB.Insert(&TestObject{ID: 1, Name: "Elmer"})
+	g.Expect(err).To(gomega.BeNil())
+}
+
+func TestCreateTable(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/create.db",
+		&TestChild{},
+		&Label{},
+		&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	object := &TestObject{ID: 1, Name: "Elmer"}
+	err = DB.Insert(object)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&TestChild{Parent: object.PK, Name: "Bugs"})
+	g.Expect(err).To(gomega.BeNil())
+	// Referenced.
+	err = DB.DropTable(&TestObject{})
+	g.Expect(errors.Is(err, DropRefErr)).To(gomega.BeTrue())
+	n, err := DB.Count(&TestChild{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(1)))
+	// Drop (FK order).
+	err = DB.DropTable(&TestChild{})
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.DropTable(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	_, err = DB.Count(&TestObject{}, nil)
+	g.Expect(err).ToNot(gomega.BeNil())
+	// Rebuild.
+	err = DB.CreateTable(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.CreateTable(&TestChild{})
+	g.Expect(err).To(gomega.BeNil())
+	object = &TestObject{ID: 1, Name: "Elmer"}
+	err = DB.Insert(object)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&TestChild{Parent: object.PK, Name: "Bugs"})
+	g.Expect(err).To(gomega.BeNil())
+	n, err = DB.Count(&TestChild{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(1)))
+	// Onboard (unregistered) model.
+	err = DB.CreateTable(&TestMapped{})
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&TestMapped{ID: 1, Tags: map[string]string{"a": "b"}})
+	g.Expect(err).To(gomega.BeNil())
+	n, err = DB.Count(&TestMapped{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(1)))
+	g.Expect(len(DB.(*Client).models)).To(gomega.Equal(4))
+	// Not valid (not registered).
+	err = DB.CreateTable(&TestBadEnum{})
+	g.Expect(err).ToNot(gomega.BeNil())
+	g.Expect(len(DB.(*Client).models)).To(gomega.Equal(4))
+	_, found := DB.(*Client).kinds["TestBadEnum"]
+	g.Expect(found).To(gomega.BeFalse())
+	err = DB.Open(false)
+	g.Expect(err).To(gomega.BeNil())
+	// Read-only.
+	DB.(*Client).ReadOnly = true
+	err = DB.CreateTable(&TestMapped{})
+	g.Expect(errors.Is(err, ReadOnlyErr)).To(gomega.BeTrue())
+	err = DB.DropTable(&TestMapped{})
+	g.Expect(errors.Is(err, ReadOnlyErr)).To(gomega.BeTrue())
+}
+
+func TestValidateModel(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	// Valid.
+	err := ValidateModel(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	err = ValidateModel(&TestChild{}, &TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	err = ValidateModel(TestObject{})
+	g.Expect(errors.Is(err, MustBePtrErr)).To(gomega.BeTrue())
+	// All problems reported.
+	err = ValidateModel(&TestInvalid{}, &TestObject{})
+	g.Expect(err).ToNot(gomega.BeNil())
+	vErr := &ValidationError{}
+	g.Expect(errors.As(err, &vErr)).To(gomega.BeTrue())
+	g.Expect(vErr.Model).To(gomega.Equal("TestInvalid"))
+	g.Expect(len(vErr.Errors)).To(gomega.Equal(4))
+	g.Expect(errors.Is(err, EnumErr)).To(gomega.BeTrue())
+	g.Expect(errors.Is(err, CollationErr)).To(gomega.BeTrue())
+	g.Expect(errors.Is(err, MustHavePkErr)).To(gomega.BeTrue())
+	g.Expect(errors.Is(err, FkErr)).To(gomega.BeTrue())
+	g.Expect(errors.Is(err, MapErr)).To(gomega.BeFalse())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("field: Color"))
+	g.Expect(err.Error()).To(gomega.ContainSubstring("field: Parent"))
+	// FK references unknown model are not validated.
+	err = ValidateModel(&TestInvalid{})
+	g.Expect(errors.Is(err, FkErr)).To(gomega.BeFalse())
+}
+
+func TestValidateAll(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	table := Table{}
+	fields, err := table.Fields(&TestInvalid{})
+	g.Expect(err).To(gomega.BeNil())
+	err = table.Validate(fields)
+	vErr := &ValidationError{}
+	g.Expect(errors.As(err, &vErr)).To(gomega.BeTrue())
+	g.Expect(len(vErr.Errors)).To(gomega.Equal(3))
+	g.Expect(errors.Is(err, EnumErr)).To(gomega.BeTrue())
+	g.Expect(errors.Is(err, CollationErr)).To(gomega.BeTrue())
+	g.Expect(errors.Is(err, MustHavePkErr)).To(gomega.BeTrue())
+	g.Expect(errors.Is(err, TouchErr)).To(gomega.BeFalse())
+	schemaErr := &SchemaError{}
+	g.Expect(errors.As(err, &schemaErr)).To(gomega.BeTrue())
+	g.Expect(schemaErr.Field).To(gomega.Equal("Color"))
+	g.Expect(err.Error()).To(gomega.HavePrefix("(3) problems: "))
+	// DDL names the model.
+	_, err = table.DDL(&TestInvalid{})
+	g.Expect(err.Error()).To(gomega.HavePrefix("model: TestInvalid (3) problems: "))
+	g.Expect(errors.Is(err, CollationErr)).To(gomega.BeTrue())
+	// Single problem.
+	fields, err = table.Fields(&TestVirtualKey{})
+	g.Expect(err).To(gomega.BeNil())
+	err = table.Validate(fields)
+	g.Expect(errors.As(err, &vErr)).To(gomega.BeFalse())
+	g.Expect(errors.Is(err, MutableKeyErr)).To(gomega.BeTrue())
+}
+
+func TestLog(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	log := &TestLogger{}
+	DB := New(
+		"/tmp/log.db",
+		&Label{},
+		&TestObject{})
+	DB.(*Client).Log = log
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Migrate([]Migration{{Version: 1}})
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.DropTable(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.CreateTable(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Close(true)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(log.entries).To(
+		gomega.Equal(
+			[]string{
+				"Opened.",
+				"Migration applied.",
+				"Table dropped.",
+				"Table created.",
+				"Closed.",
+			}))
+	// Default.
+	DB = NewInMemory(&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Close(true)
+	g.Expect(err).To(gomega.BeNil())
+}
+
+func TestLogStatements(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	type traceKey struct{}
+	log := &TestLogger{}
+	DB := NewInMemory(&TestObject{})
+	client := DB.(*Client)
+	client.Log = log
+	client.LogStatements = true
+	client.TraceKey = traceKey{}
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	// Client.
+	log.entries = nil
+	log.values = nil
+	_, err = DB.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(log.entries).To(gomega.Equal([]string{"Statement."}))
+	g.Expect(log.values[0][0]).To(gomega.Equal("sql"))
+	g.Expect(log.values[0][1]).To(gomega.ContainSubstring("COUNT(*)"))
+	g.Expect(len(log.values[0])).To(gomega.Equal(2))
+	// Transaction (traced).
+	log.entries = nil
+	log.values = nil
+	ctx := context.WithValue(context.TODO(), traceKey{}, "reconcile-1")
+	tx, err := DB.BeginTx(ctx)
+	g.Expect(err).To(gomega.BeNil())
+	defer tx.Rollback()
+	_, err = tx.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(log.values)).To(gomega.Equal(1))
+	g.Expect(log.values[0][2:]).To(
+		gomega.Equal([]interface{}{"trace", "reconcile-1"}))
+	// Positional (rewritten) statement logged.
+	client.Positional = true
+	log.values = nil
+	_, err = DB.Count(&TestObject{}, Eq("ID", 0))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(log.values[0][1]).To(gomega.ContainSubstring("?"))
+	// Disabled.
+	client.LogStatements = false
+	log.entries = nil
+	_, err = DB.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(log.entries).To(gomega.BeEmpty())
+}
+
+func TestStats(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/stats.db",
+		&Label{},
+		&TestObject{})
+	stats := DB.Stats()
+	g.Expect(stats.Open).To(gomega.BeFalse())
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	stats = DB.Stats()
+	g.Expect(stats.Open).To(gomega.BeTrue())
+	g.Expect(stats.TxOpen).To(gomega.BeFalse())
+	g.Expect(stats.Models).To(gomega.Equal(2))
+	g.Expect(stats.Statements).To(gomega.Equal(uint64(0)))
+	_, err = DB.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&TestObject{ID: 1, Name: "Elmer"})
+	g.Expect(err).To(gomega.BeNil())
+	stats = DB.Stats()
+	g.Expect(stats.Statements >= 2).To(gomega.BeTrue())
+	// Transaction.
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	stats = DB.Stats()
+	g.Expect(stats.TxOpen).To(gomega.BeTrue())
+	g.Expect(stats.Pool.InUse).To(gomega.Equal(1))
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+	stats = DB.Stats()
+	g.Expect(stats.TxOpen).To(gomega.BeFalse())
+	g.Expect(stats.Pool.InUse).To(gomega.Equal(0))
+	// Closed.
+	err = DB.Close(false)
+	g.Expect(err).To(gomega.BeNil())
+	stats = DB.Stats()
+	g.Expect(stats.Open).To(gomega.BeFalse())
+}
+
+func TestSingleWriter(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New(
+		"/tmp/writer.db",
+		&Label{},
+		&TestObject{})
+	DB.(*Client).SingleWriter = true
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	// Concurrent writers (and transactions).
+	writers := 20
+	n := 50
+	errs := make(chan error, (writers+1)*n*2)
+	wg := sync.WaitGroup{}
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				id := w*n + i
+				object := &TestObject{
+					ID:   id,
+					Name: fmt.Sprintf("n%d", id),
+				}
+				errs <- DB.Insert(object)
+				object.Age = id
+				errs <- DB.Update(object)
+			}
+		}(w)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < n; i++ {
+			tx, err := DB.Begin()
+			if err != nil {
+				errs <- err
+				return
+			}
+			errs <- tx.Insert(
+				&TestObject{
+					ID:   -(i + 1),
+					Name: fmt.Sprintf("tx%d", i),
+				})
+			errs <- tx.Commit()
+		}
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		g.Expect(err).To(gomega.BeNil())
+	}
+	count, err := DB.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(count).To(gomega.Equal(int64(writers*n + n)))
+	count, err = DB.Count(&TestObject{}, Gt("Age", 0))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(count).To(gomega.Equal(int64(writers*n - 1)))
+	// Closed.
+	err = DB.Close(false)
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&TestObject{ID: 1})
+	g.Expect(errors.Is(err, ClosedErr)).To(gomega.BeTrue())
+}
+
+func TestTransform(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	reverse := func(s string) string {
+		r := []rune(s)
+		for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+			r[i], r[j] = r[j], r[i]
+		}
+		return string(r)
+	}
+	// Unknown transform.
+	_, err = Table{}.DDL(&TestTransformed{})
+	g.Expect(errors.Is(err, TransformErr)).To(gomega.BeTrue())
+	DB := NewInMemory(&TestTransformed{})
+	DB.(*Client).Transforms = map[string]Transform{
+		"reverse": func(s string) string {
+			if strings.HasPrefix(s, "~") {
+				return s
+			}
+			return "~" + reverse(s)
+		},
+	}
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	// Key hashed consistently regardless of case.
+	upper := &TestTransformed{Name: "ELMER", Code: " ab ", Alias: "fudd"}
+	err = DB.Insert(upper)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(upper.Name).To(gomega.Equal("elmer"))
+	g.Expect(upper.Code).To(gomega.Equal("AB"))
+	g.Expect(upper.Alias).To(gomega.Equal("~dduf"))
+	mixed := &TestTransformed{Name: "Elmer"}
+	table := Table{}
+	fields, err := table.Fields(mixed)
+	g.Expect(err).To(gomega.BeNil())
+	err = table.SetPk(fields)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(mixed.PK).To(gomega.Equal(upper.PK))
+	err = DB.Insert(&TestTransformed{Name: "eLmEr", Code: "cd"})
+	g.Expect(err).To(gomega.BeNil())
+	n, err := DB.Count(&TestTransformed{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(1)))
+	// Get (by natural key).
+	got := &TestTransformed{Name: "ELMER"}
+	err = DB.Get(got)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(got.Name).To(gomega.Equal("elmer"))
+	g.Expect(got.Code).To(gomega.Equal("CD"))
+	// Predicate.
+	list := []TestTransformed{}
+	err = DB.List(&list, ListOptions{Predicate: Eq("Name", "ElMeR")})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	// Not str.
+	type TestBadTransform struct {
+		ID int `sql:"pk,transform(lower)"`
+	}
+	fields, err = table.Fields(&TestBadTransform{})
+	g.Expect(err).To(gomega.BeNil())
+	err = table.Validate(fields)
+	g.Expect(errors.Is(err, TransformErr)).To(gomega.BeTrue())
+}
+
+func TestDuplicateKeys(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	type NonUnique struct {
+		PK    string `sql:"pk"`
+		First string `sql:"key,nonunique"`
+		Last  string `sql:"key,nonunique"`
+	}
+	DB := NewInMemory(&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	table := Table{DB: DB.(*Client).db}
+	ddl, err := table.DDL(&NonUnique{})
+	g.Expect(err).To(gomega.BeNil())
+	for _, stmt := range ddl {
+		_, err = table.DB.Exec(stmt)
+		g.Expect(err).To(gomega.BeNil())
+	}
+	for _, m := range []*NonUnique{
+		{PK: "A", First: "Elmer", Last: "Fudd"},
+		{PK: "B", First: "Elmer", Last: "Fudd"},
+		{PK: "C", First: "Elmer", Last: "Fudd"},
+		{PK: "D", First: "Bugs", Last: "Bunny"},
+		{PK: "E", First: "Daffy", Last: "Duck"},
+		{PK: "F", First: "Daffy", Last: "Duck"},
+	} {
+		err = table.Insert(m)
+		g.Expect(err).To(gomega.BeNil())
+	}
+	duplicates, err := table.DuplicateKeys(&NonUnique{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(duplicates).To(
+		gomega.Equal(
+			[]GroupCount{
+				{Keys: []string{"Daffy", "Duck"}, Count: 2},
+				{Keys: []string{"Elmer", "Fudd"}, Count: 3},
+			}))
+	// None.
+	err = DB.Insert(&TestObject{ID: 1, Name: "Elmer"})
+	g.Expect(err).To(gomega.BeNil())
+	duplicates, err = DB.DuplicateKeys(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(duplicates).To(gomega.BeEmpty())
+	// No natural key.
+	type NoKey struct {
+		PK   string `sql:"pk"`
+		Name string `sql:""`
+	}
+	_, err = table.DuplicateKeys(&NoKey{})
+	g.Expect(errors.Is(err, GroupFieldErr)).To(gomega.BeTrue())
+}
+
+func TestHaving(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := NewInMemory(&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	for i, name := range []string{"a", "a", "a", "b", "b", "c"} {
+		err = DB.Insert(&TestObject{ID: i, Name: name, Age: i})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	// Count.
+	groups, err := DB.CountGroups(
+		&TestObject{},
+		[]string{"Name"},
+		ListOptions{Having: CountAll().Gt(1)})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(groups).To(
+		gomega.Equal(
+			[]GroupCount{
+				{Keys: []string{"a"}, Count: 3},
+				{Keys: []string{"b"}, Count: 2},
+			}))
+	// Combined with predicate and aggregate.
+	groups, err = DB.CountGroups(
+		&TestObject{},
+		[]string{"Name"},
+		ListOptions{
+			Predicate: Gt("Age", 0),
+			Having: And(
+				CountAll().Gt(0),
+				Agg("SUM", "Age").Gt(3)),
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(groups).To(
+		gomega.Equal(
+			[]GroupCount{
+				{Keys: []string{"b"}, Count: 2},
+				{Keys: []string{"c"}, Count: 1},
+			}))
+	// Not grouped.
+	list := []TestObject{}
+	err = DB.List(&list, ListOptions{Having: CountAll().Gt(1)})
+	g.Expect(errors.Is(err, HavingErr)).To(gomega.BeTrue())
+	// Aggregate not in having.
+	err = DB.List(&list, ListOptions{Predicate: CountAll().Gt(1)})
+	g.Expect(errors.Is(err, HavingErr)).To(gomega.BeTrue())
+	// Aggregate not valid.
+	_, err = DB.CountGroups(
+		&TestObject{},
+		[]string{"Name"},
+		ListOptions{Having: Agg("SUM", "Name").Gt(1)})
+	g.Expect(errors.Is(err, PredicateTypeErr)).To(gomega.BeTrue())
+	_, err = DB.CountGroups(
+		&TestObject{},
+		[]string{"Name"},
+		ListOptions{Having: Agg("SUM", "*").Gt(1)})
+	g.Expect(errors.Is(err, PredicateRefErr)).To(gomega.BeTrue())
+}
+
+func TestScalar(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := NewInMemory(&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	// None.
+	v, err := DB.Scalar(&TestObject{}, "MAX", "ID", nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(v).To(gomega.BeNil())
+	v, err = DB.Scalar(&TestObject{}, "count", "ID", nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(v).To(gomega.Equal(int64(0)))
+	for i, name := range []string{"b", "c", "a"} {
+		err = DB.Insert(&TestObject{ID: i + 1, Name: name, Int8: int8(i)})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	v, err = DB.Scalar(&TestObject{}, "MAX", "ID", nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(v).To(gomega.Equal(3))
+	v, err = DB.Scalar(&TestObject{}, "MAX", "Int8", nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(v).To(gomega.Equal(int8(2)))
+	probe := &TestObject{Name: "probe"}
+	v, err = DB.Scalar(probe, "MIN", "Name", nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(v).To(gomega.Equal("a"))
+	g.Expect(probe.Name).To(gomega.Equal("probe"))
+	v, err = DB.Scalar(&TestObject{}, "MAX", "Name", Lt("ID", 3))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(v).To(gomega.Equal("c"))
+	v, err = DB.Scalar(&TestObject{}, "SUM", "ID", nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(v).To(gomega.Equal(int64(6)))
+	v, err = DB.Scalar(&TestObject{}, "AVG", "ID", nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(v).To(gomega.Equal(float64(2)))
+	// Transaction.
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	defer tx.Rollback()
+	err = tx.Insert(&TestObject{ID: 10, Name: "z"})
+	g.Expect(err).To(gomega.BeNil())
+	v, err = tx.Scalar(&TestObject{}, "MAX", "ID", nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(v).To(gomega.Equal(10))
+	err = tx.Rollback()
+	g.Expect(err).To(gomega.BeNil())
+	// Not valid.
+	_, err = DB.Scalar(&TestObject{}, "SUM", "Name", nil)
+	g.Expect(errors.Is(err, AggregateErr)).To(gomega.BeTrue())
+	_, err = DB.Scalar(&TestObject{}, "MAX", "Slice", nil)
+	g.Expect(errors.Is(err, AggregateErr)).To(gomega.BeTrue())
+	_, err = DB.Scalar(&TestObject{}, "LENGTH", "Name", nil)
+	g.Expect(errors.Is(err, AggregateErr)).To(gomega.BeTrue())
+}
+
+func TestCountPredicate(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := NewInMemory(&TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	for i := 0; i < 5; i++ {
+		err = DB.Insert(&TestObject{ID: i, Name: "Elmer", D4: "x"})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	// Valid field.
+	n, err := DB.Count(&TestObject{}, Gt("ID", 1))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(3)))
+	// Field outside the default detail level.
+	n, err = DB.Count(&TestObject{}, Eq("D4", "x"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(5)))
+	// Invalid field.
+	_, err = DB.Count(&TestObject{}, Eq("Unknown", 1))
+	g.Expect(errors.Is(err, PredicateRefErr)).To(gomega.BeTrue())
+	_, err = DB.Count(&TestObject{}, And(Gt("ID", 1), Eq("Unknown", 1)))
+	g.Expect(errors.Is(err, PredicateRefErr)).To(gomega.BeTrue())
+	// Page and sort ignored.
+	table := Table{}
+	fields, err := table.Fields(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	options := ListOptions{
+		Predicate: Gt("ID", 1),
+		Page:      &Page{Limit: 1},
+		Sort:      []int{2},
+	}
+	stmt, err := table.countSQL("TestObject", fields, &options)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(stmt).ToNot(gomega.ContainSubstring("ORDER BY"))
+	g.Expect(stmt).ToNot(gomega.ContainSubstring("LIMIT"))
+	g.Expect(len(options.Params())).To(gomega.Equal(1))
+}
+
+func TestPredicateString(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	g.Expect(Eq("Name", "a").String()).To(gomega.Equal("Name = :p0"))
+	g.Expect(Neq("Desired", FieldRef("Ready")).String()).To(
+		gomega.Equal("Desired != Ready"))
+	g.Expect(In("ID", 1, 2, 3).String()).To(gomega.Equal("ID IN (:p0,:p1,:p2)"))
+	g.Expect(IsNull("Name").String()).To(gomega.Equal("Name IS NULL"))
+	g.Expect(
+		And(
+			Eq("Name", "a"),
+			Gt("Count", 1)).String()).To(
+		gomega.Equal("(Name = :p0 AND Count > :p1)"))
+	g.Expect(
+		Or(
+			Eq("Name", "a"),
+			Eq("Name", "a"),
+			And(Lt("Age", 10), Lower("Name").Eq("b"))).String()).To(
+		gomega.Equal("(Name = :p0 OR Name = :p1 OR (Age < :p2 AND LOWER(Name) = :p3))"))
+	g.Expect(Match(Labels{"b": "2", "a": "1"}).String()).To(
+		gomega.Equal("MATCH(a = :p0,b = :p1)"))
+	g.Expect(Exists(&TestObject{}, "Parent", Gt("Age", 1)).String()).To(
+		gomega.Equal("EXISTS(TestObject.Parent WHERE Age > :p0)"))
+	g.Expect(MapEq("Tags", "k", "v").String()).To(gomega.Equal("Tags[:p0] = :p1"))
+	g.Expect(MapHas("Tags", "k").String()).To(gomega.Equal("HAS Tags[:p0]"))
+	// Distinct from Expr() and independent of Build().
+	p := Eq("Name", "a")
+	g.Expect(p.Expr()).To(gomega.Equal(""))
+	options := ListOptions{Predicate: p}
+	err := options.Build("TestObject", []*Field{})
+	g.Expect(err).ToNot(gomega.BeNil())
+	g.Expect(p.String()).To(gomega.Equal("Name = :p0"))
+}
+
+func TestGetByRowID(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := NewInMemory(&TestRowID{}, &TestNoRowID{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	for _, pk := range []string{"c", "a", "b"} {
+		err = DB.Insert(&TestRowID{PK: pk, Name: "n" + pk})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	// Listed (virtual) rowid.
+	list := []TestRowID{}
+	err = DB.List(&list, ListOptions{Sort: []int{1}})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(3))
+	g.Expect(list[0].Serial).To(gomega.Equal(int64(1)))
+	g.Expect(list[0].PK).To(gomega.Equal("c"))
+	// Keyset pagination.
+	list = []TestRowID{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Predicate: Gt("Serial", 1),
+			Sort:      []int{1},
+			Page:      &Page{Limit: 1},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(1))
+	g.Expect(list[0].PK).To(gomega.Equal("a"))
+	// Get.
+	m := &TestRowID{}
+	err = DB.GetByRowID(m, 3)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.PK).To(gomega.Equal("b"))
+	g.Expect(m.Name).To(gomega.Equal("nb"))
+	g.Expect(m.Serial).To(gomega.Equal(int64(3)))
+	err = DB.GetByRowID(&TestRowID{}, 4)
+	g.Expect(errors.Is(err, NotFound)).To(gomega.BeTrue())
+	// Update does not touch the rowid.
+	m.Serial = 10
+	m.Name = "updated"
+	err = DB.Update(m)
+	g.Expect(err).To(gomega.BeNil())
+	m = &TestRowID{}
+	err = DB.GetByRowID(m, 3)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.Name).To(gomega.Equal("updated"))
+	// Transaction.
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	defer tx.Rollback()
+	m = &TestRowID{}
+	err = tx.GetByRowID(m, 1)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.PK).To(gomega.Equal("c"))
+	// WITHOUT ROWID.
+	err = DB.GetByRowID(&TestNoRowID{}, 1)
+	g.Expect(errors.Is(err, WithoutRowIDErr)).To(gomega.BeTrue())
+	// Not int64.
+	table := Table{}
+	_, err = table.DDL(&TestBadRowID{})
+	g.Expect(errors.Is(err, RowIDErr)).To(gomega.BeTrue())
+}
+
+func TestTxReadYourWrites(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := NewInMemory(&Label{}, &TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	err = DB.Insert(&TestObject{ID: 1, Name: "committed"})
+	g.Expect(err).To(gomega.BeNil())
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	defer tx.End()
+	err = tx.Insert(&TestObject{ID: 2, Name: "pending"})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Update(&TestObject{ID: 1, Name: "updated"})
+	g.Expect(err).To(gomega.BeNil())
+	// Get.
+	object := &TestObject{ID: 2}
+	err = tx.Get(object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(object.Name).To(gomega.Equal("pending"))
+	object = &TestObject{ID: 1}
+	err = tx.Get(object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(object.Name).To(gomega.Equal("updated"))
+	// List.
+	list := []TestObject{}
+	err = tx.List(&list, ListOptions{Detail: DetailAll})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	// Count.
+	n, err := tx.Count(&TestObject{}, Eq("Name", "pending"))
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(1)))
+	err = tx.Delete(&TestObject{ID: 1})
+	g.Expect(err).To(gomega.BeNil())
+	n, err = tx.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(1)))
+	// Committed.
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+	object = &TestObject{ID: 2}
+	err = DB.Get(object)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(object.Name).To(gomega.Equal("pending"))
+}
+
+func TestConcurrentReads(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := New("/tmp/test-wal.db", &Label{}, &TestObject{})
+	DB.(*Client).WAL = true
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	mode, err := DB.GetPragma("journal_mode")
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(mode).To(gomega.Equal("wal"))
+	for i := 0; i < 10; i++ {
+		err = DB.Insert(&TestObject{ID: i, Name: "Elmer"})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	defer tx.End()
+	err = tx.Insert(&TestObject{ID: 100, Name: "Pending"})
+	g.Expect(err).To(gomega.BeNil())
+	// Readers proceed while the transaction is open
+	// and see (only) the committed models.
+	readers := 10
+	done := make(chan error, readers)
+	for i := 0; i < readers; i++ {
+		go func(id int) {
+			list := []TestObject{}
+			err := DB.List(&list, ListOptions{})
+			if err == nil && len(list) != 10 {
+				err = fmt.Errorf("listed: %d", len(list))
+			}
+			if err == nil {
+				err = DB.Get(&TestObject{ID: id})
+			}
+			if err == nil {
+				err = DB.Get(&TestObject{ID: 100})
+				if errors.Is(err, NotFound) {
+					err = nil
+				} else {
+					err = fmt.Errorf("uncommitted model found")
+				}
+			}
+			done <- err
+		}(i)
+	}
+	for i := 0; i < readers; i++ {
+		select {
+		case err = <-done:
+			g.Expect(err).To(gomega.BeNil())
+		case <-time.After(10 * time.Second):
+			t.Fatal("read blocked by transaction.")
+		}
+	}
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+	n, err := DB.Count(&TestObject{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(11)))
+}
+
+func TestAutoIncrementPk(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	table := Table{}
+	ddl, err := table.DDL(&TestAutoIncrement{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("ID INTEGER PRIMARY KEY AUTOINCREMENT"))
+	fields, err := table.Fields(&TestAutoIncrement{})
+	g.Expect(err).To(gomega.BeNil())
+	stmt, err := table.insertSQL("TestAutoIncrement", fields)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(stmt).ToNot(gomega.ContainSubstring(":ID"))
+	_, err = table.DDL(&TestBadAutoIncrement{})
+	g.Expect(errors.Is(err, AutoIncrementErr)).To(gomega.BeTrue())
+	DB := NewInMemory(&Label{}, &TestAutoIncrement{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	// Assigned.
+	for i, name := range []string{"a", "b", "c"} {
+		m := &TestAutoIncrement{Name: name}
+		err = DB.Insert(m)
+		g.Expect(err).To(gomega.BeNil())
+		g.Expect(m.ID).To(gomega.Equal(int64(i + 1)))
+	}
+	m := &TestAutoIncrement{ID: 2}
+	err = DB.Get(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.Name).To(gomega.Equal("b"))
+	// Specified.
+	m = &TestAutoIncrement{ID: 10, Name: "d"}
+	err = DB.Insert(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.ID).To(gomega.Equal(int64(10)))
+	// Not reused after delete.
+	err = DB.Delete(m)
+	g.Expect(err).To(gomega.BeNil())
+	m = &TestAutoIncrement{Name: "e"}
+	err = DB.Insert(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.ID).To(gomega.Equal(int64(11)))
+	// Transaction.
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	defer tx.End()
+	m = &TestAutoIncrement{Name: "f"}
+	err = tx.Insert(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.ID).To(gomega.Equal(int64(12)))
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+}
+
+func TestWriteOnceField(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := NewInMemory(&Label{}, &TestWriteOnce{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	err = DB.Insert(
+		&TestWriteOnce{
+			ID:      1,
+			Created: "monday",
+			Owner:   "elmer",
+			Name:    "a",
+		})
+	g.Expect(err).To(gomega.BeNil())
+	// Const (silent).
+	err = DB.Update(
+		&TestWriteOnce{
+			ID:      1,
+			Created: "tuesday",
+			Owner:   "elmer",
+			Name:    "b",
+		})
+	g.Expect(err).To(gomega.BeNil())
+	m := &TestWriteOnce{ID: 1}
+	err = DB.Get(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.Created).To(gomega.Equal("monday"))
+	g.Expect(m.Name).To(gomega.Equal("b"))
+	// Write-once (error).
+	err = DB.Update(
+		&TestWriteOnce{
+			ID:      1,
+			Created: "monday",
+			Owner:   "bugs",
+			Name:    "c",
+		})
+	g.Expect(errors.Is(err, WriteOnceErr)).To(gomega.BeTrue())
+	woErr := &WriteOnceError{}
+	g.Expect(errors.As(err, &woErr)).To(gomega.BeTrue())
+	g.Expect(woErr.Field).To(gomega.Equal("Owner"))
+	m = &TestWriteOnce{ID: 1}
+	err = DB.Get(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.Owner).To(gomega.Equal("elmer"))
+	g.Expect(m.Name).To(gomega.Equal("b"))
+	// Transaction.
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	defer tx.End()
+	err = tx.Update(&TestWriteOnce{ID: 1, Owner: "", Name: "d"})
+	g.Expect(errors.Is(err, WriteOnceErr)).To(gomega.BeTrue())
+	err = tx.Update(&TestWriteOnce{ID: 1, Owner: "elmer", Name: "d"})
+	g.Expect(err).To(gomega.BeNil())
+	err = tx.Commit()
+	g.Expect(err).To(gomega.BeNil())
+	// Not found.
+	err = DB.Update(&TestWriteOnce{ID: 2, Owner: "elmer"})
+	g.Expect(errors.Is(err, NotFound)).To(gomega.BeTrue())
+}
+
+func TestNestedRoundTrip(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	table := Table{}
+	fields, err := table.Fields(&TestNested{})
+	g.Expect(err).To(gomega.BeNil())
+	names := []string{}
+	for _, f := range fields {
+		names = append(names, f.Name)
+	}
+	g.Expect(names).To(
+		gomega.Equal(
+			[]string{
+				"Name",
+				"Namespace",
+				"Revision",
+				"Status",
+				"Spec",
+				"Kind",
+				"Age",
+			}))
+	DB := NewInMemory(&Label{}, &TestNested{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	for i, name := range []string{"c", "a", "b"} {
+		m := &TestNested{
+			Name:   name,
+			Status: "ready-" + name,
+			Spec: TestNestedSpec{
+				Replicas: i,
+				Images:   []string{"img-" + name},
+			},
+		}
+		m.Namespace = "ns-" + name
+		m.Revision = i + 10
+		m.Owner.Kind = "kind-" + name
+		m.Owner.Age = i + 20
+		err = DB.Insert(m)
+		g.Expect(err).To(gomega.BeNil())
+	}
+	expect := func(m TestNested, name string, i int) {
+		g.Expect(m.Name).To(gomega.Equal(name))
+		g.Expect(m.Namespace).To(gomega.Equal("ns-" + name))
+		g.Expect(m.Revision).To(gomega.Equal(i + 10))
+		g.Expect(m.Status).To(gomega.Equal("ready-" + name))
+		g.Expect(m.Spec.Replicas).To(gomega.Equal(i))
+		g.Expect(m.Spec.Images).To(gomega.Equal([]string{"img-" + name}))
+		g.Expect(m.Owner.Kind).To(gomega.Equal("kind-" + name))
+		g.Expect(m.Owner.Age).To(gomega.Equal(i + 20))
+	}
+	// List.
+	list := []TestNested{}
+	err = DB.List(&list, ListOptions{Detail: DetailAll, Sort: []int{1}})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(3))
+	expect(list[0], "a", 1)
+	expect(list[1], "b", 2)
+	expect(list[2], "c", 0)
+	// Predicate and sort on flattened fields.
+	list = []TestNested{}
+	err = DB.List(
+		&list,
+		ListOptions{
+			Detail:    DetailAll,
+			Predicate: Gt("Age", 20),
+			Sort:      []int{7},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	expect(list[0], "a", 1)
+	expect(list[1], "b", 2)
+	// Get.
+	m := &TestNested{Name: "c"}
+	err = DB.Get(m)
+	g.Expect(err).To(gomega.BeNil())
+	expect(*m, "c", 0)
+	// Update.
+	m.Namespace = "ns-updated"
+	m.Owner.Age = 99
+	err = DB.Update(m)
+	g.Expect(err).To(gomega.BeNil())
+	m = &TestNested{Name: "c"}
+	err = DB.Get(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.Namespace).To(gomega.Equal("ns-updated"))
+	g.Expect(m.Owner.Age).To(gomega.Equal(99))
+	g.Expect(m.Spec.Images).To(gomega.Equal([]string{"img-c"}))
+}
+
+func TestPolymorphic(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	RegisterType("square", TestSquare{})
+	RegisterType("circle", &TestCircle{})
+	DB := NewInMemory(&Label{}, &TestDrawing{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	err = DB.Insert(
+		&TestDrawing{
+			ID:    1,
+			Shape: TestSquare{Side: 2},
+			Other: &TestCircle{Radius: 1},
+		})
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&TestDrawing{ID: 2, Shape: &TestCircle{Radius: 2}})
+	g.Expect(err).To(gomega.BeNil())
+	// Get.
+	m := &TestDrawing{ID: 1}
+	err = DB.Get(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.Shape).To(gomega.Equal(TestSquare{Side: 2}))
+	g.Expect(m.Other).To(gomega.Equal(&TestCircle{Radius: 1}))
+	g.Expect(m.Shape.Area()).To(gomega.Equal(4))
+	// List.
+	list := []TestDrawing{}
+	err = DB.List(&list, ListOptions{Detail: DetailAll, Sort: []int{1}})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(2))
+	g.Expect(list[1].Shape).To(gomega.Equal(&TestCircle{Radius: 2}))
+	g.Expect(list[1].Other).To(gomega.BeNil())
+	// Stored with the discriminator.
+	table := Table{}
+	fields, err := table.Fields(&TestDrawing{Shape: TestSquare{Side: 3}})
+	g.Expect(err).To(gomega.BeNil())
+	v, err := fields[1].Pull()
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(v).To(gomega.Equal(`{"type":"square","value":{"Side":3}}`))
+	// Not registered.
+	err = DB.Insert(&TestDrawing{ID: 3, Shape: TestTriangle{Base: 1}})
+	codecErr := &CodecError{}
+	g.Expect(errors.As(err, &codecErr)).To(gomega.BeTrue())
+	g.Expect(codecErr.Err).To(gomega.Equal(TypeErr))
+	g.Expect(codecErr.Field).To(gomega.Equal("Shape"))
+	// Not registered (decoded).
+	fields[1].string = `{"type":"triangle","value":{"Base":1}}`
+	err = fields[1].Push()
+	g.Expect(errors.As(err, &codecErr)).To(gomega.BeTrue())
+	g.Expect(codecErr.Err).To(gomega.Equal(TypeErr))
+}
+
+func TestLen(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := NewInMemory(&Label{}, &TestObject{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	n, err := DB.Len(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(0)))
+	empty, err := DB.IsEmpty(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(empty).To(gomega.BeTrue())
+	for i := 0; i < 3; i++ {
+		err = DB.Insert(&TestObject{ID: i})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	n, err = DB.Len(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(3)))
+	empty, err = DB.IsEmpty(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(empty).To(gomega.BeFalse())
+	// Transaction.
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	defer tx.End()
+	for i := 0; i < 3; i++ {
+		err = tx.Delete(&TestObject{ID: i})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	n, err = tx.Len(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(0)))
+	empty, err = tx.IsEmpty(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(empty).To(gomega.BeTrue())
+	err = tx.Rollback()
+	g.Expect(err).To(gomega.BeNil())
+	n, err = DB.Len(&TestObject{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(3)))
+}
+
+func TestMaxListRows(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := NewInMemory(&Label{}, &TestObject{})
+	DB.(*Client).MaxListRows = 5
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	for i := 0; i < 5; i++ {
+		err = DB.Insert(&TestObject{ID: i})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	// At the limit.
+	list := []TestObject{}
+	err = DB.List(&list, ListOptions{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(5))
+	maps, err := DB.ListMaps(&TestObject{}, ListOptions{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(maps)).To(gomega.Equal(5))
+	// Exceeded.
+	err = DB.Insert(&TestObject{ID: 5})
+	g.Expect(err).To(gomega.BeNil())
+	list = []TestObject{}
+	err = DB.List(&list, ListOptions{})
+	g.Expect(errors.Is(err, ResultTooLargeErr)).To(gomega.BeTrue())
+	g.Expect(len(list)).To(gomega.Equal(0))
+	_, err = DB.ListMaps(&TestObject{}, ListOptions{})
+	g.Expect(errors.Is(err, ResultTooLargeErr)).To(gomega.BeTrue())
+	// Exports are streamed (not limited).
+	bfr := &bytes.Buffer{}
+	err = DB.ExportJSON(&TestObject{}, bfr, ListOptions{})
+	g.Expect(err).To(gomega.BeNil())
+	exported := []map[string]interface{}{}
+	err = json.Unmarshal(bfr.Bytes(), &exported)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(exported)).To(gomega.Equal(6))
+	bfr = &bytes.Buffer{}
+	err = DB.ExportCSV(&TestObject{}, bfr, ListOptions{})
+	g.Expect(err).To(gomega.BeNil())
+	// Qualified by predicate.
+	list = []TestObject{}
+	err = DB.List(&list, ListOptions{Predicate: Gt("ID", 2)})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(3))
+	// Paged.
+	list = []TestObject{}
+	err = DB.List(&list, ListOptions{Page: &Page{Limit: 10}})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(6))
+	// Transaction.
+	tx, err := DB.Begin()
+	g.Expect(err).To(gomega.BeNil())
+	defer tx.End()
+	list = []TestObject{}
+	err = tx.List(&list, ListOptions{})
+	g.Expect(errors.Is(err, ResultTooLargeErr)).To(gomega.BeTrue())
+	err = tx.Commit()
 	g.Expect(err).To(gomega.BeNil())
-	// Get (not found)
-	objB = &TestObject{ID: objA.ID}
-	err = DB.Get(objB)
-	g.Expect(errors.Is(err, NotFound)).To(gomega.BeTrue())
+	// Unlimited.
+	DB.(*Client).MaxListRows = 0
+	list = []TestObject{}
+	err = DB.List(&list, ListOptions{})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(6))
 }
 
-func TestTransactions(t *testing.T) {
+func TestOrdinalEnum(t *testing.T) {
+	var err error
 	g := gomega.NewGomegaWithT(t)
-	DB := New(
-		"/tmp/test.db",
-		&Label{},
-		&TestObject{})
-	err := DB.Open(true)
+	// DDL.
+	ddl, err := Table{}.DDL(&TestOrdinal{})
 	g.Expect(err).To(gomega.BeNil())
-	// Begin
-	tx, err := DB.Begin()
-	defer tx.End()
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("Phase INTEGER NOT NULL"))
+	g.Expect(ddl[0]).To(gomega.ContainSubstring("CHECK (Phase IN (0,1,2))"))
+	// Validation.
+	fields, err := Table{}.Fields(&TestOrdinal{Phase: 2})
 	g.Expect(err).To(gomega.BeNil())
-	g.Expect(tx.dbMutex).To(gomega.Equal(&DB.(*Client).dbMutex))
-	g.Expect(tx.journal).To(gomega.Equal(&DB.(*Client).journal))
-	object := &TestObject{
-		ID:   0,
-		Name: "Elmer",
-	}
-	err = tx.Insert(object)
+	g.Expect(fields[1].ValidateValue()).To(gomega.BeNil())
+	name, found := fields[1].EnumName()
+	g.Expect(found).To(gomega.BeTrue())
+	g.Expect(name).To(gomega.Equal("Done"))
+	fields[1].Value.SetInt(3)
+	err = fields[1].ValidateValue()
+	enumErr := &EnumError{}
+	g.Expect(errors.As(err, &enumErr)).To(gomega.BeTrue())
+	g.Expect(enumErr.Value).To(gomega.Equal("3"))
+	_, found = fields[1].EnumName()
+	g.Expect(found).To(gomega.BeFalse())
+	// Insert/Update.
+	DB := NewInMemory(&TestOrdinal{})
+	err = DB.Open(true)
 	g.Expect(err).To(gomega.BeNil())
-	// Get (not found)
-	object = &TestObject{ID: object.ID}
-	err = DB.Get(object)
-	g.Expect(errors.Is(err, NotFound)).To(gomega.BeTrue())
-	tx.Commit()
-	// Get (found)
-	object = &TestObject{ID: object.ID}
-	err = DB.Get(object)
+	defer DB.Close(true)
+	err = DB.Insert(&TestOrdinal{ID: 1, Phase: 1})
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.Insert(&TestOrdinal{ID: 2, Phase: -1})
+	g.Expect(errors.As(err, &enumErr)).To(gomega.BeTrue())
+	err = DB.Update(&TestOrdinal{ID: 1, Phase: 5})
+	g.Expect(errors.As(err, &enumErr)).To(gomega.BeTrue())
+	err = DB.Insert(&TestOrdinal{ID: 2, Phase: 2})
+	g.Expect(err).To(gomega.BeNil())
+	// Enforced by the DB.
+	_, err = DB.(*Client).db.Exec("UPDATE TestOrdinal SET Phase = 7;")
+	g.Expect(err).ToNot(gomega.BeNil())
+	// Export (by name) and import.
+	bfr := &bytes.Buffer{}
+	probe := &TestOrdinal{ID: 99, Phase: 1}
+	err = DB.ExportJSON(probe, bfr, ListOptions{Detail: DetailAll, Sort: []int{1}})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(*probe).To(gomega.Equal(TestOrdinal{ID: 99, Phase: 1}))
+	g.Expect(bfr.String()).To(gomega.ContainSubstring(`"Phase":"Running"`))
+	g.Expect(bfr.String()).To(gomega.ContainSubstring(`"Phase":"Done"`))
+	exported := bfr.String()
+	csv := &bytes.Buffer{}
+	err = DB.ExportCSV(&TestOrdinal{}, csv, ListOptions{Detail: DetailAll, Sort: []int{1}})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(csv.String()).To(gomega.Equal("ID,Phase\n1,Running\n2,Done\n"))
+	_, err = DB.(*Client).db.Exec("DELETE FROM TestOrdinal;")
+	g.Expect(err).To(gomega.BeNil())
+	err = DB.ImportJSON(&TestOrdinal{}, strings.NewReader(exported))
 	g.Expect(err).To(gomega.BeNil())
+	m := &TestOrdinal{ID: 2}
+	err = DB.Get(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.Phase).To(gomega.Equal(2))
+	err = DB.ImportCSV(&TestOrdinal{}, strings.NewReader("ID,Phase\n1,Pending\n3,1\n"))
+	g.Expect(err).To(gomega.BeNil())
+	m = &TestOrdinal{ID: 1}
+	err = DB.Get(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.Phase).To(gomega.Equal(0))
+	m = &TestOrdinal{ID: 3}
+	err = DB.Get(m)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(m.Phase).To(gomega.Equal(1))
 }
 
-func TestList(t *testing.T) {
+func TestListCursor(t *testing.T) {
 	var err error
 	g := gomega.NewGomegaWithT(t)
-	DB := New(
-		"/tmp/test.db",
-		&Label{},
-		&TestObject{})
+	DB := NewInMemory(&Label{}, &TestObject{})
 	err = DB.Open(true)
 	g.Expect(err).To(gomega.BeNil())
-	N := 10
-	for i := 0; i < N; i++ {
-		object := &TestObject{
-			ID:     i,
-			Name:   "Elmer",
-			Age:    18,
-			Int8:   8,
-			Int16:  16,
-			Int32:  32,
-			Bool:   true,
-			Object: TestEncoded{Name: "json"},
-			Slice:  []string{"hello", "world"},
-			Map:    map[string]int{"A": 1, "B": 2},
-			D4:     "d-4",
-			labels: Labels{
-				"id": fmt.Sprintf("v%d", i),
-			},
-		}
-		err = DB.Insert(object)
+	defer DB.Close(true)
+	for i := 0; i < 10; i++ {
+		err = DB.Insert(
+			&TestObject{
+				ID:   i,
+				Name: "Elmer",
+				Age:  i % 3,
+			})
 		g.Expect(err).To(gomega.BeNil())
 	}
-	// List all; detail level=0
+	// By PK (default).
+	options := ListOptions{Page: &Page{Limit: 4}}
+	found := map[int]bool{}
+	pages := 0
+	token := ""
+	for {
+		list := []TestObject{}
+		token, err = DB.ListCursor(&list, options, token)
+		g.Expect(err).To(gomega.BeNil())
+		pages++
+		for _, m := range list {
+			found[m.ID] = true
+		}
+		if token == "" {
+			break
+		}
+	}
+	g.Expect(pages).To(gomega.Equal(3))
+	g.Expect(len(found)).To(gomega.Equal(10))
+	// Unlimited (max int).
 	list := []TestObject{}
-	err = DB.List(&list, ListOptions{})
+	token, err = DB.ListCursor(&list, ListOptions{Page: &Page{Limit: int(^uint(0) >> 1)}}, "")
 	g.Expect(err).To(gomega.BeNil())
 	g.Expect(len(list)).To(gomega.Equal(10))
-	g.Expect(list[0].Name).To(gomega.Equal(""))
-	g.Expect(list[0].D4).To(gomega.Equal(""))
-	// List detail level=1 (all)
+	g.Expect(token).To(gomega.Equal(""))
+	// By Age (with ties) and qualified.
+	options = ListOptions{
+		Page:      &Page{Limit: 3},
+		Detail:    DetailAll,
+		Sort:      []int{7},
+		Predicate: Eq("Name", "Elmer"),
+	}
+	all := []TestObject{}
+	token = ""
+	for {
+		list := []TestObject{}
+		token, err = DB.ListCursor(&list, options, token)
+		g.Expect(err).To(gomega.BeNil())
+		all = append(all, list...)
+		if token == "" {
+			break
+		}
+	}
+	g.Expect(len(all)).To(gomega.Equal(10))
+	for i := 1; i < len(all); i++ {
+		g.Expect(all[i].Age >= all[i-1].Age).To(gomega.BeTrue())
+		if all[i].Age == all[i-1].Age {
+			g.Expect(all[i].PK > all[i-1].PK).To(gomega.BeTrue())
+		}
+	}
+	// Token (persisted) round trip.
 	list = []TestObject{}
-	err = DB.List(&list, ListOptions{Detail: 1})
+	token, err = DB.ListCursor(&list, options, "")
 	g.Expect(err).To(gomega.BeNil())
-	g.Expect(len(list)).To(gomega.Equal(10))
-	g.Expect(list[0].Name).To(gomega.Equal("Elmer"))
-	g.Expect(list[0].D4).To(gomega.Equal("d-4"))
-	// List detail level=2
-	list = []TestObject{}
-	err = DB.List(&list, ListOptions{Detail: 2})
+	cursor, err := DecodeCursor(token)
 	g.Expect(err).To(gomega.BeNil())
-	g.Expect(len(list)).To(gomega.Equal(10))
-	g.Expect(list[0].Name).To(gomega.Equal("Elmer"))
-	g.Expect(list[0].Slice).To(gomega.BeNil())
-	// List detail level=3
-	list = []TestObject{}
-	err = DB.List(&list, ListOptions{Detail: 3})
+	g.Expect(cursor.Sort).To(gomega.Equal([]string{"Age", "PK"}))
+	token, err = EncodeCursor(cursor)
 	g.Expect(err).To(gomega.BeNil())
-	g.Expect(len(list)).To(gomega.Equal(10))
-	g.Expect(list[0].Name).To(gomega.Equal("Elmer"))
-	g.Expect(len(list[0].Slice)).To(gomega.Equal(2))
-	g.Expect(list[0].D4).To(gomega.Equal(""))
-	// List detail level=4
 	list = []TestObject{}
-	err = DB.List(&list, ListOptions{Detail: 4})
+	_, err = DB.ListCursor(&list, options, token)
 	g.Expect(err).To(gomega.BeNil())
-	g.Expect(len(list)).To(gomega.Equal(10))
-	g.Expect(list[0].Name).To(gomega.Equal("Elmer"))
-	g.Expect(len(list[0].Slice)).To(gomega.Equal(2))
-	g.Expect(list[0].D4).To(gomega.Equal("d-4"))
-	// List = (single).
+	g.Expect(list[0].PK).To(gomega.Equal(all[3].PK))
+	// Query shape changed.
+	changed := options
+	changed.Predicate = Eq("Name", "Daffy")
+	_, err = DB.ListCursor(&list, changed, token)
+	g.Expect(errors.Is(err, CursorShapeErr)).To(gomega.BeTrue())
+	changed = options
+	changed.Sort = []int{6}
+	_, err = DB.ListCursor(&list, changed, token)
+	g.Expect(errors.Is(err, CursorShapeErr)).To(gomega.BeTrue())
+	// Page limit may change.
+	changed = options
+	changed.Page = &Page{Limit: 100}
 	list = []TestObject{}
+	token, err = DB.ListCursor(&list, changed, token)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(7))
+	g.Expect(token).To(gomega.Equal(""))
+	// Not valid.
+	_, err = DB.ListCursor(&list, options, "not-valid")
+	g.Expect(errors.Is(err, CursorErr)).To(gomega.BeTrue())
+	_, err = DB.ListCursor(&list, ListOptions{}, "")
+	g.Expect(errors.Is(err, CursorErr)).To(gomega.BeTrue())
+	_, err = DB.ListCursor(&list, ListOptions{Page: &Page{Offset: 1, Limit: 1}}, "")
+	g.Expect(errors.Is(err, CursorErr)).To(gomega.BeTrue())
+	_, err = DB.ListCursor(&list, ListOptions{Page: &Page{Limit: 1}, Detail: DetailAll, Sort: []int{12}}, "")
+	g.Expect(errors.Is(err, CursorErr)).To(gomega.BeTrue())
+}
+
+func TestPredicateInterceptor(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	type key struct{}
+	DB := NewInMemory(&Label{}, &TestTenant{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	for i := 0; i < 6; i++ {
+		tenant := "A"
+		if i%2 == 1 {
+			tenant = "B"
+		}
+		err = DB.Insert(
+			&TestTenant{
+				ID:     i,
+				Tenant: tenant,
+				Name:   fmt.Sprintf("n%d", i),
+				Tags:   map[string]string{"k": tenant},
+			})
+		g.Expect(err).To(gomega.BeNil())
+	}
+	noTenant := errors.New("no tenant")
+	models := []interface{}{}
+	DB.(*Client).Interceptor = func(ctx context.Context, m interface{}, p Predicate) (Predicate, error) {
+		models = append(models, m)
+		tenant, found := ctx.Value(key{}).(string)
+		if !found {
+			tenant = "A"
+		}
+		if tenant == "" {
+			return nil, noTenant
+		}
+		if p == nil {
+			return Eq("Tenant", tenant), nil
+		}
+		return And(p, Eq("Tenant", tenant)), nil
+	}
+	// List.
+	list := []TestTenant{}
+	err = DB.List(&list, ListOptions{Detail: DetailAll})
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(len(list)).To(gomega.Equal(3))
+	for _, m := range list {
+		g.Expect(m.Tenant).To(gomega.Equal("A"))
+		g.Expect(m.Tags).To(gomega.Equal(map[string]string{"k": "A"}))
+	}
+	_, isModel := models[0].(*TestTenant)
+	g.Expect(isModel).To(gomega.BeTrue())
+	// Qualified by predicate and filter (OR).
+	list = []TestTenant{}
 	err = DB.List(
 		&list,
 		ListOptions{
-			Predicate: Eq("ID", 0),
+			Predicate: Eq("Name", "n1"),
+			Filter:    &TestTenant{Name: "n2"},
+			Combine:   CombineOr,
 		})
 	g.Expect(err).To(gomega.BeNil())
 	g.Expect(len(list)).To(gomega.Equal(1))
-	g.Expect(list[0].ID).To(gomega.Equal(0))
-	// List != AND
-	list = []TestObject{}
-	err = DB.List(
-		&list,
-		ListOptions{
-			Detail: 2,
-			Predicate: And( // Even only.
-				Neq("ID", 1),
-				Neq("ID", 3),
-				Neq("ID", 5),
-				Neq("ID", 7),
-				Neq("ID", 9)),
-		})
+	g.Expect(list[0].ID).To(gomega.Equal(2))
+	// Count.
+	n, err := DB.Count(&TestTenant{}, nil)
 	g.Expect(err).To(gomega.BeNil())
-	g.Expect(len(list)).To(gomega.Equal(5))
-	g.Expect(list[0].ID).To(gomega.Equal(0))
-	g.Expect(list[1].ID).To(gomega.Equal(2))
-	g.Expect(list[2].ID).To(gomega.Equal(4))
-	g.Expect(list[3].ID).To(gomega.Equal(6))
-	g.Expect(list[4].ID).To(gomega.Equal(8))
-	// List OR =.
-	list = []TestObject{}
-	err = DB.List(
-		&list,
-		ListOptions{
-			Predicate: Or(
-				Eq("ID", 0),
-				Eq("ID", 6)),
-		})
+	g.Expect(n).To(gomega.Equal(int64(3)))
+	n, err = DB.Count(&TestTenant{}, Eq("Name", "n1"))
 	g.Expect(err).To(gomega.BeNil())
-	g.Expect(len(list)).To(gomega.Equal(2))
-	g.Expect(list[0].ID).To(gomega.Equal(0))
-	g.Expect(list[1].ID).To(gomega.Equal(6))
-	// List < (lt).
-	list = []TestObject{}
-	err = DB.List(
-		&list,
-		ListOptions{
-			Predicate: Lt("ID", 2),
-		})
+	g.Expect(n).To(gomega.Equal(int64(0)))
+	counts, err := DB.CountBy(&TestTenant{}, "Tenant", nil)
 	g.Expect(err).To(gomega.BeNil())
-	g.Expect(len(list)).To(gomega.Equal(2))
-	g.Expect(list[0].ID).To(gomega.Equal(0))
-	g.Expect(list[1].ID).To(gomega.Equal(1))
-	// List > (gt).
-	list = []TestObject{}
-	err = DB.List(
-		&list,
-		ListOptions{
-			Predicate: Gt("ID", 7),
-		})
+	g.Expect(counts).To(gomega.Equal(map[string]int64{"A": 3}))
+	// Get.
+	m := &TestTenant{ID: 2}
+	err = DB.Get(m)
 	g.Expect(err).To(gomega.BeNil())
-	g.Expect(len(list)).To(gomega.Equal(2))
-	g.Expect(list[0].ID).To(gomega.Equal(8))
-	g.Expect(list[1].ID).To(gomega.Equal(9))
-	// List > (gt) virtual.
-	list = []TestObject{}
-	err = DB.List(
-		&list,
-		ListOptions{
-			Predicate: Gt("RowID", N/2),
-		})
+	g.Expect(m.Name).To(gomega.Equal("n2"))
+	err = DB.Get(&TestTenant{ID: 1})
+	g.Expect(errors.Is(err, NotFound)).To(gomega.BeTrue())
+	// Map.
+	v, found, err := DB.MapGet(&TestTenant{ID: 2}, "Tags", "k")
 	g.Expect(err).To(gomega.BeNil())
-	g.Expect(len(list)).To(gomega.Equal(N / 2))
-	g.Expect(list[0].RowID).To(gomega.Equal(int64(N/2) + 1))
-	// List (Eq) Field values.
-	list = []TestObject{}
-	err = DB.List(
-		&list,
-		ListOptions{
-			Predicate: Eq("RowID", Field{Name: "int8"}),
-		})
+	g.Expect(found).To(gomega.BeTrue())
+	g.Expect(v).To(gomega.Equal("A"))
+	_, found, err = DB.MapGet(&TestTenant{ID: 1}, "Tags", "k")
+	g.Expect(errors.Is(err, NotFound)).To(gomega.BeTrue())
+	g.Expect(found).To(gomega.BeFalse())
+	// Transaction (context).
+	ctx := context.WithValue(context.TODO(), key{}, "B")
+	tx, err := DB.BeginTx(ctx)
 	g.Expect(err).To(gomega.BeNil())
-	g.Expect(len(list)).To(gomega.Equal(1))
-	g.Expect(list[0].RowID).To(gomega.Equal(int64(8)))
-	// List (nEq) Field values.
-	list = []TestObject{}
-	err = DB.List(
-		&list,
-		ListOptions{
-			Predicate: Neq("RowID", Field{Name: "int8"}),
-		})
+	list = []TestTenant{}
+	err = tx.List(&list, ListOptions{Detail: DetailAll})
 	g.Expect(err).To(gomega.BeNil())
-	g.Expect(len(list)).To(gomega.Equal(N - 1))
-	// List (Lt) Field values.
-	list = []TestObject{}
-	err = DB.List(
-		&list,
-		ListOptions{
-			Predicate: Lt("int8", Field{Name: "int16"}),
-		})
+	g.Expect(len(list)).To(gomega.Equal(3))
+	for _, m := range list {
+		g.Expect(m.Tenant).To(gomega.Equal("B"))
+	}
+	err = tx.Get(&TestTenant{ID: 1})
 	g.Expect(err).To(gomega.BeNil())
-	g.Expect(len(list)).To(gomega.Equal(N))
-	// List (Gt) Field values.
-	list = []TestObject{}
-	err = DB.List(
-		&list,
-		ListOptions{
-			Predicate: Gt("RowID", Field{Name: "int8"}),
-		})
+	err = tx.Get(&TestTenant{ID: 2})
+	g.Expect(errors.Is(err, NotFound)).To(gomega.BeTrue())
+	err = tx.Commit()
 	g.Expect(err).To(gomega.BeNil())
-	g.Expect(len(list)).To(gomega.Equal(2))
-	// By label.
-	list = []TestObject{}
-	err = DB.List(
-		&list,
-		ListOptions{
-			Sort: []int{2},
-			Predicate: Or(
-				Match(Labels{"id": "v4"}),
-				Eq("ID", 8)),
-		})
+	// Refused.
+	ctx = context.WithValue(context.TODO(), key{}, "")
+	tx, err = DB.BeginTx(ctx)
 	g.Expect(err).To(gomega.BeNil())
-	g.Expect(len(list)).To(gomega.Equal(2))
-	g.Expect(list[0].ID).To(gomega.Equal(4))
-	g.Expect(list[1].ID).To(gomega.Equal(8))
-	// Test count all.
-	count, err := DB.Count(&TestObject{}, nil)
+	_, err = tx.Count(&TestTenant{}, nil)
+	g.Expect(errors.Is(err, noTenant)).To(gomega.BeTrue())
+	err = tx.Get(&TestTenant{ID: 0})
+	g.Expect(errors.Is(err, noTenant)).To(gomega.BeTrue())
+	err = tx.Commit()
 	g.Expect(err).To(gomega.BeNil())
-	g.Expect(count).To(gomega.Equal(int64(10)))
-	// Test count with predicate.
-	count, err = DB.Count(&TestObject{}, Gt("ID", 0))
+	// Not intercepted.
+	DB.(*Client).Interceptor = nil
+	n, err = DB.Count(&TestTenant{}, nil)
+	g.Expect(err).To(gomega.BeNil())
+	g.Expect(n).To(gomega.Equal(int64(6)))
+}
+
+func TestSetPkError(t *testing.T) {
+	var err error
+	g := gomega.NewGomegaWithT(t)
+	DB := NewInMemory(&Label{}, &TestBadKey{})
+	err = DB.Open(true)
+	g.Expect(err).To(gomega.BeNil())
+	defer DB.Close(true)
+	err = DB.Get(&TestBadKey{})
+	g.Expect(err).ToNot(gomega.BeNil())
+	g.Expect(errors.Is(err, NotFound)).To(gomega.BeFalse())
+	g.Expect(err.Error()).To(gomega.ContainSubstring("value failed"))
+	err = DB.Insert(&TestBadKey{})
+	g.Expect(err).ToNot(gomega.BeNil())
+	err = DB.Delete(&TestBadKey{})
+	g.Expect(err.Error()).To(gomega.ContainSubstring("value failed"))
+	// Not generated (int).
+	err = Table{}.SetPk([]*Field{})
+	g.Expect(err).To(gomega.BeNil())
+	fields, err := Table{}.Fields(&TestOrdinal{ID: 1})
+	g.Expect(err).To(gomega.BeNil())
+	err = Table{}.SetPk(fields)
 	g.Expect(err).To(gomega.BeNil())
-	g.Expect(count).To(gomega.Equal(int64(9)))
 }
 
 func TestWatch(t *testing.T) {
@@ -512,12 +6841,12 @@ func TestWatch(t *testing.T) {
 		time.Sleep(time.Millisecond * 10)
 		if len(handlerA.created) != N ||
 			len(handlerA.updated) != N ||
-			len(handlerA.created) != N ||
+			len(handlerA.deleted) != N ||
 			len(handlerB.created) != N ||
 			len(handlerB.updated) != N ||
-			len(handlerB.created) != N ||
+			len(handlerB.deleted) != N ||
 			len(handlerC.created) != N ||
-			len(handlerC.created) != N {
+			len(handlerC.deleted) != N {
 			continue
 		} else {
 			break
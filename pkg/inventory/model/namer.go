@@ -0,0 +1,103 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+//
+// Table and column naming strategy.
+// Used everywhere a table or column name is emitted
+// in generated DDL and SQL.
+type Namer interface {
+	// Get the table name for the model type.
+	TableName(mt reflect.Type) string
+	// Get the column name for the model field.
+	ColumnName(ft reflect.StructField) string
+}
+
+//
+// Identity namer.
+// The table name is the model type name and the column
+// name is the field name.
+type IdentityNamer struct{}
+
+//
+// Get the table name for the model type.
+func (n IdentityNamer) TableName(mt reflect.Type) string {
+	return mt.Name()
+}
+
+//
+// Get the column name for the model field.
+func (n IdentityNamer) ColumnName(ft reflect.StructField) string {
+	return ft.Name
+}
+
+//
+// Snake case namer.
+// Names are converted to snake_case. Example:
+// `ResourceGroup` => `resource_group`.
+type SnakeNamer struct {
+	// Pluralize table names. Example:
+	// `ResourceGroup` => `resource_groups`.
+	Plural bool
+}
+
+//
+// Get the table name for the model type.
+func (n SnakeNamer) TableName(mt reflect.Type) string {
+	name := n.snake(mt.Name())
+	if n.Plural {
+		name = n.plural(name)
+	}
+
+	return name
+}
+
+//
+// Get the column name for the model field.
+func (n SnakeNamer) ColumnName(ft reflect.StructField) string {
+	return n.snake(ft.Name)
+}
+
+//
+// Convert the name to snake_case.
+// A run of upper case letters is treated as a
+// single word. Example: `VMHost` => `vm_host`.
+func (n SnakeNamer) snake(name string) string {
+	runes := []rune(name)
+	bfr := strings.Builder{}
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) ||
+				unicode.IsDigit(prev) ||
+				(unicode.IsUpper(prev) && nextLower) {
+				bfr.WriteRune('_')
+			}
+		}
+		bfr.WriteRune(unicode.ToLower(r))
+	}
+
+	return bfr.String()
+}
+
+//
+// Pluralize the (english) name.
+func (n SnakeNamer) plural(name string) string {
+	for _, suffix := range []string{"s", "x", "z", "ch", "sh"} {
+		if strings.HasSuffix(name, suffix) {
+			return name + "es"
+		}
+	}
+	if len(name) > 1 && strings.HasSuffix(name, "y") {
+		if !strings.ContainsRune("aeiou", rune(name[len(name)-2])) {
+			return name[:len(name)-1] + "ies"
+		}
+	}
+
+	return name + "s"
+}
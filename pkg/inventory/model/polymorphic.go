@@ -0,0 +1,137 @@
+package model
+
+import (
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"reflect"
+	"sync"
+)
+
+//
+// Errors
+var (
+	// Type not registered.
+	TypeErr = errors.New("concrete type must be registered")
+)
+
+//
+// Registered (concrete) types used to encode and
+// decode interface (polymorphic) fields.
+var registry = struct {
+	mutex sync.RWMutex
+	// Types by name.
+	types map[string]reflect.Type
+	// Names by type.
+	names map[reflect.Type]string
+}{
+	types: map[string]reflect.Type{},
+	names: map[reflect.Type]string{},
+}
+
+//
+// Register a concrete type by (discriminator) name.
+// Interface fields are encoded with the name of the
+// concrete type of the value and decoded using the type
+// registered by that name. The `object` may be a struct
+// (value) or a pointer. The name is persisted and so must
+// not change once used. Registering the name again
+// replaces the type.
+// Example:
+//   RegisterType("vmware", &VMwareProvider{})
+//   RegisterType("ovirt", &OvirtProvider{})
+func RegisterType(name string, object interface{}) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+	t := reflect.TypeOf(object)
+	if previous, found := registry.types[name]; found {
+		delete(registry.names, previous)
+	}
+	registry.types[name] = t
+	registry.names[t] = name
+}
+
+//
+// Encoded interface field value.
+type typedValue struct {
+	// Registered type name.
+	Type string `json:"type"`
+	// Encoded value.
+	Value interface{} `json:"value"`
+}
+
+//
+// Encode the interface field value into the `staging`
+// field with the registered name of the concrete type.
+// A nil value is encoded as "".
+// Returns *CodecError when the type is not registered
+// or the value cannot be encoded.
+func (f *Field) encodeTyped() error {
+	if f.Value.IsNil() {
+		f.string = ""
+		return nil
+	}
+	object := f.Value.Elem().Interface()
+	registry.mutex.RLock()
+	name, found := registry.names[reflect.TypeOf(object)]
+	registry.mutex.RUnlock()
+	if !found {
+		return liberr.Wrap(
+			&CodecError{
+				Field: f.Name,
+				Err:   TypeErr,
+			})
+	}
+
+	return f.encodeTo(
+		&typedValue{
+			Type:  name,
+			Value: object,
+		})
+}
+
+//
+// Decode the `staging` field into the interface field
+// using the registered (concrete) type.
+// Returns *CodecError when the type is not registered,
+// does not implement the interface or the value cannot
+// be decoded.
+func (f *Field) decodeTyped() error {
+	if len(f.string) == 0 {
+		f.Value.Set(reflect.Zero(f.Value.Type()))
+		return nil
+	}
+	fail := func(err error) error {
+		return liberr.Wrap(
+			&CodecError{
+				Field: f.Name,
+				Err:   err,
+			})
+	}
+	typed := &typedValue{}
+	err := f.decode([]byte(f.string), typed)
+	if err != nil {
+		return fail(err)
+	}
+	registry.mutex.RLock()
+	t, found := registry.types[typed.Type]
+	registry.mutex.RUnlock()
+	if !found || !t.Implements(f.Value.Type()) {
+		return fail(TypeErr)
+	}
+	var object reflect.Value
+	if t.Kind() == reflect.Ptr {
+		object = reflect.New(t.Elem())
+		typed.Value = object.Interface()
+	} else {
+		object = reflect.New(t)
+		typed.Value = object.Interface()
+		object = object.Elem()
+	}
+	err = f.decode([]byte(f.string), typed)
+	if err != nil {
+		return fail(err)
+	}
+	f.Value.Set(object)
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"strings"
+)
+
+//
+// Errors
+var (
+	// Named parameter not bound.
+	ParamErr = errors.New("named parameter referenced but not bound")
+)
+
+//
+// Positional (?) parameter DB.
+// Statements using named (:name) parameters are rewritten
+// to use positional (?) parameters and the named arguments
+// are bound by position. Intended for drivers (and tooling)
+// not supporting named parameters. Statements with
+// (any) positional arguments are not rewritten.
+type PositionalDB struct {
+	// Wrapped DB.
+	DB DBTX
+}
+
+//
+// Execute a statement.
+func (d *PositionalDB) Exec(stmt string, args ...interface{}) (sql.Result, error) {
+	stmt, args, err := Positional(stmt, args)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+
+	return d.DB.Exec(stmt, args...)
+}
+
+//
+// Execute a query.
+func (d *PositionalDB) Query(stmt string, args ...interface{}) (*sql.Rows, error) {
+	stmt, args, err := Positional(stmt, args)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+
+	return d.DB.Query(stmt, args...)
+}
+
+//
+// Execute a query expected to return (at most) one row.
+// When the statement cannot be rewritten, it is passed
+// through (as-is) and the error reported by the driver.
+func (d *PositionalDB) QueryRow(stmt string, args ...interface{}) *sql.Row {
+	rewritten, bound, err := Positional(stmt, args)
+	if err == nil {
+		stmt = rewritten
+		args = bound
+	}
+
+	return d.DB.QueryRow(stmt, args...)
+}
+
+//
+// Rewrite the statement using positional (?) parameters
+// and bind the named arguments by position.
+// Returns ParamErr when a named parameter is not bound.
+func Positional(stmt string, args []interface{}) (string, []interface{}, error) {
+	named := map[string]interface{}{}
+	for _, arg := range args {
+		p, cast := arg.(sql.NamedArg)
+		if !cast {
+			return stmt, args, nil
+		}
+		named[p.Name] = p.Value
+	}
+	rewritten, names := positional(stmt)
+	bound := []interface{}{}
+	for _, name := range names {
+		v, found := named[name]
+		if !found {
+			return "", nil, liberr.Wrap(ParamErr)
+		}
+		bound = append(bound, v)
+	}
+
+	return rewritten, bound, nil
+}
+
+//
+// Rewrite named (:name) parameters as positional (?).
+// String literals, quoted identifiers and comments are
+// not rewritten. Returns the rewritten statement and the
+// (ordered) parameter names.
+func positional(stmt string) (string, []string) {
+	names := []string{}
+	bfr := strings.Builder{}
+	n := len(stmt)
+	for i := 0; i < n; i++ {
+		c := stmt[i]
+		switch {
+		case c == '\'' || c == '"':
+			j := i + 1
+			for j < n {
+				if stmt[j] == c {
+					if j+1 < n && stmt[j+1] == c {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			if j >= n {
+				j = n - 1
+			}
+			bfr.WriteString(stmt[i : j+1])
+			i = j
+		case c == '-' && i+1 < n && stmt[i+1] == '-':
+			j := strings.IndexByte(stmt[i:], '\n')
+			if j < 0 {
+				j = n - i - 1
+			}
+			bfr.WriteString(stmt[i : i+j+1])
+			i += j
+		case c == '/' && i+1 < n && stmt[i+1] == '*':
+			j := strings.Index(stmt[i+2:], "*/")
+			if j < 0 {
+				j = n - i - 2
+			} else {
+				j += 2
+			}
+			end := i + 2 + j
+			if end > n {
+				end = n
+			}
+			bfr.WriteString(stmt[i:end])
+			i = end - 1
+		case c == ':' && i+1 < n && identStart(stmt[i+1]):
+			j := i + 1
+			for j < n && identPart(stmt[j]) {
+				j++
+			}
+			names = append(names, stmt[i+1:j])
+			bfr.WriteByte('?')
+			i = j - 1
+		default:
+			bfr.WriteByte(c)
+		}
+	}
+
+	return bfr.String(), names
+}
+
+//
+// Get whether the character may start an identifier.
+func identStart(c byte) bool {
+	return c == '_' ||
+		(c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z')
+}
+
+//
+// Get whether the character may be part of an identifier.
+func identPart(c byte) bool {
+	return identStart(c) || (c >= '0' && c <= '9')
+}
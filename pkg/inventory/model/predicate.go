@@ -2,6 +2,7 @@ package model
 
 import (
 	"bytes"
+	"fmt"
 	liberr "github.com/konveyor/controller/pkg/error"
 	"reflect"
 	"strings"
@@ -13,17 +14,17 @@ import (
 var LabelSQL = `
 {{ $kind := .Kind -}}
 {{ if .Len }}
-{{ .Pk.Name }} IN
+{{ .Pk.Column }} IN
 (
 {{ range $i,$l := .List -}}
 {{ if $i }}
 INTERSECT
 {{ end -}}
-SELECT parent
-FROM Label
-WHERE kind = '{{ $kind }}' AND
-name = {{ $l.Name }} AND
-value = {{ $l.Value }}
+SELECT {{ $.Column "Parent" }}
+FROM {{ $.Table }}
+WHERE {{ $.Column "Kind" }} = '{{ $kind }}' AND
+{{ $.Column "Name" }} = {{ $l.Name }} AND
+{{ $.Column "Value" }} = {{ $l.Value }}
 {{ end -}}
 )
 {{ end -}}
@@ -73,6 +74,65 @@ func Lt(field string, value interface{}) *LtPredicate {
 	}
 }
 
+//
+// New field reference.
+// Used as a predicate value to compare with another
+// field (column) of the same model.
+// Example:
+//   Neq("Desired", FieldRef("Ready"))
+func FieldRef(name string) Field {
+	return Field{Name: name}
+}
+
+//
+// New In (IN) predicate.
+func In(field string, values ...interface{}) *InPredicate {
+	return &InPredicate{
+		SimplePredicate: SimplePredicate{
+			Field: field,
+		},
+		Values: values,
+	}
+}
+
+//
+// New IsNull (IS NULL) predicate.
+func IsNull(field string) *IsNullPredicate {
+	return &IsNullPredicate{
+		SimplePredicate{
+			Field: field,
+		},
+	}
+}
+
+//
+// New OneOf predicate.
+// Match the field equal to any of the values. A `nil`
+// value matches NULL.
+// Example:
+//   OneOf("Namespace", "a", "b", nil)
+// is equivalent to:
+//   Or(In("Namespace", "a", "b"), IsNull("Namespace"))
+func OneOf(field string, values ...interface{}) Predicate {
+	null := false
+	list := []interface{}{}
+	for _, v := range values {
+		if v == nil {
+			null = true
+			continue
+		}
+		list = append(list, v)
+	}
+	if !null {
+		return In(field, list...)
+	}
+	if len(list) == 0 {
+		return IsNull(field)
+	}
+
+	return Or(In(field, list...), IsNull(field))
+}
+
 //
 // AND predicate.
 func And(predicates ...Predicate) *AndPredicate {
@@ -87,7 +147,7 @@ func And(predicates ...Predicate) *AndPredicate {
 // OR predicate.
 func Or(predicates ...Predicate) *OrPredicate {
 	return &OrPredicate{
-		CompoundPredicate{
+		CompoundPredicate: CompoundPredicate{
 			Predicates: predicates,
 		},
 	}
@@ -101,6 +161,41 @@ func Match(labels Labels) *LabelPredicate {
 	}
 }
 
+//
+// Exists predicate.
+// Match models referenced by at least one (child) `model`
+// matching the predicate. The `field` is the child field
+// referencing the model. The referenced field is determined
+// by the `fk` tag on the child field, else the PK.
+func Exists(model interface{}, field string, predicate Predicate) *ExistsPredicate {
+	return &ExistsPredicate{
+		Model:     model,
+		Field:     field,
+		Predicate: predicate,
+	}
+}
+
+//
+// Map (entry) predicate.
+// Match models with the `map` field entry.
+func MapEq(field, key, value string) *MapPredicate {
+	return &MapPredicate{
+		Field: field,
+		Key:   key,
+		Value: &value,
+	}
+}
+
+//
+// Map (key) predicate.
+// Match models with the key in the `map` field.
+func MapHas(field, key string) *MapPredicate {
+	return &MapPredicate{
+		Field: field,
+		Key:   key,
+	}
+}
+
 //
 // List predicate.
 type Predicate interface {
@@ -132,7 +227,8 @@ func (p *SimplePredicate) match(fields []*Field) (*Field, bool) {
 func (p *SimplePredicate) field(name string, fields []*Field) (*Field, bool) {
 	name = strings.ToLower(name)
 	for _, f := range fields {
-		if name == strings.ToLower(f.Name) {
+		if name == strings.ToLower(f.Name) ||
+			name == strings.ToLower(f.Column) {
 			return f, true
 		}
 	}
@@ -140,6 +236,16 @@ func (p *SimplePredicate) field(name string, fields []*Field) (*Field, bool) {
 	return nil, false
 }
 
+//
+// Identity key used to detect duplicates.
+func (p *SimplePredicate) key(predicate Predicate) string {
+	return fmt.Sprintf(
+		"%T|%s|%#v",
+		predicate,
+		strings.ToLower(p.Field),
+		p.Value)
+}
+
 //
 // Build.
 func (p *SimplePredicate) build(operator string, options *ListOptions) error {
@@ -147,6 +253,9 @@ func (p *SimplePredicate) build(operator string, options *ListOptions) error {
 	if !found {
 		return liberr.Wrap(PredicateRefErr)
 	}
+	if f.Encrypted() {
+		return liberr.Wrap(PredicateEncryptedErr)
+	}
 	switch p.Value.(type) {
 	case Field:
 		value := p.Value.(Field)
@@ -154,11 +263,17 @@ func (p *SimplePredicate) build(operator string, options *ListOptions) error {
 		if !found {
 			return liberr.Wrap(PredicateRefErr)
 		}
+		if fv.Encrypted() {
+			return liberr.Wrap(PredicateEncryptedErr)
+		}
+		if !f.Comparable(fv) {
+			return liberr.Wrap(PredicateTypeErr)
+		}
 		p.expr = strings.Join(
 			[]string{
-				f.Name,
+				f.Column,
 				operator,
-				fv.Name,
+				fv.Column,
 			}, " ")
 	default:
 		v, err := f.AsValue(p.Value)
@@ -167,7 +282,7 @@ func (p *SimplePredicate) build(operator string, options *ListOptions) error {
 		}
 		p.expr = strings.Join(
 			[]string{
-				f.Name,
+				f.Column,
 				operator,
 				options.Param(f.Name, v)},
 			" ")
@@ -280,6 +395,67 @@ func (p *LtPredicate) Expr() string {
 	return p.expr
 }
 
+//
+// In (IN) predicate.
+type InPredicate struct {
+	SimplePredicate
+	// Values.
+	Values []interface{}
+}
+
+//
+// Build.
+func (p *InPredicate) Build(options *ListOptions) error {
+	f, found := p.match(options.fields)
+	if !found {
+		return liberr.Wrap(PredicateRefErr)
+	}
+	if f.Encrypted() {
+		return liberr.Wrap(PredicateEncryptedErr)
+	}
+	params := []string{}
+	for _, value := range p.Values {
+		v, err := f.AsValue(value)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		params = append(params, options.Param(f.Name, v))
+	}
+	p.expr = f.Column + " IN (" + strings.Join(params, ",") + ")"
+
+	return nil
+}
+
+//
+// Render the expression.
+func (p *InPredicate) Expr() string {
+	return p.expr
+}
+
+//
+// Is NULL (IS NULL) predicate.
+type IsNullPredicate struct {
+	SimplePredicate
+}
+
+//
+// Build.
+func (p *IsNullPredicate) Build(options *ListOptions) error {
+	f, found := p.match(options.fields)
+	if !found {
+		return liberr.Wrap(PredicateRefErr)
+	}
+	p.expr = f.Column + " IS NULL"
+
+	return nil
+}
+
+//
+// Render the expression.
+func (p *IsNullPredicate) Expr() string {
+	return p.expr
+}
+
 //
 // Compound predicate.
 type CompoundPredicate struct {
@@ -314,21 +490,28 @@ func (p *AndPredicate) Expr() string {
 		predicates = append(predicates, p.Expr())
 	}
 
-	expr := strings.Join(predicates, " AND ")
+	expr := "(" + strings.Join(predicates, " AND ") + ")"
 
 	return expr
 }
 
 //
 // OR predicate.
+// When built, nested OR predicates are flattened, duplicate
+// simple predicates are removed and Eq predicates on the same
+// field are combined into a single IN. This keeps large
+// disjunctions within the SQLite expression depth limit.
 type OrPredicate struct {
 	CompoundPredicate
+	// Built (flattened) predicates.
+	built []Predicate
 }
 
 //
 // Build.
 func (p *OrPredicate) Build(options *ListOptions) error {
-	for _, p := range p.Predicates {
+	p.built = p.flatten()
+	for _, p := range p.built {
 		err := p.Build(options)
 		if err != nil {
 			return liberr.Wrap(err)
@@ -342,11 +525,11 @@ func (p *OrPredicate) Build(options *ListOptions) error {
 // Render the expression.
 func (p *OrPredicate) Expr() string {
 	predicates := []string{}
-	for _, p := range p.Predicates {
+	for _, p := range p.built {
 		predicates = append(predicates, p.Expr())
 	}
 
-	expr := strings.Join(predicates, " OR ")
+	expr := "(" + strings.Join(predicates, " OR ") + ")"
 
 	return expr
 }
@@ -360,6 +543,10 @@ type LabelPredicate struct {
 	options *ListOptions
 	// Parent PK field name.
 	pk *Field
+	// Label table name.
+	table string
+	// Label fields.
+	fields []*Field
 	// SQL expression.
 	expr string
 }
@@ -374,8 +561,16 @@ func (p *LabelPredicate) Build(options *ListOptions) error {
 			break
 		}
 	}
+	table := Table{Namer: options.namer}
+	label := &Label{}
+	fields, err := table.Fields(label)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	p.table = table.Name(label)
+	p.fields = fields
 	tpl := template.New("")
-	tpl, err := tpl.Parse(LabelSQL)
+	tpl, err = tpl.Parse(LabelSQL)
 	if err != nil {
 		return liberr.Wrap(err)
 	}
@@ -402,6 +597,24 @@ func (p *LabelPredicate) Pk() *Field {
 	return p.pk
 }
 
+//
+// Label table name.
+func (p *LabelPredicate) Table() string {
+	return p.table
+}
+
+//
+// Label column name for the named field.
+func (p *LabelPredicate) Column(name string) string {
+	for _, f := range p.fields {
+		if f.Name == name {
+			return f.Column
+		}
+	}
+
+	return name
+}
+
 //
 // List of labels.
 func (p *LabelPredicate) List() []Label {
@@ -431,3 +644,447 @@ func (p *LabelPredicate) Len() int {
 func (p *LabelPredicate) Expr() string {
 	return p.expr
 }
+
+//
+// Exists (subquery) predicate.
+type ExistsPredicate struct {
+	// Child model.
+	Model interface{}
+	// Child field referencing the parent.
+	Field string
+	// Child predicate (optional).
+	Predicate Predicate
+	// SQL expression.
+	expr string
+}
+
+//
+// Build.
+func (p *ExistsPredicate) Build(options *ListOptions) error {
+	table := Table{Namer: options.namer}
+	fields, err := table.Fields(p.Model)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	child := &ListOptions{
+		model:  p.Model,
+		table:  table.Name(p.Model),
+		namer:  options.namer,
+		fields: fields,
+		params: options.params,
+		prefix: options.prefix,
+	}
+	ref := &SimplePredicate{}
+	fk, found := ref.field(p.Field, child.fields)
+	if !found {
+		return liberr.Wrap(PredicateRefErr)
+	}
+	pk := table.PkField(options.fields)
+	if fkRef := fk.Fk(); fkRef != nil {
+		pk, found = ref.field(fkRef.Field, options.fields)
+		if !found {
+			return liberr.Wrap(PredicateRefErr)
+		}
+	}
+	if pk == nil {
+		return liberr.Wrap(PredicateRefErr)
+	}
+	column := pk.Column
+	if !strings.Contains(column, ".") {
+		column = options.table + "." + column
+	}
+	join := child.table + "." + fk.Column + " = " + column
+	if p.Predicate != nil {
+		err = p.Predicate.Build(child)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		options.params = child.params
+		join += " AND " + p.Predicate.Expr()
+	}
+	p.expr = "EXISTS (SELECT 1 FROM " + child.table + " WHERE " + join + ")"
+
+	return nil
+}
+
+//
+// Render the expression.
+func (p *ExistsPredicate) Expr() string {
+	return p.expr
+}
+
+//
+// SQL functions supported by function predicates.
+// Mapped to the kind of the function result.
+var Functions = map[string]reflect.Kind{
+	"ABS":    reflect.Int64,
+	"LENGTH": reflect.Int64,
+	"LOWER":  reflect.String,
+	"LTRIM":  reflect.String,
+	"RTRIM":  reflect.String,
+	"TRIM":   reflect.String,
+	"UPPER":  reflect.String,
+}
+
+//
+// New function (reference).
+// The (whitelisted) SQL `function` applied to the field.
+// Example: Func("LENGTH", "Name").Gt(10)
+func Func(function, field string) *Function {
+	return &Function{
+		Name:  strings.ToUpper(function),
+		Field: field,
+	}
+}
+
+//
+// New LOWER() function (reference).
+func Lower(field string) *Function {
+	return Func("LOWER", field)
+}
+
+//
+// New UPPER() function (reference).
+func Upper(field string) *Function {
+	return Func("UPPER", field)
+}
+
+//
+// New LENGTH() function (reference).
+func Length(field string) *Function {
+	return Func("LENGTH", field)
+}
+
+//
+// New COUNT(*) aggregate (reference).
+// Valid only in `Having` predicates.
+// Example: CountAll().Gt(10)
+func CountAll() *Function {
+	return Func("COUNT", "*")
+}
+
+//
+// New aggregate (reference).
+// The aggregate `function` (COUNT, SUM, AVG, MIN, MAX)
+// applied to the field. Valid only in `Having` predicates.
+// Example: Agg("SUM", "Age").Gt(100)
+func Agg(function, field string) *Function {
+	return Func(function, field)
+}
+
+//
+// SQL function applied to a field.
+type Function struct {
+	// Function name.
+	Name string
+	// Field name.
+	Field string
+}
+
+//
+// New Eq (=) predicate.
+func (f *Function) Eq(value interface{}) *FuncPredicate {
+	return f.predicate("=", value)
+}
+
+//
+// New Neq (!=) predicate.
+func (f *Function) Neq(value interface{}) *FuncPredicate {
+	return f.predicate("!=", value)
+}
+
+//
+// New Gt (>) predicate.
+func (f *Function) Gt(value interface{}) *FuncPredicate {
+	return f.predicate(">", value)
+}
+
+//
+// New Lt (<) predicate.
+func (f *Function) Lt(value interface{}) *FuncPredicate {
+	return f.predicate("<", value)
+}
+
+//
+// New predicate.
+func (f *Function) predicate(operator string, value interface{}) *FuncPredicate {
+	return &FuncPredicate{
+		Function: *f,
+		Operator: operator,
+		Value:    value,
+	}
+}
+
+//
+// Function predicate.
+// Compares the result of a function applied to
+// a field with the value.
+type FuncPredicate struct {
+	// Function.
+	Function Function
+	// Comparison operator.
+	Operator string
+	// Value.
+	Value interface{}
+	// SQL expression.
+	expr string
+}
+
+//
+// Build.
+func (p *FuncPredicate) Build(options *ListOptions) error {
+	if p.aggregate() {
+		return p.buildAggregate(options)
+	}
+	kind, found := Functions[p.Function.Name]
+	if !found {
+		return liberr.Wrap(FunctionErr)
+	}
+	ref := &SimplePredicate{}
+	f, found := ref.field(p.Function.Field, options.fields)
+	if !found {
+		return liberr.Wrap(PredicateRefErr)
+	}
+	if f.Encrypted() {
+		return liberr.Wrap(PredicateEncryptedErr)
+	}
+	switch p.Function.Name {
+	case "ABS":
+		switch f.Value.Kind() {
+		case reflect.Int,
+			reflect.Int8,
+			reflect.Int16,
+			reflect.Int32,
+			reflect.Int64:
+		default:
+			return liberr.Wrap(PredicateTypeErr)
+		}
+	case "LENGTH":
+	default:
+		if f.Value.Kind() != reflect.String {
+			return liberr.Wrap(PredicateTypeErr)
+		}
+	}
+	var value interface{}
+	v := reflect.ValueOf(p.Value)
+	switch kind {
+	case reflect.String:
+		if v.Kind() != reflect.String {
+			return liberr.Wrap(PredicateValueErr)
+		}
+		value = v.String()
+	default:
+		switch v.Kind() {
+		case reflect.Int,
+			reflect.Int8,
+			reflect.Int16,
+			reflect.Int32,
+			reflect.Int64:
+			value = v.Int()
+		default:
+			return liberr.Wrap(PredicateValueErr)
+		}
+	}
+	switch p.Operator {
+	case "=", "!=", ">", "<":
+	default:
+		return liberr.Wrap(PredicateTypeErr)
+	}
+	p.expr = strings.Join(
+		[]string{
+			p.Function.Name + "(" + f.Column + ")",
+			p.Operator,
+			options.Param(f.Name, value),
+		},
+		" ")
+
+	return nil
+}
+
+//
+// Render the expression.
+func (p *FuncPredicate) Expr() string {
+	return p.expr
+}
+
+//
+// Get whether the function is an aggregate.
+func (p *FuncPredicate) aggregate() bool {
+	if p.Function.Name == "COUNT" {
+		return true
+	}
+	for _, name := range Aggregates {
+		if p.Function.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+//
+// Build the aggregate predicate.
+// Valid only when building the `Having` predicate.
+func (p *FuncPredicate) buildAggregate(options *ListOptions) error {
+	if !options.having {
+		return liberr.Wrap(HavingErr)
+	}
+	column := "*"
+	name := "count"
+	if p.Function.Field != "*" {
+		ref := &SimplePredicate{}
+		f, found := ref.field(p.Function.Field, options.fields)
+		if !found {
+			return liberr.Wrap(PredicateRefErr)
+		}
+		if f.Encrypted() {
+			return liberr.Wrap(PredicateEncryptedErr)
+		}
+		if p.Function.Name != "COUNT" {
+			switch f.Value.Kind() {
+			case reflect.Int,
+				reflect.Int8,
+				reflect.Int16,
+				reflect.Int32,
+				reflect.Int64:
+			default:
+				return liberr.Wrap(PredicateTypeErr)
+			}
+		}
+		column = f.Column
+		name = f.Name
+	} else if p.Function.Name != "COUNT" {
+		return liberr.Wrap(PredicateRefErr)
+	}
+	var value interface{}
+	v := reflect.ValueOf(p.Value)
+	switch v.Kind() {
+	case reflect.Int,
+		reflect.Int8,
+		reflect.Int16,
+		reflect.Int32,
+		reflect.Int64:
+		value = v.Int()
+	case reflect.Float32,
+		reflect.Float64:
+		value = v.Float()
+	default:
+		return liberr.Wrap(PredicateValueErr)
+	}
+	switch p.Operator {
+	case "=", "!=", ">", "<":
+	default:
+		return liberr.Wrap(PredicateTypeErr)
+	}
+	p.expr = strings.Join(
+		[]string{
+			p.Function.Name + "(" + column + ")",
+			p.Operator,
+			options.Param(name, value),
+		},
+		" ")
+
+	return nil
+}
+
+//
+// Flatten the predicates.
+// Nested OR predicates are flattened; duplicates removed and
+// Eq predicates on the same field combined into an IN.
+func (p *OrPredicate) flatten() (flattened []Predicate) {
+	seen := map[string]bool{}
+	in := map[string]*InPredicate{}
+	var add func(list []Predicate)
+	add = func(list []Predicate) {
+		for _, predicate := range list {
+			switch predicate.(type) {
+			case *OrPredicate:
+				add(predicate.(*OrPredicate).Predicates)
+				continue
+			case *EqPredicate:
+				eq := predicate.(*EqPredicate)
+				if _, isField := eq.Value.(Field); isField {
+					break
+				}
+				key := eq.key(eq)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				field := strings.ToLower(eq.Field)
+				if matched, found := in[field]; found {
+					matched.Values = append(matched.Values, eq.Value)
+					continue
+				}
+				matched := In(eq.Field, eq.Value)
+				in[field] = matched
+				flattened = append(flattened, matched)
+				continue
+			case *NeqPredicate:
+				key := predicate.(*NeqPredicate).key(predicate)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			case *GtPredicate:
+				key := predicate.(*GtPredicate).key(predicate)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			case *LtPredicate:
+				key := predicate.(*LtPredicate).key(predicate)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			case *IsNullPredicate:
+				key := predicate.(*IsNullPredicate).key(predicate)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+			}
+			flattened = append(flattened, predicate)
+		}
+	}
+	add(p.Predicates)
+
+	return
+}
+
+//
+// Estimate the SQLite expression (tree) depth of the
+// built predicate. Binary operators (AND, OR) are parsed
+// into a left-deep tree so each term adds a level.
+func exprDepth(predicate Predicate) int {
+	compound := func(list []Predicate) (depth int) {
+		for _, p := range list {
+			if d := exprDepth(p); d > depth {
+				depth = d
+			}
+		}
+		if len(list) > 1 {
+			depth += len(list) - 1
+		}
+		return
+	}
+	switch predicate.(type) {
+	case *AndPredicate:
+		return compound(predicate.(*AndPredicate).Predicates)
+	case *OrPredicate:
+		return compound(predicate.(*OrPredicate).built)
+	case *ExistsPredicate:
+		p := predicate.(*ExistsPredicate)
+		if p.Predicate != nil {
+			return exprDepth(p.Predicate) + 2
+		}
+		return 2
+	case *FuncPredicate:
+		return 3
+	case nil:
+		return 0
+	default:
+		return 2
+	}
+}
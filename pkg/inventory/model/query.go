@@ -0,0 +1,179 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"reflect"
+)
+
+//
+// Errors
+var (
+	// Prepared statements not supported.
+	PrepareErr = errors.New("DB does not support prepared statements")
+	// Query parameter (values) error.
+	QueryParamErr = errors.New("query values must match the (compiled) parameters")
+)
+
+//
+// Prepared (compiled) list query.
+// The SQL is rendered and the statement prepared once and
+// may be run many times with different parameter values.
+// Safe for concurrent use. Must be closed.
+// Example:
+//   q, err := DB.Compile(&Person{}, ListOptions{Predicate: Eq("Age", 0)})
+//   defer q.Close()
+//   err = q.Run(&persons, 18)
+type Query struct {
+	// Rendered SQL.
+	SQL string
+	// Table.
+	table Table
+	// Model (template).
+	model interface{}
+	// Built list options.
+	options ListOptions
+	// Prepared statement.
+	stmt *sql.Stmt
+	// Parameter names by position.
+	// Set when parameters are bound by position.
+	order []string
+}
+
+//
+// Compile a list query for the model.
+// The DB must support prepared statements (sql.DB, sql.Tx).
+func (t Table) Compile(model interface{}, options ListOptions) (*Query, error) {
+	mt := reflect.TypeOf(model)
+	if mt.Kind() != reflect.Ptr {
+		return nil, liberr.Wrap(MustBePtrErr)
+	}
+	if options.Filter != nil {
+		if reflect.TypeOf(options.Filter) != mt {
+			return nil, liberr.Wrap(FilterTypeErr)
+		}
+	}
+	db, cast := t.DB.(interface {
+		Prepare(string) (*sql.Stmt, error)
+	})
+	if !cast {
+		return nil, liberr.Wrap(PrepareErr)
+	}
+	model = reflect.New(mt.Elem()).Interface()
+	options = options.Clone()
+	t.detail(model, &options)
+	t.intercept(model, &options)
+	fields, err := t.Fields(model)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	err = t.ValidateKeyring(fields)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	stmt, err := t.listSQL(t.Name(model), fields, &options)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	var order []string
+	if t.Positional {
+		stmt, order = positional(stmt)
+	}
+	prepared, err := db.Prepare(stmt)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	q := &Query{
+		SQL:     stmt,
+		table:   t,
+		model:   model,
+		options: options,
+		stmt:    prepared,
+		order:   order,
+	}
+
+	return q, nil
+}
+
+//
+// Names of the (compiled) parameters.
+// Ordered as the values passed to Run().
+func (q *Query) Names() (names []string) {
+	for _, p := range q.options.Params() {
+		names = append(names, p.(sql.NamedArg).Name)
+	}
+
+	return
+}
+
+//
+// Run the query.
+// The `list` must be: *[]Model (of the compiled model).
+// When specified, the `values` replace the (compiled)
+// parameter values by position. See: Names().
+func (q *Query) Run(list interface{}, values ...interface{}) error {
+	lt := reflect.TypeOf(list)
+	lv := reflect.ValueOf(list)
+	if lt.Kind() != reflect.Ptr ||
+		lt.Elem().Kind() != reflect.Slice ||
+		reflect.PtrTo(lt.Elem().Elem()) != reflect.TypeOf(q.model) {
+		return liberr.Wrap(MustBeSlicePtrErr)
+	}
+	params := q.options.Params()
+	if len(values) > 0 {
+		if len(values) != len(params) {
+			return liberr.Wrap(QueryParamErr)
+		}
+		bound := []interface{}{}
+		for i, p := range params {
+			bound = append(
+				bound,
+				sql.Named(p.(sql.NamedArg).Name, values[i]))
+		}
+		params = bound
+	}
+	if q.order != nil {
+		named := map[string]interface{}{}
+		for _, p := range params {
+			named[p.(sql.NamedArg).Name] = p.(sql.NamedArg).Value
+		}
+		bound := []interface{}{}
+		for _, name := range q.order {
+			bound = append(bound, named[name])
+		}
+		params = bound
+	}
+	model := reflect.New(reflect.TypeOf(q.model).Elem()).Interface()
+	fields, err := q.table.Fields(model)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	options := q.options
+	options.fields = fields
+	cursor, err := q.stmt.Query(params...)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	defer cursor.Close()
+	mList, err := q.table.fetch(cursor, lt.Elem(), &options)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+
+	lv.Elem().Set(mList)
+
+	return nil
+}
+
+//
+// Close the query.
+// The prepared statement is closed.
+func (q *Query) Close() error {
+	err := q.stmt.Close()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,209 @@
+package model
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+//
+// Predicate renderer.
+// Renders the (human readable) structure of a predicate
+// tree independent of Build(). Values are rendered as
+// numbered (:p0, :p1, ...) placeholders in order. Intended
+// for logging and tests; see Expr() for the (built) SQL.
+// Example:
+//   And(Eq("Name", "a"), Gt("Count", 1)).String()
+// renders:
+//   (Name = :p0 AND Count > :p1)
+type renderer struct {
+	// Number of placeholders rendered.
+	n int
+}
+
+//
+// Render the predicate.
+func (r *renderer) render(predicate Predicate) string {
+	switch predicate.(type) {
+	case *EqPredicate:
+		p := predicate.(*EqPredicate)
+		return r.simple(&p.SimplePredicate, "=")
+	case *NeqPredicate:
+		p := predicate.(*NeqPredicate)
+		return r.simple(&p.SimplePredicate, "!=")
+	case *GtPredicate:
+		p := predicate.(*GtPredicate)
+		return r.simple(&p.SimplePredicate, ">")
+	case *LtPredicate:
+		p := predicate.(*LtPredicate)
+		return r.simple(&p.SimplePredicate, "<")
+	case *InPredicate:
+		p := predicate.(*InPredicate)
+		params := []string{}
+		for range p.Values {
+			params = append(params, r.param())
+		}
+		return p.Field + " IN (" + strings.Join(params, ",") + ")"
+	case *IsNullPredicate:
+		return predicate.(*IsNullPredicate).Field + " IS NULL"
+	case *AndPredicate:
+		return r.compound(predicate.(*AndPredicate).Predicates, "AND")
+	case *OrPredicate:
+		return r.compound(predicate.(*OrPredicate).Predicates, "OR")
+	case *LabelPredicate:
+		p := predicate.(*LabelPredicate)
+		keys := []string{}
+		for k := range p.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		labels := []string{}
+		for _, k := range keys {
+			labels = append(labels, k+" = "+r.param())
+		}
+		return "MATCH(" + strings.Join(labels, ",") + ")"
+	case *ExistsPredicate:
+		p := predicate.(*ExistsPredicate)
+		kind := ""
+		if mt := reflect.TypeOf(p.Model); mt != nil {
+			if mt.Kind() == reflect.Ptr {
+				mt = mt.Elem()
+			}
+			kind = mt.Name()
+		}
+		s := "EXISTS(" + kind + "." + p.Field
+		if p.Predicate != nil {
+			s += " WHERE " + r.render(p.Predicate)
+		}
+		return s + ")"
+	case *FuncPredicate:
+		p := predicate.(*FuncPredicate)
+		return strings.Join(
+			[]string{
+				p.Function.Name + "(" + p.Function.Field + ")",
+				p.Operator,
+				r.param(),
+			},
+			" ")
+	case *MapPredicate:
+		p := predicate.(*MapPredicate)
+		s := p.Field + "[" + r.param() + "]"
+		if p.Value != nil {
+			return s + " = " + r.param()
+		}
+		return "HAS " + s
+	case nil:
+		return ""
+	case fmt.Stringer:
+		return predicate.(fmt.Stringer).String()
+	default:
+		return fmt.Sprintf("%T", predicate)
+	}
+}
+
+//
+// Render a simple predicate.
+// A field reference is rendered as the field name.
+func (r *renderer) simple(p *SimplePredicate, operator string) string {
+	value := ""
+	if ref, isField := p.Value.(Field); isField {
+		value = ref.Name
+	} else {
+		value = r.param()
+	}
+
+	return strings.Join([]string{p.Field, operator, value}, " ")
+}
+
+//
+// Render a compound predicate.
+func (r *renderer) compound(list []Predicate, operator string) string {
+	predicates := []string{}
+	for _, p := range list {
+		predicates = append(predicates, r.render(p))
+	}
+
+	return "(" + strings.Join(predicates, " "+operator+" ") + ")"
+}
+
+//
+// Render the next placeholder.
+func (r *renderer) param() string {
+	s := fmt.Sprintf(":p%d", r.n)
+	r.n++
+	return s
+}
+
+//
+// String representation.
+func (p *EqPredicate) String() string {
+	return (&renderer{}).render(p)
+}
+
+//
+// String representation.
+func (p *NeqPredicate) String() string {
+	return (&renderer{}).render(p)
+}
+
+//
+// String representation.
+func (p *GtPredicate) String() string {
+	return (&renderer{}).render(p)
+}
+
+//
+// String representation.
+func (p *LtPredicate) String() string {
+	return (&renderer{}).render(p)
+}
+
+//
+// String representation.
+func (p *InPredicate) String() string {
+	return (&renderer{}).render(p)
+}
+
+//
+// String representation.
+func (p *IsNullPredicate) String() string {
+	return (&renderer{}).render(p)
+}
+
+//
+// String representation.
+func (p *AndPredicate) String() string {
+	return (&renderer{}).render(p)
+}
+
+//
+// String representation.
+// The (unflattened) predicates are rendered.
+func (p *OrPredicate) String() string {
+	return (&renderer{}).render(p)
+}
+
+//
+// String representation.
+func (p *LabelPredicate) String() string {
+	return (&renderer{}).render(p)
+}
+
+//
+// String representation.
+func (p *ExistsPredicate) String() string {
+	return (&renderer{}).render(p)
+}
+
+//
+// String representation.
+func (p *FuncPredicate) String() string {
+	return (&renderer{}).render(p)
+}
+
+//
+// String representation.
+func (p *MapPredicate) String() string {
+	return (&renderer{}).render(p)
+}
@@ -0,0 +1,114 @@
+package model
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"reflect"
+	"text/template"
+)
+
+var GetByRowIDSQL = `
+SELECT
+{{ range $i,$f := .Fields -}}
+{{ if $i }},{{ end -}}
+{{ $f.Column }}
+{{ end -}}
+FROM {{.Table}}
+WHERE
+rowid = :rowid
+{{ if .Options -}}
+AND {{ .Predicate.Expr }}
+{{ end -}}
+;
+`
+
+//
+// Errors
+var (
+	// RowID field error.
+	RowIDErr = errors.New("rowid field must be int64")
+)
+
+//
+// Get whether the field carries the (SQLite) rowid.
+// A `rowid` field is virtual and bound to the implicit
+// rowid column. Not valid on WITHOUT ROWID tables.
+func (f *Field) RowID() bool {
+	return f.hasOpt("rowid")
+}
+
+//
+// Validate the rowid field.
+func (f *Field) validateRowID() error {
+	if f.RowID() && f.Value.Kind() != reflect.Int64 {
+		return liberr.Wrap(RowIDErr)
+	}
+
+	return nil
+}
+
+//
+// Get the model in the DB by (SQLite) rowid.
+// Fetch the row and populate the model fields. The model
+// need not have a `rowid` field. Intended for tooling and
+// keyset pagination referencing the physical row.
+// Returns WithoutRowIDErr for WITHOUT ROWID tables.
+func (t Table) GetByRowID(model interface{}, rowid int64) error {
+	if m, cast := model.(WithoutRowID); cast && m.WithoutRowID() {
+		return liberr.Wrap(WithoutRowIDErr)
+	}
+	fields, err := t.Fields(model)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	err = t.ValidateKeyring(fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	options, err := t.intercepted(model, fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	tpl := template.New("")
+	tpl, err = tpl.Parse(GetByRowIDSQL)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	bfr := &bytes.Buffer{}
+	err = tpl.Execute(
+		bfr,
+		TmplData{
+			Table:   t.Name(model),
+			Fields:  fields,
+			Options: options,
+		})
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	params := []interface{}{sql.Named("rowid", rowid)}
+	if options != nil {
+		params = append(params, options.Params()...)
+	}
+	cursor, err := t.db().Query(bfr.String(), params...)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	defer cursor.Close()
+	if !cursor.Next() {
+		err = cursor.Err()
+		if err == nil {
+			err = NotFound
+		}
+		return liberr.Wrap(err)
+	}
+	err = t.scan(cursor, fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	cursor.Close()
+	err = t.getMaps(model, DetailAll)
+
+	return liberr.Wrap(err)
+}
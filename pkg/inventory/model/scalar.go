@@ -0,0 +1,146 @@
+package model
+
+import (
+	"bytes"
+	"database/sql"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+var ScalarSQL = `
+SELECT
+{{ .Function }},
+COUNT(*)
+FROM {{.Table}}
+{{ if .Predicate -}}
+WHERE
+{{ .Predicate.Expr }}
+{{ end -}}
+;
+`
+
+//
+// Get a (single) scalar value of a field in the DB.
+// The aggregate `function` is applied to the field of
+// the models matching the predicate. The value is typed:
+//   MIN, MAX = the field type; nil when no models match.
+//   COUNT, SUM = int64.
+//   AVG = float64.
+// Example (the highest sequence number):
+//   seq, err := DB.Scalar(&Event{}, "MAX", "Seq", nil)
+func (t Table) Scalar(model interface{}, function, field string, predicate Predicate) (interface{}, error) {
+	function = strings.ToUpper(function)
+	if reflect.TypeOf(model).Kind() != reflect.Ptr {
+		return nil, liberr.Wrap(MustBePtrErr)
+	}
+	scratch := reflect.New(reflect.TypeOf(model).Elem()).Interface()
+	fields, err := t.Fields(scratch)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	f, found := t.find(field, fields)
+	if !found ||
+		f.Custom() ||
+		f.Encoded() ||
+		f.Encrypted() ||
+		f.Compressed() {
+		return nil, liberr.Wrap(AggregateErr)
+	}
+	numeric := false
+	switch f.Value.Kind() {
+	case reflect.Int,
+		reflect.Int8,
+		reflect.Int16,
+		reflect.Int32,
+		reflect.Int64:
+		numeric = true
+	}
+	var expr string
+	var ptr interface{}
+	switch function {
+	case "MIN", "MAX":
+		zero := "''"
+		if f.SqlType() == "INTEGER" {
+			zero = "0"
+		}
+		expr = "COALESCE(" + function + "(" + f.Column + ")," + zero + ")"
+		f.string = ""
+		f.int = 0
+		ptr = f.Ptr()
+	case "COUNT":
+		expr = "COUNT(" + f.Column + ")"
+		ptr = &sql.NullInt64{}
+	case "SUM":
+		if !numeric {
+			return nil, liberr.Wrap(AggregateErr)
+		}
+		expr = "SUM(" + f.Column + ")"
+		ptr = &sql.NullInt64{}
+	case "AVG":
+		if !numeric {
+			return nil, liberr.Wrap(AggregateErr)
+		}
+		expr = "AVG(" + f.Column + ")"
+		ptr = &sql.NullFloat64{}
+	default:
+		return nil, liberr.Wrap(AggregateErr)
+	}
+	options := ListOptions{Predicate: predicate}
+	t.intercept(model, &options)
+	stmt, err := t.scalarSQL(t.Name(model), fields, expr, &options)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	count := int64(0)
+	err = t.db().QueryRow(stmt, options.Params()...).Scan(ptr, &count)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	switch v := ptr.(type) {
+	case *sql.NullInt64:
+		return v.Int64, nil
+	case *sql.NullFloat64:
+		return v.Float64, nil
+	}
+	if count == 0 {
+		return nil, nil
+	}
+	err = f.Push()
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+
+	return f.Value.Interface(), nil
+}
+
+//
+// Build scalar SQL.
+func (t Table) scalarSQL(table string, fields []*Field, expr string, options *ListOptions) (string, error) {
+	tpl := template.New("")
+	tpl, err := tpl.Parse(ScalarSQL)
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+	options.namer = t.namer()
+	options.limits = t.Limits
+	err = options.Build(table, fields)
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+	bfr := &bytes.Buffer{}
+	err = tpl.Execute(
+		bfr,
+		TmplData{
+			Table:    table,
+			Fields:   fields,
+			Options:  options,
+			Function: expr,
+		})
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+
+	return bfr.String(), nil
+}
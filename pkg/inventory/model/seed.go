@@ -0,0 +1,237 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"io"
+	"reflect"
+	"strings"
+)
+
+//
+// Errors
+var (
+	// Model kind not known (registered).
+	KindErr = errors.New("model kind not known")
+)
+
+//
+// Seed error.
+// Reports each model that failed to be seeded.
+type SeedError struct {
+	// Failed models.
+	Failed []SeedFailure
+}
+
+//
+// Error description.
+func (e *SeedError) Error() string {
+	list := []string{}
+	for _, f := range e.Failed {
+		list = append(list, f.String())
+	}
+
+	return "seed failed: " + strings.Join(list, "; ")
+}
+
+//
+// Model seed failure.
+type SeedFailure struct {
+	// Model kind (type name).
+	Kind string
+	// Index of the model (within the kind) as specified.
+	Index int
+	// The model.
+	Model Model
+	// Error.
+	Err error
+}
+
+//
+// String representation.
+func (f *SeedFailure) String() string {
+	return fmt.Sprintf("%s[%d]: %s", f.Kind, f.Index, f.Err)
+}
+
+//
+// Seed (load) models.
+// Intended for tests and bootstrapping. The models are inserted
+// within a single transaction ordered by foreign key dependency
+// (by kind) when possible. Models already stored (by PK) are
+// updated (upsert) so seeding may be repeated. All models are
+// attempted and a SeedError reporting each failed model is
+// returned and the transaction rolled back.
+func (r *Client) Seed(models ...Model) (err error) {
+	tx, err := r.Begin()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	defer tx.Rollback()
+	table := r.table(tx.real)
+	seedErr := &SeedError{}
+	for _, kind := range r.seedOrder(models) {
+		index := 0
+		for _, m := range models {
+			if r.kind(m).Name() != kind {
+				continue
+			}
+			err = table.Get(Clone(m))
+			switch {
+			case err == nil:
+				err = tx.Update(m)
+			case errors.Is(err, NotFound):
+				err = tx.Insert(m)
+			}
+			if err != nil {
+				seedErr.Failed = append(
+					seedErr.Failed,
+					SeedFailure{
+						Kind:  kind,
+						Index: index,
+						Model: m,
+						Err:   err,
+					})
+			}
+			index++
+		}
+	}
+	if len(seedErr.Failed) > 0 {
+		return seedErr
+	}
+	err = tx.Commit()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+
+	return nil
+}
+
+//
+// Seed (load) models from JSON.
+// The JSON object contains arrays of models (objects keyed by
+// column name) keyed by model kind (type name). See: Seed().
+// Example:
+//   {
+//       "Person": [
+//           {"First": "Elmer", "Last": "Fudd"}
+//       ]
+//   }
+func (r *Client) SeedFromJSON(rd io.Reader) error {
+	document := map[string][]map[string]json.RawMessage{}
+	err := json.NewDecoder(rd).Decode(&document)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	r.stateMutex.RLock()
+	kinds := r.kinds
+	r.stateMutex.RUnlock()
+	table := r.table(nil)
+	models := []Model{}
+	seedErr := &SeedError{}
+	for kind, list := range document {
+		mt, found := kinds[kind]
+		for i, values := range list {
+			if !found {
+				seedErr.Failed = append(
+					seedErr.Failed,
+					SeedFailure{
+						Kind:  kind,
+						Index: i,
+						Err:   liberr.Wrap(KindErr),
+					})
+				continue
+			}
+			m, cast := reflect.New(mt).Interface().(Model)
+			if !cast {
+				seedErr.Failed = append(
+					seedErr.Failed,
+					SeedFailure{
+						Kind:  kind,
+						Index: i,
+						Err:   liberr.Wrap(KindErr),
+					})
+				continue
+			}
+			err = table.SetColumns(m, values)
+			if err != nil {
+				seedErr.Failed = append(
+					seedErr.Failed,
+					SeedFailure{
+						Kind:  kind,
+						Index: i,
+						Model: m,
+						Err:   err,
+					})
+				continue
+			}
+			models = append(models, m)
+		}
+	}
+	if len(seedErr.Failed) > 0 {
+		return seedErr
+	}
+
+	return r.Seed(models...)
+}
+
+//
+// Order the kinds of the models by foreign key dependency.
+// Referenced kinds are ordered first. Otherwise, kinds are
+// ordered as first specified. Cyclic dependencies are
+// ordered as first specified.
+func (r *Client) seedOrder(models []Model) []string {
+	table := r.table(nil)
+	kinds := []string{}
+	depends := map[string]map[string]bool{}
+	for _, m := range models {
+		kind := r.kind(m).Name()
+		if _, found := depends[kind]; found {
+			continue
+		}
+		kinds = append(kinds, kind)
+		depends[kind] = map[string]bool{}
+		fields, err := table.Fields(m)
+		if err != nil {
+			continue
+		}
+		for _, f := range fields {
+			if fk := f.Fk(); fk != nil && fk.Table != kind {
+				depends[kind][fk.Table] = true
+			}
+		}
+	}
+	ordered := []string{}
+	done := map[string]bool{}
+	for len(ordered) < len(kinds) {
+		progress := false
+		for _, kind := range kinds {
+			if done[kind] {
+				continue
+			}
+			ready := true
+			for dependency := range depends[kind] {
+				if _, seeded := depends[dependency]; seeded && !done[dependency] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				ordered = append(ordered, kind)
+				done[kind] = true
+				progress = true
+			}
+		}
+		if !progress {
+			for _, kind := range kinds {
+				if !done[kind] {
+					ordered = append(ordered, kind)
+					done[kind] = true
+				}
+			}
+		}
+	}
+
+	return ordered
+}
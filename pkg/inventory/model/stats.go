@@ -0,0 +1,73 @@
+package model
+
+import (
+	"database/sql"
+	"sync/atomic"
+)
+
+//
+// Client statistics.
+// A (point in time) snapshot of the client state.
+type Stats struct {
+	// The DB is open.
+	Open bool
+	// A transaction is in progress.
+	TxOpen bool
+	// Number of models (tables) in the schema.
+	Models int
+	// Number of statements executed since opened.
+	Statements uint64
+	// Connection pool statistics.
+	Pool sql.DBStats
+}
+
+//
+// Get the client statistics.
+func (r *Client) Stats() Stats {
+	stats := Stats{
+		TxOpen: atomic.LoadInt32(&r.txOpen) == 1,
+		Models: len(r.schema()),
+	}
+	r.stateMutex.RLock()
+	defer r.stateMutex.RUnlock()
+	if r.db != nil {
+		stats.Open = true
+		stats.Pool = r.db.Stats()
+	}
+	if r.statements != nil {
+		stats.Statements = atomic.LoadUint64(r.statements)
+	}
+
+	return stats
+}
+
+//
+// Counted DB.
+// Counts the statements executed.
+type countDB struct {
+	// Wrapped DB.
+	DB DBTX
+	// Counter.
+	n *uint64
+}
+
+//
+// Execute a statement.
+func (d *countDB) Exec(stmt string, args ...interface{}) (sql.Result, error) {
+	atomic.AddUint64(d.n, 1)
+	return d.DB.Exec(stmt, args...)
+}
+
+//
+// Execute a query.
+func (d *countDB) Query(stmt string, args ...interface{}) (*sql.Rows, error) {
+	atomic.AddUint64(d.n, 1)
+	return d.DB.Query(stmt, args...)
+}
+
+//
+// Execute a query expected to return (at most) one row.
+func (d *countDB) QueryRow(stmt string, args ...interface{}) *sql.Row {
+	atomic.AddUint64(d.n, 1)
+	return d.DB.QueryRow(stmt, args...)
+}
@@ -2,17 +2,22 @@ package model
 
 import (
 	"bytes"
-	"crypto/sha1"
+	"compress/gzip"
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/binary"
-	"encoding/hex"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
 	liberr "github.com/konveyor/controller/pkg/error"
 	"github.com/mattn/go-sqlite3"
+	"io"
+	"io/ioutil"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"text/template"
@@ -20,12 +25,38 @@ import (
 
 const (
 	Tag = "sql"
+	// Maximum number of values (parameters) used
+	// in a chunked statement.
+	MaxChunk = 500
+	// Maximum (listed) slice capacity pre-allocated
+	// based on the page limit.
+	MaxPageCapacity = 1000
+	// Maximum int.
+	maxInt = int(^uint(0) >> 1)
+)
+
+//
+// Field detail levels.
+// A field is selected when its level <= the requested
+// level (DetailAll selects all fields). Levels 4-9 may
+// be assigned using `dN` tags.
+const (
+	// pk, key and virtual fields.
+	DetailCore = 0
+	// All fields.
+	DetailAll = 1
+	// Plain (not encoded) fields.
+	DetailPlain = 2
+	// Encoded (json) fields.
+	DetailEncoded = 3
+	// Maximum (dN) level.
+	DetailMax = 9
 )
 
 //
 // DDL templates.
 var TableDDL = `
-CREATE TABLE IF NOT EXISTS {{.Table}} (
+CREATE TABLE IF NOT EXISTS {{ if .Schema }}{{ .Schema }}.{{ end }}{{.Table}} (
 {{ range $i,$f := .Fields -}}
 {{ if $i }},{{ end -}}
 {{ $f.DDL }}
@@ -33,18 +64,112 @@ CREATE TABLE IF NOT EXISTS {{.Table}} (
 {{ range $i,$c := .Constraints -}}
 ,{{ $c }}
 {{ end -}}
-);
+){{ if .WithoutRowID }} WITHOUT ROWID{{ end }};
 `
 
 var IndexDDL = `
-CREATE INDEX IF NOT EXISTS {{.Table}}Index
+CREATE INDEX IF NOT EXISTS {{ if .Schema }}{{ .Schema }}.{{ end }}{{.Table}}Index
+ON {{.Table}}
+(
+{{ range $i,$f := .Fields -}}
+{{ if $i }},{{ end -}}
+{{ $f.Column }}
+{{ end -}}
+);
+`
+
+var KeyIndexDDL = `
+CREATE UNIQUE INDEX IF NOT EXISTS {{ if .Schema }}{{ .Schema }}.{{ end }}{{.Table}}KeyIndex
 ON {{.Table}}
 (
 {{ range $i,$f := .Fields -}}
 {{ if $i }},{{ end -}}
-{{ $f.Name }}
+{{ $f.Column }}
+{{ end -}}
+);
+`
+
+var FtsDDL = `
+CREATE VIRTUAL TABLE IF NOT EXISTS {{ if .Schema }}{{ .Schema }}.{{ end }}{{.Table}}Fts
+USING fts5 (
+{{ range $i,$f := .Fields -}}
+{{ $f.Column }},
+{{ end -}}
+content='{{.Table}}'
+);
+`
+
+var FtsInsertDDL = `
+CREATE TRIGGER IF NOT EXISTS {{ if .Schema }}{{ .Schema }}.{{ end }}{{.Table}}FtsInsert
+AFTER INSERT ON {{.Table}}
+BEGIN
+INSERT INTO {{.Table}}Fts (
+rowid
+{{ range $i,$f := .Fields -}}
+,{{ $f.Column }}
+{{ end -}}
+)
+VALUES (
+new.rowid
+{{ range $i,$f := .Fields -}}
+,new.{{ $f.Column }}
+{{ end -}}
+);
+END;
+`
+
+var FtsDeleteDDL = `
+CREATE TRIGGER IF NOT EXISTS {{ if .Schema }}{{ .Schema }}.{{ end }}{{.Table}}FtsDelete
+AFTER DELETE ON {{.Table}}
+BEGIN
+INSERT INTO {{.Table}}Fts (
+{{.Table}}Fts
+,rowid
+{{ range $i,$f := .Fields -}}
+,{{ $f.Column }}
+{{ end -}}
+)
+VALUES (
+'delete'
+,old.rowid
+{{ range $i,$f := .Fields -}}
+,old.{{ $f.Column }}
+{{ end -}}
+);
+END;
+`
+
+var FtsUpdateDDL = `
+CREATE TRIGGER IF NOT EXISTS {{ if .Schema }}{{ .Schema }}.{{ end }}{{.Table}}FtsUpdate
+AFTER UPDATE ON {{.Table}}
+BEGIN
+INSERT INTO {{.Table}}Fts (
+{{.Table}}Fts
+,rowid
+{{ range $i,$f := .Fields -}}
+,{{ $f.Column }}
+{{ end -}}
+)
+VALUES (
+'delete'
+,old.rowid
+{{ range $i,$f := .Fields -}}
+,old.{{ $f.Column }}
+{{ end -}}
+);
+INSERT INTO {{.Table}}Fts (
+rowid
+{{ range $i,$f := .Fields -}}
+,{{ $f.Column }}
+{{ end -}}
+)
+VALUES (
+new.rowid
+{{ range $i,$f := .Fields -}}
+,new.{{ $f.Column }}
 {{ end -}}
 );
+END;
 `
 
 //
@@ -53,7 +178,7 @@ var InsertSQL = `
 INSERT INTO {{.Table}} (
 {{ range $i,$f := .Fields -}}
 {{ if $i}},{{ end -}}
-{{ $f.Name }}
+{{ $f.Column }}
 {{ end -}}
 )
 VALUES (
@@ -69,17 +194,17 @@ UPDATE {{.Table}}
 SET
 {{ range $i,$f := .Fields -}}
 {{ if $i }},{{ end -}}
-{{ $f.Name }} = {{ $f.Param }}
+{{ $f.Column }} = {{ $f.Param }}
 {{ end -}}
 WHERE
-{{ .Pk.Name }} = {{ .Pk.Param }}
+{{ .Pk.Column }} = {{ .Pk.Param }}
 ;
 `
 
 var DeleteSQL = `
 DELETE FROM {{.Table}}
 WHERE
-{{ .Pk.Name }} = {{ .Pk.Param }}
+{{ .Pk.Column }} = {{ .Pk.Param }}
 ;
 `
 
@@ -87,11 +212,14 @@ var GetSQL = `
 SELECT
 {{ range $i,$f := .Fields -}}
 {{ if $i }},{{ end -}}
-{{ $f.Name }}
+{{ $f.Column }}
 {{ end -}}
 FROM {{.Table}}
 WHERE
-{{ .Pk.Name }} = {{ .Pk.Param }}
+{{ .Pk.Column }} = {{ .Pk.Param }}
+{{ if .Options -}}
+AND {{ .Predicate.Expr }}
+{{ end -}}
 ;
 `
 
@@ -102,16 +230,23 @@ COUNT(*)
 {{ else -}}
 {{ range $i,$f := .Options.Fields -}}
 {{ if $i }},{{ end -}}
-{{ $f.Name }}
+{{ $f.Column }}
 {{ end -}}
 {{ end -}}
 FROM {{.Table}}
+{{ if .Search -}}
+JOIN (
+SELECT rowid AS FtsRowID, rank AS FtsRank
+FROM {{.Table}}Fts({{ .Search }})
+) ON {{.Table}}.rowid = FtsRowID
+{{ end -}}
 {{ if or .Predicate -}}
 WHERE
 {{ end -}}
 {{ if .Predicate -}}
 {{ .Predicate.Expr }}
 {{ end -}}
+{{ if not .Count -}}
 {{ if .Sort -}}
 ORDER BY
 {{ range $i,$n := .Sort -}}
@@ -119,14 +254,90 @@ ORDER BY
 {{ end -}}
 {{ end -}}
 {{ if .Page -}}
-LIMIT {{.Page.Limit}} OFFSET {{.Page.Offset}}
+LIMIT {{ .Limit }} OFFSET {{ .Offset }}
+{{ end -}}
+{{ end -}}
+;
+`
+
+var CountBySQL = `
+SELECT
+{{ range $i,$f := .Group -}}
+{{ $f.Column }},
+{{ end -}}
+COUNT(*)
+FROM {{.Table}}
+{{ if .Predicate -}}
+WHERE
+{{ .Predicate.Expr }}
+{{ end -}}
+GROUP BY
+{{ range $i,$f := .Group -}}
+{{ if $i }},{{ end -}}
+{{ $f.Column }}
+{{ end -}}
+{{ if .Having -}}
+HAVING
+{{ .Having }}
+{{ end -}}
+ORDER BY
+{{ range $i,$f := .Group -}}
+{{ if $i }},{{ end -}}
+{{ $f.Column }}
+{{ end -}}
+;
+`
+
+var DistinctSQL = `
+SELECT DISTINCT
+{{ (index .Group 0).Column }}
+FROM {{.Table}}
+{{ if .Predicate -}}
+WHERE
+{{ .Predicate.Expr }}
+{{ end -}}
+ORDER BY
+{{ (index .Group 0).Column }}
+;
+`
+
+var AggregateSQL = `
+SELECT
+{{ .Function }}({{ (index .Group 0).Column }})
+FROM {{.Table}}
+{{ if .Predicate -}}
+WHERE
+{{ .Predicate.Expr }}
 {{ end -}}
 ;
 `
 
+//
+// Aggregate functions supported by Aggregate().
+var Aggregates = []string{
+	"SUM",
+	"AVG",
+	"MIN",
+	"MAX",
+}
+
+//
+// Column (SQL) types of the database/sql nullable types.
+// Their zero (NULL) value does not report the type.
+var NullTypes = map[reflect.Type]string{
+	reflect.TypeOf(sql.NullBool{}):    "INTEGER",
+	reflect.TypeOf(sql.NullInt32{}):   "INTEGER",
+	reflect.TypeOf(sql.NullInt64{}):   "INTEGER",
+	reflect.TypeOf(sql.NullFloat64{}): "REAL",
+	reflect.TypeOf(sql.NullString{}):  "TEXT",
+	reflect.TypeOf(sql.NullTime{}):    "TEXT",
+}
+
 //
 // Errors
 var (
+	// Must have (sql tagged) fields.
+	NoFieldsErr = errors.New("must have `sql` tagged fields")
 	// Must have PK.
 	MustHavePkErr = errors.New("must have PK field")
 	// Parameter must be pointer error.
@@ -147,54 +358,417 @@ var (
 	PredicateTypeErr = errors.New("predicate type not valid for field")
 	// Invalid predicate value.
 	PredicateValueErr = errors.New("predicate value not valid")
+	// Filter must be the listed model type.
+	FilterTypeErr = errors.New("filter must be the listed model type")
+	// Collation error.
+	CollationErr = errors.New("collation must be (binary, nocase, rtrim) on str field")
+	// Generated column error.
+	GeneratedErr = errors.New("generated column must not be (pk, key)")
+	// Invalid page.
+	InvalidPageErr = errors.New("page offset must be >= 0 and limit > 0")
+	// Enum error.
+	EnumErr = errors.New("enum must have values and be on str or int field")
+	// Encrypted field error.
+	EncryptErr = errors.New("encrypted field must be (str, encoded) and not (pk, key, unique, fk)")
+	// Custom (sql.Scanner, driver.Valuer) field error.
+	CustomErr = errors.New("custom field must not be (pk, encrypt, compress, enum)")
+	// Compressed field error.
+	CompressErr = errors.New("compressed field must be encoded (struct, slice, map)")
+	// Predicate references an encrypted field.
+	PredicateEncryptedErr = errors.New("predicate not valid for encrypted field")
+	// Invalid group field.
+	GroupFieldErr = errors.New("group field must be known and not (encrypted, compressed)")
+	// Function not supported (by predicates).
+	FunctionErr = errors.New("function not supported")
+	// Full-text search (fts) error.
+	FtsErr = errors.New("fts field must be (str) and not encrypted on a rowid table")
+	// Unpaged list result exceeds the maximum rows.
+	ResultTooLargeErr = errors.New("result exceeds the maximum rows; use a page")
+	// WITHOUT ROWID table error.
+	WithoutRowIDErr = errors.New("without rowid table must have str PK and no virtual fields")
+	// Detail level (dN) tag error.
+	DetailErr = errors.New("detail level (dN) tag must be unique and N = (0-9)")
+	// Natural key field error.
+	MutableKeyErr = errors.New("natural key field must be const (not virtual)")
+	// Statement parameter limit exceeded.
+	ParamLimitErr = errors.New("parameters exceed the SQLite variable number limit; use fewer values (or GetMany)")
+	// Expression depth limit exceeded.
+	ExprDepthErr = errors.New("predicate exceeds the SQLite expression depth limit; use fewer (or In) predicates")
+	// Aggregate error.
+	AggregateErr = errors.New("aggregate must be (SUM, AVG, MIN, MAX) on a known (int) field")
+	// Text (bool) field error.
+	BoolTextErr = errors.New("text field must be (bool)")
+	// Having not valid.
+	HavingErr = errors.New("having must be used with grouping (and aggregates only in having)")
 )
 
+//
+// SQLite (connection) limits.
+// Zero = not checked.
+type Limits struct {
+	// Maximum number of (statement) parameters.
+	Variables int
+	// Maximum expression (tree) depth.
+	ExprDepth int
+}
+
+//
+// Enum value error.
+// The field value is not one of the enumerated values.
+type EnumError struct {
+	// Field name.
+	Field string
+	// The (invalid) value.
+	Value string
+	// Enumerated values.
+	Enum []string
+}
+
+//
+// Error description.
+func (e *EnumError) Error() string {
+	return fmt.Sprintf(
+		"field: %s value: '%s' must be in: (%s)",
+		e.Field,
+		e.Value,
+		strings.Join(e.Enum, "|"))
+}
+
+//
+// Schema (build) error.
+// Names the model (and field) which could not be built.
+type SchemaError struct {
+	// Model (type) name.
+	Model string
+	// Field name.
+	Field string
+	// Wrapped error.
+	Err error
+}
+
+//
+// Error description.
+func (e *SchemaError) Error() string {
+	s := ""
+	if e.Model != "" {
+		s += "model: " + e.Model + " "
+	}
+	if e.Field != "" {
+		s += "field: " + e.Field + " "
+	}
+
+	return s + e.Err.Error()
+}
+
+//
+// Unwrap the error.
+func (e *SchemaError) Unwrap() error {
+	return e.Err
+}
+
+//
+// Encoded field (json) error.
+// The field value could not be encoded or decoded.
+type CodecError struct {
+	// Field name.
+	Field string
+	// The encoder (decoder) error.
+	Err error
+}
+
+//
+// Error description.
+func (e *CodecError) Error() string {
+	return fmt.Sprintf(
+		"field: %s encoding failed: %s",
+		e.Field,
+		e.Err.Error())
+}
+
+//
+// Matches the encoder (decoder) error.
+func (e *CodecError) Is(target error) bool {
+	return errors.Is(e.Err, target)
+}
+
 //
 // Represents a table in the DB.
 // Using reflect, the model is inspected to determine the
 // table name and columns. The column definition is specified
 // using field tags:
 //   pk - Primary key.
-//   key - Natural key.
+//   key - Natural key (implicitly const).
 //   fk:<table>(field) - Foreign key.
 //   unique(<group>) - Unique constraint collated by <group>.
 //   const - Not updated.
+//   writeonce - Not updated; changing the value is an error.
+//   text - Bool stored as TEXT ('true','false').
+//   map - Map stored in a (child) key/value table.
+//   touch - Set to the current time (int64 Unix nanoseconds)
+//           on insert, update and touch.
+//   transform(A|B) - (str) value transformed on write and read.
 type Table struct {
 	// Database connection.
 	DB DBTX
+	// Table and column naming strategy.
+	// Defaults to the IdentityNamer.
+	Namer Namer
+	// Generated PK hashing strategy.
+	// Defaults to the SHA1Hasher.
+	Hasher Hasher
+	// Encoded field encoder.
+	// Defaults to the JSONEncoder.
+	Encoder Encoder
+	// Encoded field decoder.
+	// Defaults to the JSONDecoder.
+	Decoder Decoder
+	// Bind parameters by position (?) rather than by
+	// name (:name). The generated SQL is rewritten when
+	// executed. See: PositionalDB.
+	Positional bool
+	// Maximum page limit.
+	// Larger limits are capped. 0 = unlimited.
+	MaxPageLimit int
+	// Maximum number of models listed without a page.
+	// Larger (unpaged) results fail with ResultTooLargeErr
+	// once the limit is exceeded while fetching. 0 = unlimited.
+	MaxListRows int
+	// SQLite limits. Predicates exceeding the limits
+	// fail before execution and bulk operations are
+	// chunked within the limits.
+	Limits Limits
+	// Strict bool columns. Bool columns are created with
+	// DEFAULT 0 and CHECK (column IN (0,1)) so values
+	// other than 0 and 1 are rejected by the DB. Predicate
+	// values on bool fields are coerced to (0,1).
+	StrictBool bool
+	// Bool columns stored as TEXT ('true','false') rather
+	// than INTEGER (0,1). Intended for interop with tools
+	// reading the DB which expect textual bools. Costs more
+	// storage and the columns no longer compare (or join)
+	// with int columns. See: the `text` field tag.
+	BoolText bool
+	// Field value transforms by name. Take precedence
+	// over the built-in `Transforms`. See: the
+	// `transform` field tag.
+	Transforms map[string]Transform
+	// Intercepts (and may rewrite) the predicate of reads
+	// (list, count, get, aggregate). See: PredicateInterceptor.
+	Interceptor PredicateInterceptor
+	// Logs each statement when set. See: LogDB.
+	Log Logger
+	// Context passed to the interceptor.
+	// Default: context.TODO().
+	ctx context.Context
+	// Key/value pairs included in the statement
+	// log entries.
+	values []interface{}
+	// Executed statement counter.
+	counter *uint64
+	// Known model types (by type name) used to
+	// resolve FK references.
+	kinds map[string]reflect.Type
+	// Attached database (schema) alias by model
+	// type name.
+	schemas map[string]string
+	// Keyring used for encrypted fields.
+	keyring *Keyring
 }
 
 //
 // Get the table name for the model.
+// Qualified by the schema when the model is bound
+// to an attached database.
 func (t Table) Name(model interface{}) string {
+	schema, name := t.split(model)
+	if schema != "" {
+		name = schema + "." + name
+	}
+
+	return name
+}
+
+//
+// Get the (attached) schema and the unqualified
+// table name for the model.
+func (t Table) split(model interface{}) (schema, name string) {
 	mt := reflect.TypeOf(model)
 	if mt.Kind() == reflect.Ptr {
 		mt = mt.Elem()
 	}
+	schema = t.schemas[mt.Name()]
+	name = t.namer().TableName(mt)
+
+	return
+}
+
+//
+// Get the naming strategy.
+func (t Table) namer() Namer {
+	if t.Namer != nil {
+		return t.Namer
+	}
+
+	return IdentityNamer{}
+}
+
+//
+// Get the PK hashing strategy.
+func (t Table) hasher() Hasher {
+	if t.Hasher != nil {
+		return t.Hasher
+	}
+
+	return SHA1Hasher{}
+}
+
+//
+// Get the DB used to execute statements.
+func (t Table) db() DBTX {
+	db := t.DB
+	if t.counter != nil {
+		db = &countDB{
+			DB: db,
+			n:  t.counter,
+		}
+	}
+	if t.Log != nil {
+		db = &LogDB{
+			DB:     db,
+			Log:    t.Log,
+			Values: t.values,
+		}
+	}
+	if t.Positional {
+		db = &PositionalDB{DB: db}
+	}
+
+	return db
+}
+
+//
+// Get the encoder.
+func (t Table) encoder() Encoder {
+	if t.Encoder != nil {
+		return t.Encoder
+	}
+
+	return JSONEncoder{}
+}
+
+//
+// Get the decoder.
+func (t Table) decoder() Decoder {
+	if t.Decoder != nil {
+		return t.Decoder
+	}
 
-	return mt.Name()
+	return JSONDecoder{}
 }
 
 //
 // Validate the model.
+// All (field, PK) problems are reported. Returns the problem
+// when only one is found, else *ValidationError.
 func (t Table) Validate(fields []*Field) error {
+	problems := t.problems(fields)
+	switch len(problems) {
+	case 0:
+		return nil
+	case 1:
+		return problems[0]
+	default:
+		return &ValidationError{Errors: problems}
+	}
+}
+
+//
+// Validate the field values.
+func (t Table) ValidateValues(fields []*Field) error {
 	for _, f := range fields {
-		err := f.Validate()
+		err := f.ValidateValue()
 		if err != nil {
-			return liberr.Wrap(err)
+			return err
 		}
 	}
+
+	return nil
+}
+
+//
+// Validate a keyring is provided when
+// the model has encrypted fields.
+func (t Table) ValidateKeyring(fields []*Field) error {
+	if t.keyring != nil {
+		return nil
+	}
+	for _, f := range fields {
+		if f.Encrypted() {
+			return liberr.Wrap(KeyErr)
+		}
+	}
+
+	return nil
+}
+
+//
+// Get the table drop DDL.
+// Includes the map (child) and full-text search tables.
+// Indexes and triggers are dropped with the tables.
+func (t Table) DropDDL(model interface{}) ([]string, error) {
+	list := []string{}
+	fields, err := t.Fields(model)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	maps, err := t.MapFields(model)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
 	pk := t.PkField(fields)
 	if pk == nil {
-		return liberr.Wrap(MustHavePkErr)
+		return nil, liberr.Wrap(MustHavePkErr)
 	}
+	drop := func(table string) {
+		list = append(list, "DROP TABLE IF EXISTS "+table+";")
+	}
+	for _, f := range maps {
+		drop(t.mapTable(model, pk, f).Name())
+	}
+	if len(t.FtsFields(fields)) > 0 {
+		drop(t.Name(model) + "Fts")
+	}
+	drop(t.Name(model))
 
-	return nil
+	return list, nil
 }
 
 //
 // Get table and index create DDL.
+// Returns *SchemaError naming the model on failure.
 func (t Table) DDL(model interface{}) ([]string, error) {
+	list, err := t.ddl(model)
+	if err != nil {
+		sErr, cast := err.(*SchemaError)
+		if !cast {
+			sErr = &SchemaError{Err: err}
+		}
+		mt := reflect.TypeOf(model)
+		if mt != nil && mt.Kind() == reflect.Ptr {
+			mt = mt.Elem()
+		}
+		if mt != nil {
+			sErr.Model = mt.Name()
+		}
+		return nil, sErr
+	}
+
+	return list, nil
+}
+
+//
+// Build table and index create DDL.
+func (t Table) ddl(model interface{}) ([]string, error) {
 	list := []string{}
 	tpl := template.New("")
 	fields, err := t.Fields(model)
@@ -205,24 +779,72 @@ func (t Table) DDL(model interface{}) ([]string, error) {
 	if err != nil {
 		return nil, liberr.Wrap(err)
 	}
+	withoutRowID := false
+	if m, cast := model.(WithoutRowID); cast {
+		withoutRowID = m.WithoutRowID()
+	}
+	ftsFields := t.FtsFields(fields)
+	if withoutRowID {
+		if len(ftsFields) > 0 {
+			return nil, liberr.Wrap(FtsErr)
+		}
+		if t.PkField(fields).Value.Kind() != reflect.String {
+			return nil, liberr.Wrap(WithoutRowIDErr)
+		}
+		for _, f := range fields {
+			if f.Virtual() {
+				return nil, liberr.Wrap(WithoutRowIDErr)
+			}
+		}
+	}
 	// Table
 	tpl, err = tpl.Parse(TableDDL)
 	if err != nil {
 		return nil, liberr.Wrap(err)
 	}
 	constraints := t.Constraints(fields)
+	schema, name := t.split(model)
 	bfr := &bytes.Buffer{}
 	err = tpl.Execute(
 		bfr,
 		TmplData{
-			Table:       t.Name(model),
-			Fields:      t.RealFields(fields),
-			Constraints: constraints,
+			Schema:       schema,
+			Table:        name,
+			Fields:       t.RealFields(fields),
+			Constraints:  constraints,
+			WithoutRowID: withoutRowID,
 		})
 	if err != nil {
 		return nil, liberr.Wrap(err)
 	}
 	list = append(list, bfr.String())
+	// Map (child) tables.
+	mapDDL, err := t.mapDDL(model, fields)
+	if err != nil {
+		return nil, err
+	}
+	list = append(list, mapDDL...)
+	// Full-text search.
+	if len(ftsFields) > 0 {
+		for _, ddl := range []string{FtsDDL, FtsInsertDDL, FtsDeleteDDL, FtsUpdateDDL} {
+			tpl, err = tpl.Parse(ddl)
+			if err != nil {
+				return nil, liberr.Wrap(err)
+			}
+			bfr = &bytes.Buffer{}
+			err = tpl.Execute(
+				bfr,
+				TmplData{
+					Schema: schema,
+					Table:  name,
+					Fields: ftsFields,
+				})
+			if err != nil {
+				return nil, liberr.Wrap(err)
+			}
+			list = append(list, bfr.String())
+		}
+	}
 	// Index.
 	fields = t.KeyFields(fields)
 	if len(fields) > 0 {
@@ -234,33 +856,76 @@ func (t Table) DDL(model interface{}) ([]string, error) {
 		err = tpl.Execute(
 			bfr,
 			TmplData{
-				Table:  t.Name(model),
+				Schema: schema,
+				Table:  name,
 				Fields: t.RealFields(fields),
 			})
 		if err != nil {
 			return nil, liberr.Wrap(err)
 		}
 		list = append(list, bfr.String())
+		// Natural key (unique) index.
+		if t.UniqueKey(fields) {
+			tpl, err = tpl.Parse(KeyIndexDDL)
+			if err != nil {
+				return nil, liberr.Wrap(err)
+			}
+			bfr = &bytes.Buffer{}
+			err = tpl.Execute(
+				bfr,
+				TmplData{
+					Schema: schema,
+					Table:  name,
+					Fields: t.RealFields(fields),
+				})
+			if err != nil {
+				return nil, liberr.Wrap(err)
+			}
+			list = append(list, bfr.String())
+		}
+	}
+	// Triggers.
+	triggers, err := t.TriggerDDL(model)
+	if err != nil {
+		return nil, liberr.Wrap(err)
 	}
+	list = append(list, triggers...)
 
 	return list, nil
 }
 
 //
 // Insert the model in the DB.
-// Expects the primary key (PK) to be set.
+// Expects the primary key (PK) to be set. An (int)
+// autoincrement PK may be zero and is set to the value
+// assigned by the DB.
 func (t Table) Insert(model interface{}) error {
 	fields, err := t.Fields(model)
 	if err != nil {
 		return liberr.Wrap(err)
 	}
-	t.SetPk(fields)
+	err = t.SetPk(fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	err = t.ValidateValues(fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	err = t.ValidateKeyring(fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	t.touch(fields)
 	stmt, err := t.insertSQL(t.Name(model), fields)
 	if err != nil {
 		return liberr.Wrap(err)
 	}
-	params := t.Params(fields)
-	r, err := t.DB.Exec(stmt, params...)
+	params, err := t.Params(fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	r, err := t.db().Exec(stmt, params...)
 	if err != nil {
 		if sql3Err, cast := err.(sqlite3.Error); cast {
 			if sql3Err.Code == sqlite3.ErrConstraint {
@@ -273,25 +938,56 @@ func (t Table) Insert(model interface{}) error {
 	if err != nil {
 		return liberr.Wrap(err)
 	}
-
-	return nil
-}
+	if pk := t.PkField(fields); pk != nil && pk.assigned() {
+		id, err := r.LastInsertId()
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		pk.Value.SetInt(id)
+	}
+	err = t.putMaps(model, fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+
+	return nil
+}
 
 //
 // Update the model in the DB.
 // Expects the primary key (PK) or natural keys to be set.
+// Returns *WriteOnceError when a write-once field is changed.
 func (t Table) Update(model interface{}) error {
 	fields, err := t.Fields(model)
 	if err != nil {
 		return liberr.Wrap(err)
 	}
-	t.SetPk(fields)
+	err = t.SetPk(fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	err = t.ValidateValues(fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	err = t.ValidateKeyring(fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	t.touch(fields)
+	err = t.validateWriteOnce(model, fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
 	stmt, err := t.updateSQL(t.Name(model), fields)
 	if err != nil {
 		return liberr.Wrap(err)
 	}
-	params := t.Params(fields)
-	r, err := t.DB.Exec(stmt, params...)
+	params, err := t.Params(fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	r, err := t.db().Exec(stmt, params...)
 	if err != nil {
 		return liberr.Wrap(err)
 	}
@@ -302,6 +998,10 @@ func (t Table) Update(model interface{}) error {
 	if nRows == 0 {
 		return liberr.Wrap(NotFound)
 	}
+	err = t.putMaps(model, fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
 
 	return nil
 }
@@ -314,13 +1014,23 @@ func (t Table) Delete(model interface{}) error {
 	if err != nil {
 		return liberr.Wrap(err)
 	}
-	t.SetPk(fields)
+	err = t.SetPk(fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	err = t.deleteMaps(model, fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
 	stmt, err := t.deleteSQL(t.Name(model), fields)
 	if err != nil {
 		return liberr.Wrap(err)
 	}
-	params := t.Params(fields)
-	r, err := t.DB.Exec(stmt, params...)
+	params, err := t.Params(fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	r, err := t.db().Exec(stmt, params...)
 	if err != nil {
 		return liberr.Wrap(err)
 	}
@@ -340,18 +1050,71 @@ func (t Table) Delete(model interface{}) error {
 // Expects the primary key (PK) or natural keys to be set.
 // Fetch the row and populate the fields in the model.
 func (t Table) Get(model interface{}) error {
+	return t.GetDetail(model, DetailAll)
+}
+
+//
+// Get the model in the DB.
+// Expects the primary key (PK) or natural keys to be set.
+// Fetch the row and populate the fields in the model
+// matching the detail level (see: ListOptions.Detail).
+// The PK is always fetched. Omitted fields (including
+// encoded fields) are set to their zero values.
+func (t Table) GetDetail(model interface{}, detail int) error {
 	fields, err := t.Fields(model)
 	if err != nil {
 		return liberr.Wrap(err)
 	}
-	t.SetPk(fields)
-	stmt, err := t.getSQL(t.Name(model), fields)
+	err = t.ValidateKeyring(fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	err = t.SetPk(fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	options, err := t.intercepted(model, fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	selected := []*Field{}
+	for _, f := range fields {
+		if f.Pk() || f.MatchDetail(detail) {
+			selected = append(selected, f)
+		} else {
+			f.Value.Set(reflect.Zero(f.Value.Type()))
+		}
+	}
+	fields = selected
+	stmt, err := t.getSQL(t.Name(model), fields, options)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	params, err := t.Params(fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	if options != nil {
+		params = append(params, options.Params()...)
+	}
+	cursor, err := t.db().Query(stmt, params...)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	defer cursor.Close()
+	if !cursor.Next() {
+		err = cursor.Err()
+		if err == nil {
+			err = NotFound
+		}
+		return liberr.Wrap(err)
+	}
+	err = t.scan(cursor, fields)
 	if err != nil {
 		return liberr.Wrap(err)
 	}
-	params := t.Params(fields)
-	row := t.DB.QueryRow(stmt, params...)
-	err = t.scan(row, fields)
+	cursor.Close()
+	err = t.getMaps(model, detail)
 
 	return liberr.Wrap(err)
 }
@@ -376,32 +1139,35 @@ func (t Table) List(list interface{}, options ListOptions) error {
 	default:
 		return liberr.Wrap(MustBeSlicePtrErr)
 	}
+	if options.Filter != nil {
+		if reflect.TypeOf(options.Filter) != reflect.TypeOf(model) {
+			return liberr.Wrap(FilterTypeErr)
+		}
+	}
+	options = options.Clone()
+	t.detail(model, &options)
+	t.intercept(model, &options)
 	fields, err := t.Fields(model)
 	if err != nil {
 		return liberr.Wrap(err)
 	}
+	err = t.ValidateKeyring(fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
 	stmt, err := t.listSQL(t.Name(model), fields, &options)
 	if err != nil {
 		return liberr.Wrap(err)
 	}
 	params := options.Params()
-	cursor, err := t.DB.Query(stmt, params...)
+	cursor, err := t.db().Query(stmt, params...)
 	if err != nil {
 		return liberr.Wrap(err)
 	}
 	defer cursor.Close()
-	mList := reflect.MakeSlice(lt, 0, 0)
-	for cursor.Next() {
-		mt := reflect.TypeOf(model)
-		mPtr := reflect.New(mt.Elem())
-		mInt := mPtr.Interface()
-		mFields, _ := t.Fields(mInt)
-		options.fields = mFields
-		err = t.scan(cursor, options.Fields())
-		if err != nil {
-			return liberr.Wrap(err)
-		}
-		mList = reflect.Append(mList, mPtr.Elem())
+	mList, err := t.fetch(cursor, lt, &options)
+	if err != nil {
+		return liberr.Wrap(err)
 	}
 
 	lv.Set(mList)
@@ -410,186 +1176,1072 @@ func (t Table) List(list interface{}, options ListOptions) error {
 }
 
 //
-// Count the models in the DB.
-// Qualified by the model field values and list options.
-// Expects natural keys to be set.
-// Else, ALL models counted.
-func (t Table) Count(model interface{}, predicate Predicate) (int64, error) {
-	fields, err := t.Fields(model)
+// Fetch (scan) the rows into a new slice of models.
+// The `lt` is the slice type.
+// Returns ResultTooLargeErr when not paged and the number
+// of rows exceeds the maximum.
+func (t Table) fetch(cursor *sql.Rows, lt reflect.Type, options *ListOptions) (reflect.Value, error) {
+	mList := reflect.MakeSlice(lt, 0, options.capacity())
+	columns, err := cursor.Columns()
 	if err != nil {
-		return 0, liberr.Wrap(err)
+		return mList, liberr.Wrap(err)
 	}
-	options := ListOptions{Predicate: predicate}
-	stmt, err := t.countSQL(t.Name(model), fields, &options)
-	if err != nil {
-		return 0, liberr.Wrap(err)
+	selected := options.Fields()
+	aligned := t.align(columns, selected)
+	mt := lt.Elem()
+	for cursor.Next() {
+		if options.Page == nil &&
+			options.maxRows > 0 &&
+			mList.Len() == options.maxRows {
+			return reflect.MakeSlice(lt, 0, 0), liberr.Wrap(ResultTooLargeErr)
+		}
+		mv := reflect.New(mt).Elem()
+		t.bind(selected, mv)
+		err = t.scanAligned(cursor, aligned)
+		if err != nil {
+			return mList, liberr.Wrap(err)
+		}
+		mList = reflect.Append(mList, mv)
 	}
-	count := int64(0)
-	params := options.Params()
-	row := t.DB.QueryRow(stmt, params...)
+	err = cursor.Err()
 	if err != nil {
-		return 0, liberr.Wrap(err)
+		return mList, liberr.Wrap(err)
 	}
-	err = row.Scan(&count)
+	err = t.fetchMaps(mList, options.Detail)
 	if err != nil {
-		return 0, liberr.Wrap(err)
+		return mList, liberr.Wrap(err)
 	}
 
-	return count, nil
+	return mList, nil
 }
 
 //
-// Get the `Fields` for the model.
-func (t Table) Fields(model interface{}) ([]*Field, error) {
-	fields := []*Field{}
-	mt := reflect.TypeOf(model)
-	mv := reflect.ValueOf(model)
-	if mt.Kind() == reflect.Ptr {
-		mt = mt.Elem()
-		mv = mv.Elem()
-	} else {
-		return nil, liberr.Wrap(MustBePtrErr)
+// List the model in the DB and report whether more models
+// exist beyond the page. One model more than the page limit
+// is fetched (and trimmed) which is less expensive than a
+// count. Without a page, all models are listed and
+// hasMore is always false.
+func (t Table) ListMore(list interface{}, options ListOptions) (hasMore bool, err error) {
+	if options.Page == nil {
+		err = t.List(list, options)
+		if err != nil {
+			err = liberr.Wrap(err)
+		}
+		return
 	}
-	if mv.Kind() != reflect.Struct {
-		return nil, liberr.Wrap(MustBeObjectErr)
+	err = options.Page.Validate()
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
 	}
-	for i := 0; i < mt.NumField(); i++ {
-		ft := mt.Field(i)
-		fv := mv.Field(i)
-		if !fv.CanSet() {
-			continue
-		}
-		switch fv.Kind() {
-		case reflect.Struct:
-			sqlTag, found := ft.Tag.Lookup(Tag)
-			if !found {
-				nested, err := t.Fields(fv.Addr().Interface())
-				if err != nil {
-					return nil, nil
-				}
-				fields = append(fields, nested...)
-			} else {
-				fields = append(
-					fields,
-					&Field{
-						Tag:   sqlTag,
-						Name:  ft.Name,
-						Value: &fv,
-					})
-			}
-		case reflect.Slice,
-			reflect.Map,
-			reflect.String,
-			reflect.Bool,
-			reflect.Int,
-			reflect.Int8,
-			reflect.Int16,
-			reflect.Int32,
-			reflect.Int64:
-			sqlTag, found := ft.Tag.Lookup(Tag)
-			if !found {
-				continue
-			}
-			fields = append(
-				fields,
-				&Field{
-					Tag:   sqlTag,
-					Name:  ft.Name,
-					Value: &fv,
-				})
+	options = options.Clone()
+	limit := options.Page.Limit
+	if t.MaxPageLimit > 0 && limit > t.MaxPageLimit {
+		limit = t.MaxPageLimit
+	}
+	if limit == maxInt {
+		err = t.List(list, options)
+		if err != nil {
+			err = liberr.Wrap(err)
 		}
+		return
+	}
+	options.Page.Limit = limit + 1
+	t.MaxPageLimit = 0
+	err = t.List(list, options)
+	if err != nil {
+		err = liberr.Wrap(err)
+		return
+	}
+	lv := reflect.ValueOf(list).Elem()
+	if lv.Len() > limit {
+		lv.Set(lv.Slice(0, limit))
+		hasMore = true
 	}
 
-	return fields, nil
+	return
 }
 
 //
-// Get the `Fields` referenced as param in SQL.
-func (t Table) Params(fields []*Field) []interface{} {
-	list := []interface{}{}
-	for _, f := range fields {
-		if f.isParam {
-			p := sql.Named(f.Name, f.Pull())
-			list = append(list, p)
-		}
+// Search (full-text) the model in the DB.
+// The `list` must be: *[]Model. The `query` uses the
+// FTS5 query syntax and is matched against the `fts` fields.
+// Further qualified by the list options. Models are
+// ordered by rank (best match first) and then by the
+// list options sort criteria.
+func (t Table) Search(list interface{}, query string, options ListOptions) error {
+	if query == "" {
+		return liberr.Wrap(PredicateValueErr)
+	}
+	options.query = query
+	err := t.List(list, options)
+	if err != nil {
+		return liberr.Wrap(err)
 	}
 
-	return list
+	return nil
 }
 
 //
-// Set PK
-// Generated when not already set as sha1
-// of the (const) natural keys.
-func (t Table) SetPk(fields []*Field) error {
+// Get models in the DB by PK.
+// The `list` must be: *[]Model. Models are returned in the
+// order of the specified PKs. PKs not found are omitted.
+// The PKs are fetched in chunks (see: MaxChunk).
+func (t Table) GetMany(model interface{}, pks []string, list interface{}) error {
+	lt := reflect.TypeOf(list)
+	lv := reflect.ValueOf(list)
+	if lt.Kind() != reflect.Ptr || lt.Elem().Kind() != reflect.Slice {
+		return liberr.Wrap(MustBeSlicePtrErr)
+	}
+	lt = lt.Elem()
+	lv = lv.Elem()
+	if reflect.PtrTo(lt.Elem()) != reflect.TypeOf(model) {
+		return liberr.Wrap(MustBeSlicePtrErr)
+	}
+	fields, err := t.Fields(model)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
 	pk := t.PkField(fields)
 	if pk == nil {
-		return nil
+		return liberr.Wrap(MustHavePkErr)
 	}
-	switch pk.Value.Kind() {
-	case reflect.String:
-		if pk.Pull() != "" {
-			return nil
+	chunkSize := MaxChunk
+	if t.Limits.Variables > 0 && t.Limits.Variables < chunkSize {
+		chunkSize = t.Limits.Variables
+	}
+	found := map[string]reflect.Value{}
+	for start := 0; start < len(pks); start += chunkSize {
+		end := start + chunkSize
+		if end > len(pks) {
+			end = len(pks)
+		}
+		values := []interface{}{}
+		for _, v := range pks[start:end] {
+			values = append(values, v)
+		}
+		chunk := reflect.New(lt)
+		err = t.List(
+			chunk.Interface(),
+			ListOptions{
+				Detail:   DetailAll,
+				Capacity: len(values),
+				Predicate: &InPredicate{
+					SimplePredicate: SimplePredicate{Field: pk.Name},
+					Values:          values,
+				},
+			})
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		chunk = chunk.Elem()
+		for i := 0; i < chunk.Len(); i++ {
+			m := chunk.Index(i)
+			found[m.Addr().Interface().(Model).Pk()] = m
 		}
-	default:
-		return liberr.Wrap(GenPkTypeErr)
 	}
-	h := sha1.New()
-	for _, f := range t.KeyFields(fields) {
-		f.Pull()
-		switch f.Value.Kind() {
-		case reflect.String:
-			h.Write([]byte(f.string))
-		case reflect.Bool,
-			reflect.Int,
-			reflect.Int8,
-			reflect.Int16,
-			reflect.Int32,
-			reflect.Int64:
-			bfr := new(bytes.Buffer)
-			binary.Write(bfr, binary.BigEndian, f.int)
-			h.Write(bfr.Bytes())
+	mList := reflect.MakeSlice(lt, 0, len(found))
+	for _, key := range pks {
+		if m, matched := found[key]; matched {
+			mList = reflect.Append(mList, m)
+			delete(found, key)
 		}
 	}
-	pk.string = hex.EncodeToString(h.Sum(nil))
-	pk.Push()
+	lv.Set(mList)
+
 	return nil
 }
 
 //
-// Get the mutable `Fields` for the model.
-func (t Table) MutableFields(fields []*Field) []*Field {
-	list := []*Field{}
-	for _, f := range fields {
-		if f.Mutable() {
-			list = append(list, f)
-		}
+// List the model in the DB as maps keyed by column name.
+// Qualified by the list options.
+// Encoded (json) columns are decoded.
+// Returns ResultTooLargeErr when not paged and the number
+// of rows exceeds the maximum.
+func (t Table) ListMaps(model interface{}, options ListOptions) ([]map[string]interface{}, error) {
+	list := []map[string]interface{}{}
+	err := t.each(
+		model,
+		options,
+		func(fields []*Field) error {
+			if options.Page == nil &&
+				t.MaxListRows > 0 &&
+				len(list) == t.MaxListRows {
+				return liberr.Wrap(ResultTooLargeErr)
+			}
+			m := map[string]interface{}{}
+			for _, f := range fields {
+				if f.Encoded() {
+					var object interface{}
+					err := f.decode([]byte(f.string), &object)
+					if err != nil {
+						return liberr.Wrap(err)
+					}
+					m[f.Column] = object
+				} else {
+					m[f.Column] = f.Value.Interface()
+				}
+			}
+			list = append(list, m)
+			return nil
+		})
+	if err != nil {
+		return nil, liberr.Wrap(err)
 	}
 
-	return list
+	return list, nil
 }
 
 //
-// Get the natural key `Fields` for the model.
-func (t Table) KeyFields(fields []*Field) []*Field {
-	list := []*Field{}
-	for _, f := range fields {
-		if f.Key() {
-			list = append(list, f)
-		}
+// Export the model in the DB as a JSON array of objects
+// keyed by column name. Qualified (and sorted) by the list
+// options. All fields are exported unless the detail level
+// is specified (or the model default is declared). Rows
+// are written as they are fetched. Encoded
+// (json) columns are exported as nested objects. Enumerated
+// (int) columns are exported by name.
+func (t Table) ExportJSON(model interface{}, w io.Writer, options ListOptions) error {
+	t.detail(model, &options)
+	if options.Detail == 0 {
+		options.Detail = DetailAll
+	}
+	_, err := io.WriteString(w, "[\n")
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	n := 0
+	err = t.each(
+		model,
+		options,
+		func(fields []*Field) error {
+			m := map[string]interface{}{}
+			for _, f := range fields {
+				if f.Encoded() {
+					var object interface{}
+					err := f.decode([]byte(f.string), &object)
+					if err != nil {
+						return liberr.Wrap(err)
+					}
+					m[f.Column] = object
+					continue
+				}
+				m[f.Column] = f.Value.Interface()
+				if f.ordinal() {
+					if name, found := f.EnumName(); found {
+						m[f.Column] = name
+					}
+				}
+			}
+			if n > 0 {
+				_, err := io.WriteString(w, ",\n")
+				if err != nil {
+					return liberr.Wrap(err)
+				}
+			}
+			n++
+			b, err := json.Marshal(m)
+			if err != nil {
+				return liberr.Wrap(err)
+			}
+			_, err = w.Write(b)
+			return liberr.Wrap(err)
+		})
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	_, err = io.WriteString(w, "\n]\n")
+	if err != nil {
+		return liberr.Wrap(err)
 	}
 
-	return list
+	return nil
 }
 
 //
-// Get the non-virtual `Fields` for the model.
-func (t Table) RealFields(fields []*Field) []*Field {
-	list := []*Field{}
-	for _, f := range fields {
-		if !f.Virtual() {
-			list = append(list, f)
-		}
+// Export the model in the DB as CSV (RFC 4180).
+// Qualified by the list options. The header row contains
+// the column names. All fields are exported unless the detail
+// level is specified (or the model default is declared).
+// Encoded columns are exported as (json) strings. Booleans are
+// exported as: (true|false).
+func (t Table) ExportCSV(model interface{}, w io.Writer, options ListOptions) error {
+	t.detail(model, &options)
+	if options.Detail == 0 {
+		options.Detail = DetailAll
+	}
+	fields, err := t.Fields(model)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	header := []string{}
+	for _, f := range fields {
+		if f.MatchDetail(options.Detail) {
+			header = append(header, f.Column)
+		}
+	}
+	writer := csv.NewWriter(w)
+	err = writer.Write(header)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	err = t.each(
+		model,
+		options,
+		func(fields []*Field) error {
+			record := []string{}
+			for _, f := range fields {
+				text, err := f.text()
+				if err != nil {
+					return liberr.Wrap(err)
+				}
+				record = append(record, text)
+			}
+			return writer.Write(record)
+		})
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	writer.Flush()
+	err = writer.Error()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+
+	return nil
+}
+
+//
+// Iterate the model in the DB.
+// Qualified by the list options. The handler is called with
+// the selected fields of each (scanned) row.
+func (t Table) each(model interface{}, options ListOptions, handler func([]*Field) error) error {
+	options = options.Clone()
+	t.detail(model, &options)
+	t.intercept(model, &options)
+	if options.Filter != nil {
+		if reflect.TypeOf(options.Filter) != reflect.TypeOf(model) {
+			return liberr.Wrap(FilterTypeErr)
+		}
+	}
+	mt := reflect.TypeOf(model)
+	if mt.Kind() != reflect.Ptr {
+		return liberr.Wrap(MustBePtrErr)
+	}
+	fields, err := t.Fields(model)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	err = t.ValidateKeyring(fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	stmt, err := t.listSQL(t.Name(model), fields, &options)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	params := options.Params()
+	cursor, err := t.db().Query(stmt, params...)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	defer cursor.Close()
+	columns, err := cursor.Columns()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	selected := options.Fields()
+	aligned := t.align(columns, selected)
+	for cursor.Next() {
+		t.bind(selected, reflect.New(mt.Elem()).Elem())
+		err = t.scanAligned(cursor, aligned)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		err = handler(selected)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+	}
+	err = cursor.Err()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+
+	return nil
+}
+
+//
+// Set the model fields using values keyed by column name.
+// Virtual and generated (read-only) columns are ignored.
+func (t Table) SetColumns(model interface{}, values map[string]json.RawMessage) error {
+	fields, err := t.Fields(model)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	for _, f := range t.InsertFields(fields) {
+		v, found := values[f.Column]
+		if !found {
+			continue
+		}
+		if f.ordinal() {
+			name := ""
+			if json.Unmarshal(v, &name) == nil && f.setEnumName(name) {
+				continue
+			}
+		}
+		err = json.Unmarshal(v, f.Value.Addr().Interface())
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+	}
+
+	return nil
+}
+
+//
+// Set the model fields using (text) values keyed by
+// column name. See: ExportCSV().
+// Virtual and generated (read-only) columns are ignored.
+func (t Table) SetText(model interface{}, values map[string]string) error {
+	fields, err := t.Fields(model)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	for _, f := range t.InsertFields(fields) {
+		v, found := values[f.Column]
+		if !found {
+			continue
+		}
+		err = f.setText(v)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+	}
+
+	return nil
+}
+
+//
+// Count the models in the DB matching the predicate.
+// Fields referenced by the predicate are validated against
+// all of the model fields (regardless of detail level).
+// Returns PredicateRefErr when not found.
+// When the predicate is nil, ALL models are counted.
+func (t Table) Count(model interface{}, predicate Predicate) (int64, error) {
+	fields, err := t.Fields(model)
+	if err != nil {
+		return 0, liberr.Wrap(err)
+	}
+	options := ListOptions{Predicate: predicate}
+	t.intercept(model, &options)
+	stmt, err := t.countSQL(t.Name(model), fields, &options)
+	if err != nil {
+		return 0, liberr.Wrap(err)
+	}
+	count := int64(0)
+	row := t.db().QueryRow(stmt, options.Params()...)
+	err = row.Scan(&count)
+	if err != nil {
+		return 0, liberr.Wrap(err)
+	}
+
+	return count, nil
+}
+
+//
+// Get the number of (all) models in the DB.
+func (t Table) Len(model interface{}) (int64, error) {
+	return t.Count(model, nil)
+}
+
+//
+// Get whether the table has no models.
+// Stops at the first row rather than counting.
+func (t Table) IsEmpty(model interface{}) (bool, error) {
+	if reflect.TypeOf(model).Kind() != reflect.Ptr {
+		return false, liberr.Wrap(MustBePtrErr)
+	}
+	if t.Interceptor != nil {
+		n, err := t.Count(model, nil)
+		if err != nil {
+			return false, liberr.Wrap(err)
+		}
+		return n == 0, nil
+	}
+	found := 0
+	err := t.db().QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM " + t.Name(model) + ");").Scan(&found)
+	if err != nil {
+		return false, liberr.Wrap(err)
+	}
+
+	return found == 0, nil
+}
+
+//
+// Count models in the DB grouped by the value of
+// a single field. Qualified by the predicate.
+// Returns the count by (text) value.
+func (t Table) CountBy(model interface{}, field string, predicate Predicate) (map[string]int64, error) {
+	groups, err := t.CountByGroup(model, []string{field}, predicate)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	counts := map[string]int64{}
+	for _, g := range groups {
+		counts[g.Keys[0]] = g.Count
+	}
+
+	return counts, nil
+}
+
+//
+// Count models in the DB grouped by the values of
+// the specified fields. Qualified by the predicate.
+// Groups are ordered by the key values.
+func (t Table) CountByGroup(model interface{}, group []string, predicate Predicate) ([]GroupCount, error) {
+	return t.CountGroups(model, group, ListOptions{Predicate: predicate})
+}
+
+//
+// Count models in the DB grouped by the values of
+// the specified fields. The groups are qualified by
+// the options (predicate, filter) and the `Having`
+// predicate which may reference aggregates.
+// Example:
+//   CountGroups(
+//       &Pod{},
+//       []string{"Namespace"},
+//       ListOptions{Having: CountAll().Gt(100)})
+func (t Table) CountGroups(model interface{}, group []string, options ListOptions) ([]GroupCount, error) {
+	fields, err := t.Fields(model)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	if len(group) == 0 {
+		return nil, liberr.Wrap(GroupFieldErr)
+	}
+	groupFields := []*Field{}
+	for _, name := range group {
+		f, found := t.find(name, fields)
+		if !found || f.Encrypted() || f.Compressed() {
+			return nil, liberr.Wrap(GroupFieldErr)
+		}
+		groupFields = append(groupFields, f)
+	}
+	options = options.Clone()
+	options.grouped = true
+
+	return t.countGroups(model, fields, groupFields, &options, false)
+}
+
+//
+// Find duplicate natural keys.
+// Returns the natural key values (and count) of groups
+// of models sharing the same natural key. Duplicates exist
+// only when the natural key is not enforced unique.
+// See: `nonunique`.
+func (t Table) DuplicateKeys(model interface{}) ([]GroupCount, error) {
+	fields, err := t.Fields(model)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	keyFields := t.KeyFields(fields)
+	if len(keyFields) == 0 {
+		return nil, liberr.Wrap(GroupFieldErr)
+	}
+	for _, f := range keyFields {
+		if f.Compressed() {
+			return nil, liberr.Wrap(GroupFieldErr)
+		}
+	}
+	options := ListOptions{}
+
+	return t.countGroups(model, fields, keyFields, &options, true)
+}
+
+//
+// Count models grouped by the fields.
+// Only groups with duplicates (count > 1) when specified.
+func (t Table) countGroups(model interface{}, fields, groupFields []*Field, options *ListOptions, duplicates bool) ([]GroupCount, error) {
+	t.intercept(model, options)
+	stmt, err := t.countBySQL(t.Name(model), fields, groupFields, options, duplicates)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	cursor, err := t.db().Query(stmt, options.Params()...)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	defer cursor.Close()
+	list := []GroupCount{}
+	for cursor.Next() {
+		keys := make([]sql.NullString, len(groupFields))
+		ptrs := []interface{}{}
+		for i := range keys {
+			ptrs = append(ptrs, &keys[i])
+		}
+		g := GroupCount{}
+		ptrs = append(ptrs, &g.Count)
+		err = cursor.Scan(ptrs...)
+		if err != nil {
+			return nil, liberr.Wrap(err)
+		}
+		for _, key := range keys {
+			g.Keys = append(g.Keys, key.String)
+		}
+		list = append(list, g)
+	}
+	err = cursor.Err()
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+
+	return list, nil
+}
+
+//
+// List the distinct values of a field in the DB.
+// Qualified by the predicate. The values are ordered and
+// typed by the field (kind).
+func (t Table) DistinctValues(model interface{}, field string, predicate Predicate) ([]interface{}, error) {
+	if reflect.TypeOf(model).Kind() != reflect.Ptr {
+		return nil, liberr.Wrap(MustBePtrErr)
+	}
+	scratch := reflect.New(reflect.TypeOf(model).Elem()).Interface()
+	fields, err := t.Fields(scratch)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	f, found := t.find(field, fields)
+	if !found || f.Encrypted() || f.Compressed() {
+		return nil, liberr.Wrap(GroupFieldErr)
+	}
+	options := ListOptions{Predicate: predicate}
+	t.intercept(model, &options)
+	stmt, err := t.distinctSQL(t.Name(model), fields, f, &options)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	cursor, err := t.db().Query(stmt, options.Params()...)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	defer cursor.Close()
+	list := []interface{}{}
+	for cursor.Next() {
+		err = cursor.Scan(f.Ptr())
+		if err != nil {
+			return nil, liberr.Wrap(err)
+		}
+		err = f.Push()
+		if err != nil {
+			return nil, liberr.Wrap(err)
+		}
+		list = append(list, f.Value.Interface())
+	}
+	err = cursor.Err()
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+
+	return list, nil
+}
+
+//
+// Aggregate a numeric field of models in the DB.
+// The `function` is one of: (SUM, AVG, MIN, MAX).
+// Qualified by the predicate. Returns 0 when no models
+// are matched.
+func (t Table) Aggregate(model interface{}, function, field string, predicate Predicate) (float64, error) {
+	function = strings.ToUpper(function)
+	valid := false
+	for _, name := range Aggregates {
+		if function == name {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return 0, liberr.Wrap(AggregateErr)
+	}
+	fields, err := t.Fields(model)
+	if err != nil {
+		return 0, liberr.Wrap(err)
+	}
+	f, found := t.find(field, fields)
+	if !found || f.Custom() || f.Encrypted() {
+		return 0, liberr.Wrap(AggregateErr)
+	}
+	switch f.Value.Kind() {
+	case reflect.Int,
+		reflect.Int8,
+		reflect.Int16,
+		reflect.Int32,
+		reflect.Int64:
+	default:
+		return 0, liberr.Wrap(AggregateErr)
+	}
+	options := ListOptions{Predicate: predicate}
+	t.intercept(model, &options)
+	stmt, err := t.aggregateSQL(t.Name(model), fields, f, function, &options)
+	if err != nil {
+		return 0, liberr.Wrap(err)
+	}
+	value := sql.NullFloat64{}
+	err = t.db().QueryRow(stmt, options.Params()...).Scan(&value)
+	if err != nil {
+		return 0, liberr.Wrap(err)
+	}
+
+	return value.Float64, nil
+}
+
+//
+// Gather query planner statistics for the model table
+// and indexes. When `model` is nil, ALL tables are analyzed.
+func (t Table) Analyze(model interface{}) error {
+	stmt := "ANALYZE"
+	if model != nil {
+		stmt += " " + t.Name(model)
+	}
+	_, err := t.db().Exec(stmt)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+
+	return nil
+}
+
+//
+// Rebuild the indexes for the model table.
+func (t Table) Reindex(model interface{}) error {
+	_, err := t.db().Exec("REINDEX " + t.Name(model))
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+
+	return nil
+}
+
+//
+// Get the query planner statistics for the model table.
+// Empty until analyzed.
+func (t Table) PlannerStats(model interface{}) ([]PlannerStat, error) {
+	list := []PlannerStat{}
+	found := 0
+	row := t.db().QueryRow(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'sqlite_stat1'")
+	err := row.Scan(&found)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	if found == 0 {
+		return list, nil
+	}
+	cursor, err := t.db().Query(
+		"SELECT idx, stat FROM sqlite_stat1 WHERE tbl = :table",
+		sql.Named("table", t.Name(model)))
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	defer cursor.Close()
+	for cursor.Next() {
+		stat := PlannerStat{}
+		index := sql.NullString{}
+		err = cursor.Scan(&index, &stat.Stat)
+		if err != nil {
+			return nil, liberr.Wrap(err)
+		}
+		stat.Index = index.String
+		list = append(list, stat)
+	}
+
+	return list, nil
+}
+
+//
+// Describe the table for the model.
+// Table, column and FK names are those used in the DB.
+func (t Table) Describe(model interface{}) (*TableSchema, error) {
+	fields, err := t.Fields(model)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	mt := reflect.TypeOf(model).Elem()
+	schema := &TableSchema{
+		Name:  t.Name(model),
+		Model: mt.Name(),
+	}
+	for _, f := range fields {
+		column := ColumnSchema{
+			Name:       f.Column,
+			Field:      f.Name,
+			Type:       f.SqlType(),
+			Pk:         f.Pk(),
+			Key:        f.Key(),
+			Const:      f.Const(),
+			Virtual:    f.Virtual(),
+			Unique:     f.Unique(),
+			Enum:       f.Enum(),
+			Generated:  f.Generated(),
+			Compressed: f.Compressed(),
+			Encrypted:  f.Encrypted(),
+			Fts:        f.Fts(),
+			Detail:     f.Detail(),
+			Doc:        f.Doc(),
+		}
+		if fk := f.Fk(); fk != nil {
+			column.Fk = t.resolveFk(fk)
+		}
+		if collate, found := f.Collate(); found {
+			column.Collate = collate
+		}
+		schema.Columns = append(schema.Columns, column)
+	}
+
+	return schema, nil
+}
+
+//
+// Get the `Fields` for the model.
+func (t Table) Fields(model interface{}) ([]*Field, error) {
+	fields := []*Field{}
+	mt := reflect.TypeOf(model)
+	mv := reflect.ValueOf(model)
+	if mt.Kind() == reflect.Ptr {
+		mt = mt.Elem()
+		mv = mv.Elem()
+	} else {
+		return nil, liberr.Wrap(MustBePtrErr)
+	}
+	if mv.Kind() != reflect.Struct {
+		return nil, liberr.Wrap(MustBeObjectErr)
+	}
+	for i := 0; i < mt.NumField(); i++ {
+		ft := mt.Field(i)
+		fv := mv.Field(i)
+		if !fv.CanSet() {
+			continue
+		}
+		if custom(ft.Type) {
+			sqlTag, found := ft.Tag.Lookup(Tag)
+			if found {
+				fields = append(fields, t.field(ft, &fv, sqlTag))
+			}
+			continue
+		}
+		switch fv.Kind() {
+		case reflect.Struct:
+			sqlTag, found := ft.Tag.Lookup(Tag)
+			if !found {
+				nested, err := t.Fields(fv.Addr().Interface())
+				if err != nil {
+					return nil, liberr.Wrap(err)
+				}
+				for _, f := range nested {
+					f.index = append([]int{i}, f.index...)
+				}
+				fields = append(fields, nested...)
+			} else {
+				fields = append(fields, t.field(ft, &fv, sqlTag))
+			}
+		case reflect.Slice,
+			reflect.Map,
+			reflect.Interface,
+			reflect.String,
+			reflect.Bool,
+			reflect.Int,
+			reflect.Int8,
+			reflect.Int16,
+			reflect.Int32,
+			reflect.Int64:
+			sqlTag, found := ft.Tag.Lookup(Tag)
+			if !found {
+				continue
+			}
+			f := t.field(ft, &fv, sqlTag)
+			if fv.Kind() == reflect.Map && f.Map() {
+				continue
+			}
+			fields = append(fields, f)
+		}
+	}
+
+	return fields, nil
+}
+
+//
+// Build a `Field`.
+// Virtual fields reference columns managed internally
+// by the DB (example: rowid) and are not renamed.
+func (t Table) field(ft reflect.StructField, fv *reflect.Value, tag string) *Field {
+	f := &Field{
+		Tag:        tag,
+		Name:       ft.Name,
+		Column:     ft.Name,
+		Value:      fv,
+		index:      []int{ft.Index[0]},
+		keyring:    t.keyring,
+		encoder:    t.encoder(),
+		decoder:    t.decoder(),
+		strict:     t.StrictBool,
+		boolText:   t.BoolText,
+		transforms: t.Transforms,
+	}
+	if !f.Virtual() {
+		f.Column = t.namer().ColumnName(ft)
+	}
+	if f.RowID() {
+		f.Column = "rowid"
+	}
+
+	return f
+}
+
+//
+// Get the `Fields` referenced as param in SQL.
+// Returns *CodecError when a value cannot be encoded.
+func (t Table) Params(fields []*Field) ([]interface{}, error) {
+	list := []interface{}{}
+	for _, f := range fields {
+		if f.isParam {
+			v, err := f.Pull()
+			if err != nil {
+				return nil, liberr.Wrap(err)
+			}
+			list = append(list, sql.Named(f.Name, v))
+		}
+	}
+
+	return list, nil
+}
+
+//
+// Set PK
+// Generated when not already set as a hash
+// (default: sha1) of the (const) natural keys.
+// Only (str) PKs are generated; others are used as set.
+// Returns an error when a key cannot be encoded.
+func (t Table) SetPk(fields []*Field) error {
+	pk := t.PkField(fields)
+	if pk == nil {
+		return nil
+	}
+	if pk.Value.Kind() != reflect.String || pk.Value.String() != "" {
+		return nil
+	}
+	hasher := t.hasher()
+	h := hasher.New()
+	for _, f := range t.KeyFields(fields) {
+		if f.Custom() {
+			text, err := f.text()
+			if err != nil {
+				return liberr.Wrap(err)
+			}
+			h.Write([]byte(text))
+			continue
+		}
+		_, err := f.Pull()
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		switch f.Value.Kind() {
+		case reflect.String:
+			h.Write([]byte(f.string))
+		case reflect.Bool,
+			reflect.Int,
+			reflect.Int8,
+			reflect.Int16,
+			reflect.Int32,
+			reflect.Int64:
+			bfr := new(bytes.Buffer)
+			binary.Write(bfr, binary.BigEndian, f.int)
+			h.Write(bfr.Bytes())
+		}
+	}
+	pk.string = hasher.Encode(h.Sum(nil))
+	err := pk.Push()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	return nil
+}
+
+//
+// Get the mutable `Fields` for the model.
+func (t Table) MutableFields(fields []*Field) []*Field {
+	list := []*Field{}
+	for _, f := range fields {
+		if f.Mutable() {
+			list = append(list, f)
+		}
+	}
+
+	return list
+}
+
+//
+// Get the full-text searchable `Fields` for the model.
+func (t Table) FtsFields(fields []*Field) []*Field {
+	list := []*Field{}
+	for _, f := range fields {
+		if f.Fts() {
+			list = append(list, f)
+		}
+	}
+
+	return list
+}
+
+//
+// Get the natural key `Fields` for the model.
+func (t Table) KeyFields(fields []*Field) []*Field {
+	list := []*Field{}
+	for _, f := range fields {
+		if f.Key() {
+			list = append(list, f)
+		}
+	}
+
+	return list
+}
+
+//
+// Get whether the natural key is enforced unique.
+// Opt-out when any natural key field is tagged `nonunique`.
+func (t Table) UniqueKey(fields []*Field) bool {
+	for _, f := range t.KeyFields(fields) {
+		if f.hasOpt("nonunique") {
+			return false
+		}
+	}
+
+	return true
+}
+
+//
+// Get the non-virtual `Fields` for the model.
+func (t Table) RealFields(fields []*Field) []*Field {
+	list := []*Field{}
+	for _, f := range fields {
+		if !f.Virtual() {
+			list = append(list, f)
+		}
+	}
+
+	return list
+}
+
+//
+// Get the `Fields` included on insert.
+// Excludes virtual and generated fields.
+func (t Table) InsertFields(fields []*Field) []*Field {
+	list := []*Field{}
+	for _, f := range t.RealFields(fields) {
+		if f.Generated() == nil {
+			list = append(list, f)
+		}
 	}
 
 	return list
@@ -609,20 +2261,37 @@ func (t Table) PkField(fields []*Field) *Field {
 
 //
 // Get constraint DDL.
+// Unique constraints are ordered by group name and the
+// columns within each group are sorted so the DDL is stable.
 func (t Table) Constraints(fields []*Field) []string {
 	constraints := []string{}
 	unique := map[string][]string{}
 	for _, field := range fields {
+		if field.Virtual() {
+			continue
+		}
 		for _, name := range field.Unique() {
-			list, found := unique[name]
-			if found {
-				unique[name] = append(list, field.Name)
-			} else {
-				unique[name] = []string{field.Name}
+			list := unique[name]
+			found := false
+			for _, column := range list {
+				if column == field.Column {
+					found = true
+					break
+				}
+			}
+			if !found {
+				unique[name] = append(list, field.Column)
 			}
 		}
 	}
-	for _, list := range unique {
+	groups := []string{}
+	for name := range unique {
+		groups = append(groups, name)
+	}
+	sort.Strings(groups)
+	for _, name := range groups {
+		list := unique[name]
+		sort.Strings(list)
 		constraints = append(
 			constraints,
 			fmt.Sprintf(
@@ -634,26 +2303,180 @@ func (t Table) Constraints(fields []*Field) []string {
 		if fk == nil {
 			continue
 		}
-		constraints = append(constraints, fk.DDL(field))
+		constraints = append(constraints, t.resolveFk(fk).DDL(field))
+	}
+	for _, field := range fields {
+		if field.Virtual() || field.Generated() != nil {
+			continue
+		}
+		if field.strictBool() {
+			values := "0,1"
+			if field.BoolText() {
+				values = "'true','false'"
+			}
+			constraints = append(
+				constraints,
+				fmt.Sprintf(
+					"CHECK (%s IN (%s))",
+					field.Column,
+					values))
+		}
+	}
+	for _, field := range fields {
+		enum := field.Enum()
+		if len(enum) == 0 {
+			continue
+		}
+		values := []string{}
+		for i, v := range enum {
+			if field.ordinal() {
+				values = append(values, strconv.Itoa(i))
+				continue
+			}
+			values = append(values, "'"+strings.ReplaceAll(v, "'", "''")+"'")
+		}
+		constraints = append(
+			constraints,
+			fmt.Sprintf(
+				"CHECK (%s IN (%s))",
+				field.Column,
+				strings.Join(values, ",")))
 	}
 
 	return constraints
 }
 
+//
+// Resolve the table and column names referenced by
+// the FK using the naming strategy. The referenced
+// model type must be known, else the names are
+// used as specified.
+func (t Table) resolveFk(fk *FK) *FK {
+	mt, found := t.kinds[fk.Table]
+	if !found {
+		return fk
+	}
+	resolved := &FK{
+		Table:    t.namer().TableName(mt),
+		Field:    fk.Field,
+		Deferred: fk.Deferred,
+	}
+	if ft, found := mt.FieldByName(fk.Field); found {
+		resolved.Field = t.namer().ColumnName(ft)
+	}
+
+	return resolved
+}
+
+//
+// Apply the model default detail level when
+// not specified by the list options.
+func (t Table) detail(model interface{}, options *ListOptions) {
+	if options.Detail != 0 {
+		return
+	}
+	if m, cast := model.(DetailDefault); cast {
+		options.Detail = m.DetailDefault()
+	}
+}
+
+//
+// Find a field by (case insensitive) field or column name.
+func (t Table) find(name string, fields []*Field) (*Field, bool) {
+	name = strings.ToLower(name)
+	for _, f := range fields {
+		if name == strings.ToLower(f.Name) ||
+			name == strings.ToLower(f.Column) {
+			return f, true
+		}
+	}
+
+	return nil, false
+}
+
+//
+// Build aggregate SQL.
+func (t Table) aggregateSQL(table string, fields []*Field, field *Field, function string, options *ListOptions) (string, error) {
+	tpl := template.New("")
+	tpl, err := tpl.Parse(AggregateSQL)
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+	options.namer = t.namer()
+	options.limits = t.Limits
+	err = options.Build(table, fields)
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+	bfr := &bytes.Buffer{}
+	err = tpl.Execute(
+		bfr,
+		TmplData{
+			Table:    table,
+			Fields:   fields,
+			Options:  options,
+			Group:    []*Field{field},
+			Function: function,
+		})
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+
+	return bfr.String(), nil
+}
+
+//
+// Build distinct (field) values SQL.
+func (t Table) distinctSQL(table string, fields []*Field, field *Field, options *ListOptions) (string, error) {
+	tpl := template.New("")
+	tpl, err := tpl.Parse(DistinctSQL)
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+	options.namer = t.namer()
+	options.limits = t.Limits
+	err = options.Build(table, fields)
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+	bfr := &bytes.Buffer{}
+	err = tpl.Execute(
+		bfr,
+		TmplData{
+			Table:   table,
+			Fields:  fields,
+			Options: options,
+			Group:   []*Field{field},
+		})
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+
+	return bfr.String(), nil
+}
+
 //
 // Build model insert SQL.
+// A zero autoincrement PK is omitted and assigned by the DB.
 func (t Table) insertSQL(table string, fields []*Field) (string, error) {
 	tpl := template.New("")
 	tpl, err := tpl.Parse(InsertSQL)
 	if err != nil {
 		return "", liberr.Wrap(err)
 	}
+	inserted := []*Field{}
+	for _, f := range t.InsertFields(fields) {
+		if f.Pk() && f.assigned() {
+			continue
+		}
+		inserted = append(inserted, f)
+	}
 	bfr := &bytes.Buffer{}
 	err = tpl.Execute(
 		bfr,
 		TmplData{
 			Table:  table,
-			Fields: t.RealFields(fields),
+			Fields: inserted,
 		})
 	if err != nil {
 		return "", liberr.Wrap(err)
@@ -709,7 +2532,7 @@ func (t Table) deleteSQL(table string, fields []*Field) (string, error) {
 
 //
 // Build model get SQL.
-func (t Table) getSQL(table string, fields []*Field) (string, error) {
+func (t Table) getSQL(table string, fields []*Field, options *ListOptions) (string, error) {
 	tpl := template.New("")
 	tpl, err := tpl.Parse(GetSQL)
 	if err != nil {
@@ -719,9 +2542,10 @@ func (t Table) getSQL(table string, fields []*Field) (string, error) {
 	err = tpl.Execute(
 		bfr,
 		TmplData{
-			Table:  table,
-			Pk:     t.PkField(fields),
-			Fields: fields,
+			Table:   table,
+			Pk:      t.PkField(fields),
+			Fields:  fields,
+			Options: options,
 		})
 	if err != nil {
 		return "", liberr.Wrap(err)
@@ -738,6 +2562,10 @@ func (t Table) listSQL(table string, fields []*Field, options *ListOptions) (str
 	if err != nil {
 		return "", liberr.Wrap(err)
 	}
+	options.namer = t.namer()
+	options.maxLimit = t.MaxPageLimit
+	options.maxRows = t.MaxListRows
+	options.limits = t.Limits
 	err = options.Build(table, fields)
 	if err != nil {
 		return "", liberr.Wrap(err)
@@ -760,12 +2588,20 @@ func (t Table) listSQL(table string, fields []*Field, options *ListOptions) (str
 
 //
 // Build model count SQL.
+// Pagination, sorting and detail level do not affect
+// the count and are ignored.
 func (t Table) countSQL(table string, fields []*Field, options *ListOptions) (string, error) {
 	tpl := template.New("")
 	tpl, err := tpl.Parse(ListSQL)
 	if err != nil {
 		return "", liberr.Wrap(err)
 	}
+	options.Page = nil
+	options.Sort = nil
+	options.Nulls = nil
+	options.Detail = DetailAll
+	options.namer = t.namer()
+	options.limits = t.Limits
 	err = options.Build(table, fields)
 	if err != nil {
 		return "", liberr.Wrap(err)
@@ -787,23 +2623,143 @@ func (t Table) countSQL(table string, fields []*Field, options *ListOptions) (st
 	return bfr.String(), nil
 }
 
+//
+// Build model count (by group) SQL.
+func (t Table) countBySQL(table string, fields, group []*Field, options *ListOptions, duplicates bool) (string, error) {
+	tpl := template.New("")
+	tpl, err := tpl.Parse(CountBySQL)
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+	options.namer = t.namer()
+	options.limits = t.Limits
+	err = options.Build(table, fields)
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+	having := []string{}
+	if duplicates {
+		having = append(having, "COUNT(*) > 1")
+	}
+	if options.Having != nil {
+		having = append(having, options.Having.Expr())
+	}
+	bfr := &bytes.Buffer{}
+	err = tpl.Execute(
+		bfr,
+		TmplData{
+			Table:   table,
+			Fields:  fields,
+			Options: options,
+			Group:   group,
+			Having:  strings.Join(having, " AND "),
+		})
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+
+	return bfr.String(), nil
+}
+
 //
 // Scan the fetch row into the model.
 // The model fields are updated.
+// When the row provides the column names, the fields
+// are bound by column name. Columns without a matching field
+// are ignored and fields without a matching column are
+// not updated. Else, bound by position.
 func (t Table) scan(row Row, fields []*Field) error {
+	if cursor, cast := row.(interface{ Columns() ([]string, error) }); cast {
+		columns, err := cursor.Columns()
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		return t.scanColumns(row, columns, fields)
+	}
 	list := []interface{}{}
 	for _, f := range fields {
-		f.Pull()
+		f.string = ""
+		f.int = 0
 		list = append(list, f.Ptr())
 	}
 	err := row.Scan(list...)
-	if err == nil {
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	for _, f := range fields {
+		err = f.Push()
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+	}
+
+	return nil
+}
+
+//
+// Scan the fetched row into the model.
+// The fields are bound by column name.
+func (t Table) scanColumns(row Row, columns []string, fields []*Field) error {
+	return t.scanAligned(row, t.align(columns, fields))
+}
+
+//
+// Align the fields with the (fetched) columns.
+// The field is nil for columns not matched.
+func (t Table) align(columns []string, fields []*Field) []*Field {
+	aligned := make([]*Field, len(columns))
+	for i, column := range columns {
 		for _, f := range fields {
-			f.Push()
+			if strings.EqualFold(column, f.Column) {
+				aligned[i] = f
+				break
+			}
 		}
 	}
 
-	return liberr.Wrap(err)
+	return aligned
+}
+
+//
+// Scan the fetched row into the fields aligned
+// with the columns. See: align().
+func (t Table) scanAligned(row Row, aligned []*Field) error {
+	list := make([]interface{}, len(aligned))
+	for i, f := range aligned {
+		if f == nil {
+			list[i] = new(interface{})
+			continue
+		}
+		f.string = ""
+		f.int = 0
+		list[i] = f.Ptr()
+	}
+	err := row.Scan(list...)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	for _, f := range aligned {
+		if f == nil {
+			continue
+		}
+		err = f.Push()
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+	}
+
+	return nil
+}
+
+//
+// Bind the fields to the (struct) value of a model.
+// Supports scanning rows into new models using the
+// fields (layout) of another model of the same type.
+func (t Table) bind(fields []*Field, mv reflect.Value) {
+	for _, f := range fields {
+		fv := mv.FieldByIndex(f.index)
+		f.Value = &fv
+	}
 }
 
 //
@@ -814,19 +2770,76 @@ var UniqueRegex = regexp.MustCompile(`(unique)(\()(.+)(\))`)
 // Regex used for `fk:<table>(field)` tags.
 var FkRegex = regexp.MustCompile(`(fk):(.+)(\()(.+)(\))`)
 
+//
+// Regex used for `enum(a|b|c)` tags.
+var EnumRegex = regexp.MustCompile(`(enum)(\()(.*)(\))`)
+
+//
+// Regex used for `collate(name)` tags.
+var CollateRegex = regexp.MustCompile(`(collate)(\()(.+)(\))`)
+
+//
+// Regex used for `generated(expr, stored|virtual)` tags.
+var GeneratedRegex = regexp.MustCompile(`(?i)^(generated)(\()(.+),\s*(stored|virtual)\s*(\))$`)
+
+//
+// Regex used for `dN` (detail level) tags.
+var DetailRegex = regexp.MustCompile(`^d[0-9]+$`)
+
+//
+// Regex used for `doc(description)` tags.
+var DocRegex = regexp.MustCompile(`(?s)^(doc)(\()(.*)(\))$`)
+
+//
+// Supported collations.
+var Collations = []string{
+	"BINARY",
+	"NOCASE",
+	"RTRIM",
+}
+
 //
 // Model (struct) Field
 // Tags:
 //   `sql:"pk"`
 //       The primary key.
+//   `sql:"autoincrement"`
+//       The (int) PK is AUTOINCREMENT and assigned on insert.
 //   `sql:"key"`
 //       The field is part of the natural key.
+//       The natural key is enforced unique.
+//   `sql:"nonunique"`
+//       The natural key is not enforced unique.
 //   `sql:"fk:T(F)"`
 //       Foreign key `T` = model type, `F` = model field.
+//   `sql:"fk:T(F,deferred)"`
+//       Foreign key checked on transaction commit.
 //   `sql:"unique(G)"`
 //       Unique index. `G` = unique-together fields.
 //   `sql:"const"`
 //       The field is immutable and not included on update.
+//   `sql:"writeonce"`
+//       The field is set on insert. Update returns
+//       *WriteOnceError when the value is changed.
+//   `sql:"rowid"`
+//       The (int64, virtual) field carries the SQLite rowid.
+//   `sql:"collate(C)"`
+//       Column collation `C` = (binary|nocase|rtrim).
+//   `sql:"generated(E, M)"`
+//       Generated column. `E` = expression, `M` = (stored|virtual).
+//   `sql:"enum(A|B|C)"`
+//       The value must be one of the enumerated values.
+//       An (int) value is the ordinal (0=A,1=B,2=C).
+//   `sql:"doc(D)"`
+//       Column description `D`. See: Table.Describe().
+//   `sql:"encrypt"`
+//       The (str, encoded) value is encrypted using the keyring.
+//   `sql:"compress"`
+//       The encoded value is compressed (gzip).
+//   `sql:"transform(A|B)"`
+//       The (str) value is transformed. See: Transforms.
+// Fields with types implementing sql.Scanner and
+// driver.Valuer are converted using those interfaces.
 //
 type Field struct {
 	// reflect.Value of the field.
@@ -835,17 +2848,51 @@ type Field struct {
 	Tag string
 	// Field name.
 	Name string
+	// Column name.
+	Column string
 	// Staging (string) values.
 	string string
 	// Staging (int) values.
 	int int64
 	// Referenced as a parameter.
 	isParam bool
+	// Index (path) of the field within the model.
+	index []int
+	// Keyring used when encrypted.
+	keyring *Keyring
+	// Encoder used when encoded.
+	encoder Encoder
+	// Decoder used when encoded.
+	decoder Decoder
+	// Strict (bool) column.
+	strict bool
+	// Bool stored as TEXT.
+	boolText bool
+	// Registered transforms.
+	transforms map[string]Transform
 }
 
 //
 // Validate.
 func (f *Field) Validate() error {
+	err := f.validateTransforms()
+	if err != nil {
+		return err
+	}
+	err = f.validateRowID()
+	if err != nil {
+		return err
+	}
+	err = f.validateAutoIncrement()
+	if err != nil {
+		return err
+	}
+	if f.Custom() {
+		if f.Pk() || f.Encrypted() || f.Compressed() || f.hasEnum() {
+			return liberr.Wrap(CustomErr)
+		}
+		return nil
+	}
 	switch f.Value.Kind() {
 	case reflect.String,
 		reflect.Int,
@@ -858,61 +2905,332 @@ func (f *Field) Validate() error {
 			return liberr.Wrap(PkTypeErr)
 		}
 	}
+	if f.Generated() != nil {
+		if f.Pk() || f.Key() {
+			return liberr.Wrap(GeneratedErr)
+		}
+	}
+	if f.Key() && f.Virtual() {
+		return liberr.Wrap(MutableKeyErr)
+	}
+	if f.hasOpt("text") && f.Value.Kind() != reflect.Bool {
+		return liberr.Wrap(BoolTextErr)
+	}
+	if f.Map() {
+		return liberr.Wrap(MapErr)
+	}
+	if f.Fts() {
+		if f.Value.Kind() != reflect.String || f.Encrypted() {
+			return liberr.Wrap(FtsErr)
+		}
+	}
+	levels := 0
+	for _, opt := range f.options() {
+		if DetailRegex.MatchString(opt) {
+			n, _ := strconv.Atoi(opt[1:])
+			if n > DetailMax {
+				return liberr.Wrap(DetailErr)
+			}
+			levels++
+		}
+	}
+	if levels > 1 {
+		return liberr.Wrap(DetailErr)
+	}
+	if f.hasEnum() {
+		if (f.Value.Kind() != reflect.String && !f.ordinal()) || len(f.Enum()) == 0 {
+			return liberr.Wrap(EnumErr)
+		}
+	}
+	if f.Compressed() && !f.Encoded() {
+		return liberr.Wrap(CompressErr)
+	}
+	if f.Encrypted() {
+		if f.Value.Kind() != reflect.String && !f.Encoded() {
+			return liberr.Wrap(EncryptErr)
+		}
+		if f.Pk() || f.Key() || len(f.Unique()) > 0 || f.Fk() != nil {
+			return liberr.Wrap(EncryptErr)
+		}
+	}
+	if collate, found := f.Collate(); found {
+		if f.Value.Kind() != reflect.String {
+			return liberr.Wrap(CollationErr)
+		}
+		valid := false
+		for _, name := range Collations {
+			if collate == name {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return liberr.Wrap(CollationErr)
+		}
+	}
+
+	return nil
+}
+
+//
+// Validate the field value.
+// Returns *EnumError when the value is not enumerated.
+func (f *Field) ValidateValue() error {
+	enum := f.Enum()
+	if len(enum) == 0 {
+		return nil
+	}
+	if f.ordinal() {
+		n := f.Value.Int()
+		if n >= 0 && n < int64(len(enum)) {
+			return nil
+		}
+		return liberr.Wrap(
+			&EnumError{
+				Field: f.Name,
+				Value: strconv.FormatInt(n, 10),
+				Enum:  enum,
+			})
+	}
+	value := f.Value.String()
+	for _, v := range enum {
+		if value == v {
+			return nil
+		}
+	}
+
+	return liberr.Wrap(
+		&EnumError{
+			Field: f.Name,
+			Value: value,
+			Enum:  enum,
+		})
+}
+
+//
+// Pull from model.
+// Populate the appropriate `staging` field using the
+// model field value. Compressed and encrypted when specified.
+// Returns *CodecError when the value cannot be encoded,
+// compressed or encrypted.
+func (f *Field) Pull() (interface{}, error) {
+	v, err := f.pull()
+	if err != nil {
+		return nil, err
+	}
+	if f.Compressed() {
+		compressed, err := compress(f.string)
+		if err != nil {
+			return nil, liberr.Wrap(
+				&CodecError{
+					Field: f.Name,
+					Err:   err,
+				})
+		}
+		f.string = compressed
+		v = []byte(f.string)
+	}
+	if f.Encrypted() && f.keyring != nil {
+		encrypted, err := f.keyring.Encrypt(f.string)
+		if err != nil {
+			return nil, liberr.Wrap(
+				&CodecError{
+					Field: f.Name,
+					Err:   err,
+				})
+		}
+		f.string = encrypted
+		v = f.string
+	}
 
-	return nil
+	return v, nil
 }
 
 //
-// Pull from model.
 // Populate the appropriate `staging` field using the
-// model field value.
-func (f *Field) Pull() interface{} {
+// (plain) model field value.
+func (f *Field) pull() (interface{}, error) {
+	if f.Custom() {
+		v, err := f.valuer().Value()
+		if err != nil {
+			return nil, liberr.Wrap(err)
+		}
+		return v, nil
+	}
 	switch f.Value.Kind() {
 	case reflect.Struct:
 		object := f.Value.Interface()
-		b, err := json.Marshal(&object)
-		if err == nil {
-			f.string = string(b)
+		err := f.encodeTo(&object)
+		if err != nil {
+			return nil, err
 		}
-		return f.string
+		return f.string, nil
 	case reflect.Slice:
 		if !f.Value.IsNil() {
 			object := f.Value.Interface()
-			b, err := json.Marshal(&object)
-			if err == nil {
-				f.string = string(b)
+			err := f.encodeTo(&object)
+			if err != nil {
+				return nil, err
 			}
 		} else {
 			f.string = "[]"
 		}
-		return f.string
+		return f.string, nil
 	case reflect.Map:
 		if !f.Value.IsNil() {
 			object := f.Value.Interface()
-			b, err := json.Marshal(&object)
-			if err == nil {
-				f.string = string(b)
+			err := f.encodeTo(&object)
+			if err != nil {
+				return nil, err
 			}
 		} else {
 			f.string = "{}"
 		}
-		return f.string
+		return f.string, nil
+	case reflect.Interface:
+		err := f.encodeTyped()
+		if err != nil {
+			return nil, err
+		}
+		return f.string, nil
 	case reflect.String:
+		if len(f.Transforms()) > 0 {
+			f.Value.SetString(f.transform(f.Value.String()))
+		}
 		f.string = f.Value.String()
-		return f.string
+		return f.string, nil
 	case reflect.Bool:
 		b := f.Value.Bool()
 		if b {
 			f.int = 1
 		}
-		return f.int
+		if f.BoolText() {
+			f.string = strconv.FormatBool(b)
+			return f.string, nil
+		}
+		return f.int, nil
 	case reflect.Int,
 		reflect.Int8,
 		reflect.Int16,
 		reflect.Int32,
 		reflect.Int64:
 		f.int = f.Value.Int()
-		return f.int
+		return f.int, nil
+	}
+
+	return nil, nil
+}
+
+//
+// Encode the object into the `staging` field.
+// Returns *CodecError when the object cannot be encoded.
+func (f *Field) encodeTo(object interface{}) error {
+	b, err := f.encode(object)
+	if err != nil {
+		return liberr.Wrap(
+			&CodecError{
+				Field: f.Name,
+				Err:   err,
+			})
+	}
+	f.string = string(b)
+
+	return nil
+}
+
+//
+// Text representation of the model field value.
+// Encoded fields are represented as json.
+func (f *Field) text() (string, error) {
+	v, err := f.pull()
+	if err != nil {
+		return "", err
+	}
+	if f.Custom() {
+		switch v := v.(type) {
+		case nil:
+			return "", nil
+		case string:
+			return v, nil
+		case []byte:
+			return string(v), nil
+		default:
+			return fmt.Sprint(v), nil
+		}
+	}
+	switch f.Value.Kind() {
+	case reflect.Struct,
+		reflect.Slice,
+		reflect.Map,
+		reflect.Interface:
+		return f.string, nil
+	case reflect.String:
+		return f.Value.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(f.Value.Bool()), nil
+	case reflect.Int,
+		reflect.Int8,
+		reflect.Int16,
+		reflect.Int32,
+		reflect.Int64:
+		if name, found := f.EnumName(); found {
+			return name, nil
+		}
+		return strconv.FormatInt(f.Value.Int(), 10), nil
+	}
+
+	return "", nil
+}
+
+//
+// Set the model field value using the text representation.
+// See: text().
+func (f *Field) setText(s string) error {
+	if f.Custom() {
+		err := f.Value.Addr().Interface().(sql.Scanner).Scan(s)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		return nil
+	}
+	switch f.Value.Kind() {
+	case reflect.Struct,
+		reflect.Slice,
+		reflect.Map:
+		if len(s) == 0 {
+			break
+		}
+		err := f.decode([]byte(s), f.Value.Addr().Interface())
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+	case reflect.Interface:
+		f.string = s
+		err := f.decodeTyped()
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+	case reflect.String:
+		f.Value.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		f.Value.SetBool(b)
+	case reflect.Int,
+		reflect.Int8,
+		reflect.Int16,
+		reflect.Int32,
+		reflect.Int64:
+		if f.setEnumName(s) {
+			break
+		}
+		n, err := strconv.ParseInt(s, 10, f.Value.Type().Bits())
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		f.Value.SetInt(n)
 	}
 
 	return nil
@@ -921,6 +3239,12 @@ func (f *Field) Pull() interface{} {
 //
 // Pointer used for Scan().
 func (f *Field) Ptr() interface{} {
+	if f.Custom() {
+		return f.Value.Addr().Interface()
+	}
+	if f.BoolText() {
+		return &f.string
+	}
 	switch f.Value.Kind() {
 	case reflect.Bool,
 		reflect.Int,
@@ -937,7 +3261,36 @@ func (f *Field) Ptr() interface{} {
 //
 // Push to the model.
 // Set the model field value using the `staging` field.
-func (f *Field) Push() {
+// Decrypted and decompressed when specified.
+// Returns *CodecError when the encoded value cannot be decoded,
+// decompressed or decrypted (DecryptErr). An empty (NULL) value
+// is not decrypted.
+func (f *Field) Push() error {
+	if f.Encrypted() && f.keyring != nil && f.string != "" {
+		plain, err := f.keyring.Decrypt(f.string)
+		if err != nil {
+			return liberr.Wrap(
+				&CodecError{
+					Field: f.Name,
+					Err:   err,
+				})
+		}
+		f.string = plain
+	}
+	if f.Compressed() {
+		plain, err := decompress(f.string)
+		if err != nil {
+			return liberr.Wrap(
+				&CodecError{
+					Field: f.Name,
+					Err:   err,
+				})
+		}
+		f.string = plain
+	}
+	if f.Custom() {
+		return nil
+	}
 	switch f.Value.Kind() {
 	case reflect.Struct:
 		if len(f.string) == 0 {
@@ -945,11 +3298,16 @@ func (f *Field) Push() {
 		}
 		tv := reflect.New(f.Value.Type())
 		object := tv.Interface()
-		err := json.Unmarshal([]byte(f.string), &object)
-		if err == nil {
-			tv = reflect.ValueOf(object)
-			f.Value.Set(tv.Elem())
+		err := f.decode([]byte(f.string), &object)
+		if err != nil {
+			return liberr.Wrap(
+				&CodecError{
+					Field: f.Name,
+					Err:   err,
+				})
 		}
+		tv = reflect.ValueOf(object)
+		f.Value.Set(tv.Elem())
 	case reflect.Slice,
 		reflect.Map:
 		if len(f.string) == 0 {
@@ -957,17 +3315,26 @@ func (f *Field) Push() {
 		}
 		tv := reflect.New(f.Value.Type())
 		object := tv.Interface()
-		err := json.Unmarshal([]byte(f.string), object)
-		if err == nil {
-			tv = reflect.ValueOf(object)
-			tv = reflect.Indirect(tv)
-			f.Value.Set(tv)
+		err := f.decode([]byte(f.string), object)
+		if err != nil {
+			return liberr.Wrap(
+				&CodecError{
+					Field: f.Name,
+					Err:   err,
+				})
 		}
+		tv = reflect.ValueOf(object)
+		tv = reflect.Indirect(tv)
+		f.Value.Set(tv)
+	case reflect.Interface:
+		return f.decodeTyped()
 	case reflect.String:
-		f.Value.SetString(f.string)
+		f.Value.SetString(f.transform(f.string))
 	case reflect.Bool:
 		b := false
-		if f.int != 0 {
+		if f.BoolText() {
+			b, _ = strconv.ParseBool(f.string)
+		} else if f.int != 0 {
 			b = true
 		}
 		f.Value.SetBool(b)
@@ -978,100 +3345,390 @@ func (f *Field) Push() {
 		reflect.Int64:
 		f.Value.SetInt(f.int)
 	}
+
+	return nil
+}
+
+//
+// Column DDL.
+func (f *Field) DDL() string {
+	part := []string{
+		f.Column,    // name
+		f.SqlType(), // type
+		"",          // constraint
+	}
+	if f.Pk() {
+		part[2] = "PRIMARY KEY"
+		if f.AutoIncrement() {
+			part[2] += " AUTOINCREMENT"
+		}
+	} else if !f.Custom() {
+		part[2] = "NOT NULL"
+	}
+	if generated := f.Generated(); generated != nil {
+		part[2] = generated.DDL()
+	} else if f.strictBool() {
+		if f.BoolText() {
+			part = append(part, "DEFAULT 'false'")
+		} else {
+			part = append(part, "DEFAULT 0")
+		}
+	}
+	if collate, found := f.Collate(); found {
+		part = append(part, "COLLATE", collate)
+	}
+
+	return strings.Join(part, " ")
+}
+
+//
+// Get as SQL param.
+func (f *Field) Param() string {
+	f.isParam = true
+	return ":" + f.Name
+}
+
+//
+// Column (SQL) type.
+// Custom fields are typed by the value (reported by the
+// zero value) except for the known nullable types.
+// See: NullTypes.
+func (f *Field) SqlType() string {
+	if f.Custom() {
+		if sqlType, found := NullTypes[f.Value.Type()]; found {
+			return sqlType
+		}
+		v, _ := f.valuer().Value()
+		switch v.(type) {
+		case int64, bool:
+			return "INTEGER"
+		case float64:
+			return "REAL"
+		default:
+			return "TEXT"
+		}
+	}
+	if f.BoolText() {
+		return "TEXT"
+	}
+	switch f.Value.Kind() {
+	case reflect.Bool,
+		reflect.Int,
+		reflect.Int8,
+		reflect.Int16,
+		reflect.Int32,
+		reflect.Int64:
+		return "INTEGER"
+	}
+	if f.Compressed() && !f.Encrypted() {
+		return "BLOB"
+	}
+
+	return "TEXT"
+}
+
+//
+// Get whether field is the primary key.
+func (f *Field) Pk() bool {
+	return f.hasOpt("pk")
+}
+
+//
+// Get whether field is mutable.
+// Only mutable fields will be updated.
+func (f *Field) Mutable() bool {
+	if f.Pk() || f.Key() || f.Virtual() || f.Generated() != nil {
+		return false
+	}
+
+	return !f.Const() && !f.WriteOnce()
+}
+
+//
+// Get whether field is const.
+// Natural keys are (implicitly) const because the
+// generated PK is derived from them.
+func (f *Field) Const() bool {
+	return f.Key() || f.hasOpt("const")
+}
+
+//
+// Get whether field is a natural key.
+func (f *Field) Key() bool {
+	return f.hasOpt("key")
+}
+
+//
+// Get whether the field is a strict bool column.
+func (f *Field) strictBool() bool {
+	return f.strict &&
+		!f.Custom() &&
+		!f.Pk() &&
+		f.Value.Kind() == reflect.Bool
+}
+
+//
+// Get whether field is virtual.
+// A `virtual` field is read-only and managed
+// internally in the DB.
+func (f *Field) Virtual() bool {
+	return f.hasOpt("virtual") || f.RowID()
+}
+
+//
+// Get whether the field is unique.
+func (f *Field) Unique() []string {
+	list := []string{}
+	for _, opt := range f.options() {
+		m := UniqueRegex.FindStringSubmatch(opt)
+		if m != nil && len(m) == 5 {
+			list = append(list, strings.TrimSpace(m[3]))
+		}
+	}
+
+	return list
+}
+
+//
+// Get whether the field is a generated column.
+// A `generated` field is read-only and the value is
+// computed by the DB using the expression.
+func (f *Field) Generated() *Generated {
+	for _, opt := range f.options() {
+		m := GeneratedRegex.FindStringSubmatch(opt)
+		if m != nil && len(m) == 6 {
+			return &Generated{
+				Expr:   strings.TrimSpace(m[3]),
+				Stored: strings.ToLower(m[4]) == "stored",
+			}
+		}
+	}
+
+	return nil
+}
+
+//
+// Get the enumerated values.
+func (f *Field) Enum() (list []string) {
+	for _, opt := range f.options() {
+		m := EnumRegex.FindStringSubmatch(opt)
+		if m != nil && len(m) == 5 {
+			for _, v := range strings.Split(m[3], "|") {
+				v = strings.TrimSpace(v)
+				if v != "" {
+					list = append(list, v)
+				}
+			}
+			return
+		}
+	}
+
+	return
+}
+
+//
+// Get whether the field is an (int) enum stored as
+// the ordinal (index) of the enumerated value.
+func (f *Field) ordinal() bool {
+	if f.Custom() || !f.hasEnum() {
+		return false
+	}
+	switch f.Value.Kind() {
+	case reflect.Int,
+		reflect.Int8,
+		reflect.Int16,
+		reflect.Int32,
+		reflect.Int64:
+		return true
+	}
+
+	return false
+}
+
+//
+// Get the enumerated (name) value of an ordinal field.
+// Returns false when the value is not enumerated.
+func (f *Field) EnumName() (string, bool) {
+	enum := f.Enum()
+	if !f.ordinal() {
+		return "", false
+	}
+	n := f.Value.Int()
+	if n < 0 || n >= int64(len(enum)) {
+		return "", false
+	}
+
+	return enum[n], true
+}
+
+//
+// Set the value of an ordinal field by enumerated name.
+// Returns false when the name is not enumerated.
+func (f *Field) setEnumName(name string) bool {
+	if !f.ordinal() {
+		return false
+	}
+	for i, v := range f.Enum() {
+		if v == name {
+			f.Value.SetInt(int64(i))
+			return true
+		}
+	}
+
+	return false
+}
+
+//
+// Get whether the field has an `enum` option.
+func (f *Field) hasEnum() bool {
+	for _, opt := range f.options() {
+		if EnumRegex.MatchString(opt) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//
+// Get whether the field type is custom.
+// Custom types implement sql.Scanner and driver.Valuer
+// which are used instead of the built-in conversion (and
+// json encoding).
+func (f *Field) Custom() bool {
+	return custom(f.Value.Type())
 }
 
 //
-// Column DDL.
-func (f *Field) DDL() string {
-	part := []string{
-		f.Name, // name
-		"",     // type
-		"",     // constraint
-	}
-	switch f.Value.Kind() {
-	case reflect.Bool,
-		reflect.Int,
-		reflect.Int8,
-		reflect.Int16,
-		reflect.Int32,
-		reflect.Int64:
-		part[1] = "INTEGER"
-	default:
-		part[1] = "TEXT"
-	}
-	if f.Pk() {
-		part[2] = "PRIMARY KEY"
-	} else {
-		part[2] = "NOT NULL"
+// Get the (custom) field valuer.
+func (f *Field) valuer() driver.Valuer {
+	if valuer, cast := f.Value.Interface().(driver.Valuer); cast {
+		return valuer
 	}
 
-	return strings.Join(part, " ")
+	return f.Value.Addr().Interface().(driver.Valuer)
 }
 
 //
-// Get as SQL param.
-func (f *Field) Param() string {
-	f.isParam = true
-	return ":" + f.Name
+// Get whether the field is compressed.
+func (f *Field) Compressed() bool {
+	return f.hasOpt("compress")
 }
 
 //
-// Get whether field is the primary key.
-func (f *Field) Pk() bool {
-	return f.hasOpt("pk")
+// Get whether the field is encrypted.
+func (f *Field) Encrypted() bool {
+	return f.hasOpt("encrypt")
 }
 
 //
-// Get whether field is mutable.
-// Only mutable fields will be updated.
-func (f *Field) Mutable() bool {
-	if f.Pk() || f.Key() || f.Virtual() {
+// Get whether the field may be compared with another
+// field. Int (and bool) fields are comparable; str fields
+// are comparable; custom fields are comparable with fields
+// of the same type. Encoded fields are not comparable.
+func (f *Field) Comparable(other *Field) bool {
+	if f.Custom() || other.Custom() {
+		return f.Value.Type() == other.Value.Type()
+	}
+	family := func(f *Field) int {
+		if f.Encoded() {
+			return 0
+		}
+		if f.BoolText() {
+			return 3
+		}
+		switch f.Value.Kind() {
+		case reflect.String:
+			return 1
+		case reflect.Bool,
+			reflect.Int,
+			reflect.Int8,
+			reflect.Int16,
+			reflect.Int32,
+			reflect.Int64:
+			return 2
+		}
+		return 0
+	}
+
+	return family(f) != 0 && family(f) == family(other)
+}
+
+//
+// Get whether the field is stored in a (child) key/value
+// table rather than json encoded.
+func (f *Field) Map() bool {
+	return f.hasOpt("map")
+}
+
+//
+// Get whether the (bool) field is stored as TEXT.
+// Set by the `text` tag or Table.BoolText.
+func (f *Field) BoolText() bool {
+	if f.Custom() || f.Value.Kind() != reflect.Bool {
 		return false
 	}
 
-	return !f.hasOpt("const")
+	return f.boolText || f.hasOpt("text")
 }
 
 //
-// Get whether field is a natural key.
-func (f *Field) Key() bool {
-	return f.hasOpt("key")
+// Get whether the field is touched (last seen).
+func (f *Field) Touch() bool {
+	return f.hasOpt("touch")
 }
 
 //
-// Get whether field is virtual.
-// A `virtual` field is read-only and managed
-// internally in the DB.
-func (f *Field) Virtual() bool {
-	return f.hasOpt("virtual")
+// Get whether the field is full-text searchable.
+func (f *Field) Fts() bool {
+	return f.hasOpt("fts")
 }
 
 //
-// Get whether the field is unique.
-func (f *Field) Unique() []string {
-	list := []string{}
-	for _, opt := range strings.Split(f.Tag, ",") {
-		opt = strings.TrimSpace(opt)
-		m := UniqueRegex.FindStringSubmatch(opt)
+// Get the (doc) description.
+func (f *Field) Doc() string {
+	for _, opt := range f.options() {
+		m := DocRegex.FindStringSubmatch(opt)
 		if m != nil && len(m) == 5 {
-			list = append(list, m[3])
+			return strings.TrimSpace(m[3])
 		}
 	}
 
-	return list
+	return ""
+}
+
+//
+// Get the field collation.
+// The name is returned in upper case.
+func (f *Field) Collate() (name string, found bool) {
+	for _, opt := range f.options() {
+		m := CollateRegex.FindStringSubmatch(opt)
+		if m != nil && len(m) == 5 {
+			name = strings.ToUpper(strings.TrimSpace(m[3]))
+			found = true
+			return
+		}
+	}
+
+	return
 }
 
 //
 // Get whether the field is a foreign key.
 func (f *Field) Fk() *FK {
-	for _, opt := range strings.Split(f.Tag, ",") {
-		opt = strings.TrimSpace(opt)
+	for _, opt := range f.options() {
 		m := FkRegex.FindStringSubmatch(opt)
 		if m != nil && len(m) == 6 {
-			return &FK{
+			fk := &FK{
 				Table: m[2],
 				Field: m[4],
 			}
+			part := strings.Split(m[4], ",")
+			if len(part) == 2 {
+				fk.Field = strings.TrimSpace(part[0])
+				fk.Deferred = strings.TrimSpace(part[1]) == "deferred"
+			}
+			return fk
 		}
 	}
 
@@ -1080,7 +3737,25 @@ func (f *Field) Fk() *FK {
 
 // Convert the specified `object` to a value
 // (type) appropriate for the field.
+// Integers are converted to/from strings in base 10 (only);
+// prefixed (0x, 0o, 0b) strings are not valid int values.
+// Bools stored as TEXT are converted to ('true','false').
 func (f *Field) AsValue(object interface{}) (value interface{}, err error) {
+	if f.Custom() {
+		if valuer, cast := object.(driver.Valuer); cast {
+			value, err = valuer.Value()
+			if err != nil {
+				err = liberr.Wrap(err)
+			}
+			return
+		}
+		if !driver.IsValue(object) {
+			err = liberr.Wrap(PredicateValueErr)
+			return
+		}
+		value = object
+		return
+	}
 	val := reflect.ValueOf(object)
 	switch val.Kind() {
 	case reflect.Ptr:
@@ -1095,7 +3770,7 @@ func (f *Field) AsValue(object interface{}) (value interface{}, err error) {
 	case reflect.String:
 		switch val.Kind() {
 		case reflect.String:
-			value = val.String()
+			value = f.transform(val.String())
 		case reflect.Bool:
 			b := val.Bool()
 			value = strconv.FormatBool(b)
@@ -1105,7 +3780,7 @@ func (f *Field) AsValue(object interface{}) (value interface{}, err error) {
 			reflect.Int32,
 			reflect.Int64:
 			n := val.Int()
-			value = strconv.FormatInt(n, 0)
+			value = strconv.FormatInt(n, 10)
 		default:
 			err = liberr.Wrap(PredicateValueErr)
 		}
@@ -1142,9 +3817,10 @@ func (f *Field) AsValue(object interface{}) (value interface{}, err error) {
 		reflect.Int64:
 		switch val.Kind() {
 		case reflect.String:
-			n, err := strconv.ParseInt(val.String(), 0, 64)
-			if err != nil {
-				err = liberr.Wrap(err)
+			n, pErr := strconv.ParseInt(val.String(), 10, 64)
+			if pErr != nil {
+				err = liberr.Wrap(pErr)
+				return
 			}
 			value = n
 		case reflect.Bool:
@@ -1165,17 +3841,44 @@ func (f *Field) AsValue(object interface{}) (value interface{}, err error) {
 	default:
 		err = liberr.Wrap(FieldTypeErr)
 	}
+	if err == nil && f.BoolText() {
+		value = strconv.FormatBool(value.(bool))
+	}
 
 	return
 }
 
+//
+// Encode the (encoded field) object.
+func (f *Field) encode(object interface{}) ([]byte, error) {
+	if f.encoder == nil {
+		return JSONEncoder{}.Encode(object)
+	}
+
+	return f.encoder.Encode(object)
+}
+
+//
+// Decode the (encoded field) object.
+func (f *Field) decode(encoded []byte, object interface{}) error {
+	if f.decoder == nil {
+		return JSONDecoder{}.Decode(encoded, object)
+	}
+
+	return f.decoder.Decode(encoded, object)
+}
+
 //
 // Get whether the field is `json` encoded.
 func (f *Field) Encoded() (encoded bool) {
+	if f.Custom() {
+		return
+	}
 	switch f.Value.Kind() {
 	case reflect.Struct,
 		reflect.Slice,
-		reflect.Map:
+		reflect.Map,
+		reflect.Interface:
 		encoded = true
 	}
 
@@ -1185,17 +3888,17 @@ func (f *Field) Encoded() (encoded bool) {
 //
 // Detail level.
 func (f *Field) Detail() (level int) {
-	for n := 0; n < 10; n++ {
+	for n := 0; n <= DetailMax; n++ {
 		if f.hasOpt(fmt.Sprintf("d%d", n)) {
 			return n
 		}
 	}
-	level = 2
+	level = DetailPlain
 	if f.Pk() || f.Key() || f.Virtual() {
-		level = 0
+		level = DetailCore
 	}
 	if f.Encoded() {
-		level = 3
+		level = DetailEncoded
 	}
 
 	return
@@ -1204,18 +3907,41 @@ func (f *Field) Detail() (level int) {
 //
 // Match detail level.
 func (f *Field) MatchDetail(level int) bool {
-	if level == 1 { // ALL
+	if level == DetailAll {
 		return true
 	}
 
 	return f.Detail() <= level
 }
 
+//
+// Get the tag options.
+// Commas within parentheses do not delimit options.
+func (f *Field) options() (list []string) {
+	depth := 0
+	mark := 0
+	for i, r := range f.Tag {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				list = append(list, strings.TrimSpace(f.Tag[mark:i]))
+				mark = i + 1
+			}
+		}
+	}
+	list = append(list, strings.TrimSpace(f.Tag[mark:]))
+
+	return
+}
+
 //
 // Get whether field has an option.
 func (f *Field) hasOpt(name string) bool {
-	for _, opt := range strings.Split(f.Tag, ",") {
-		opt = strings.TrimSpace(opt)
+	for _, opt := range f.options() {
 		if opt == name {
 			return true
 		}
@@ -1224,6 +3950,55 @@ func (f *Field) hasOpt(name string) bool {
 	return false
 }
 
+//
+// Get whether the type implements sql.Scanner
+// and driver.Valuer.
+func custom(t reflect.Type) bool {
+	scanner := reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuer := reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	pt := reflect.PtrTo(t)
+	return pt.Implements(scanner) &&
+		(t.Implements(valuer) || pt.Implements(valuer))
+}
+
+//
+// Compress (gzip).
+func compress(s string) (string, error) {
+	bfr := &bytes.Buffer{}
+	writer := gzip.NewWriter(bfr)
+	_, err := writer.Write([]byte(s))
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+	err = writer.Close()
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+
+	return bfr.String(), nil
+}
+
+//
+// Decompress (gzip).
+// Values without the gzip magic bytes (example: stored
+// before the field was compressed) are returned as-is.
+func decompress(s string) (string, error) {
+	if len(s) < 2 || s[0] != 0x1f || s[1] != 0x8b {
+		return s, nil
+	}
+	reader, err := gzip.NewReader(strings.NewReader(s))
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+	defer reader.Close()
+	b, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+
+	return string(b), nil
+}
+
 //
 // FK constraint.
 type FK struct {
@@ -1231,21 +4006,110 @@ type FK struct {
 	Table string
 	// Field name.
 	Field string
+	// Deferred until commit.
+	Deferred bool
 }
 
 //
 // Get DDL.
 func (f *FK) DDL(field *Field) string {
-	return fmt.Sprintf(
+	ddl := fmt.Sprintf(
 		"FOREIGN KEY (%s) REFERENCES %s (%s) ON DELETE CASCADE",
-		field.Name,
+		field.Column,
 		f.Table,
 		f.Field)
+	if f.Deferred {
+		ddl += " DEFERRABLE INITIALLY DEFERRED"
+	}
+
+	return ddl
+}
+
+//
+// Generated column.
+type Generated struct {
+	// SQL expression.
+	Expr string
+	// Stored (else virtual).
+	Stored bool
+}
+
+//
+// Get DDL.
+func (g *Generated) DDL() string {
+	kind := "VIRTUAL"
+	if g.Stored {
+		kind = "STORED"
+	}
+
+	return fmt.Sprintf("GENERATED ALWAYS AS (%s) %s", g.Expr, kind)
+}
+
+//
+// Table description.
+type TableSchema struct {
+	// Table name.
+	Name string
+	// Model (type) name.
+	Model string
+	// Columns.
+	Columns []ColumnSchema
+}
+
+//
+// Column description.
+type ColumnSchema struct {
+	// Column name.
+	Name string
+	// Model field name.
+	Field string
+	// SQL type.
+	Type string
+	// Primary key.
+	Pk bool
+	// Part of the natural key.
+	Key bool
+	// Immutable.
+	Const bool
+	// Managed by the DB.
+	Virtual bool
+	// Unique (together) groups.
+	Unique []string
+	// Foreign key.
+	Fk *FK
+	// Enumerated values.
+	Enum []string
+	// Collation.
+	Collate string
+	// Generated column.
+	Generated *Generated
+	// Compressed.
+	Compressed bool
+	// Encrypted.
+	Encrypted bool
+	// Full-text searchable.
+	Fts bool
+	// Detail level.
+	Detail int
+	// Description.
+	Doc string
+}
+
+//
+// Query planner statistics (sqlite_stat1).
+type PlannerStat struct {
+	// Index name.
+	// Empty for the table.
+	Index string
+	// Statistics. Format: `rows [rows/distinct-key] ...`.
+	Stat string
 }
 
 //
 // Template data.
 type TmplData struct {
+	// Schema (attached database) name.
+	Schema string
 	// Table name.
 	Table string
 	// Fields.
@@ -1260,12 +4124,20 @@ type TmplData struct {
 	Options *ListOptions
 	// Count
 	Count bool
+	// Group by fields.
+	Group []*Field
+	// Having (grouped) expression.
+	Having string
+	// Aggregate function.
+	Function string
+	// Create the table WITHOUT ROWID.
+	WithoutRowID bool
 }
 
 //
 // Predicate
 func (t TmplData) Predicate() Predicate {
-	return t.Options.Predicate
+	return t.Options.predicate
 }
 
 //
@@ -1275,55 +4147,342 @@ func (t TmplData) Page() *Page {
 }
 
 //
-// Sort criteria
-func (t TmplData) Sort() []int {
-	return t.Options.Sort
+// Page limit (param).
+func (t TmplData) Limit() string {
+	return t.Options.limit
+}
+
+//
+// Page offset (param).
+func (t TmplData) Offset() string {
+	return t.Options.offset
+}
+
+//
+// Full-text search query (param).
+func (t TmplData) Search() string {
+	if t.Options == nil {
+		return ""
+	}
+	return t.Options.search
+}
+
+//
+// Sort criteria.
+// The PK is appended (as a tie-breaker) unless already
+// included or disabled. This ensures a total (stable)
+// order which is needed for stable pagination.
+func (t TmplData) Sort() (list []string) {
+	options := t.Options
+	if options.search != "" {
+		list = append(list, "FtsRank")
+	}
+	fields := options.Fields()
+	for _, n := range options.Sort {
+		if n > 0 && n <= len(fields) {
+			column := fields[n-1].Column
+			switch options.Nulls[n] {
+			case NullsFirst:
+				list = append(list, "CASE WHEN "+column+" IS NULL THEN 0 ELSE 1 END")
+			case NullsLast:
+				list = append(list, "CASE WHEN "+column+" IS NULL THEN 1 ELSE 0 END")
+			}
+		}
+		list = append(list, strconv.Itoa(n))
+	}
+	if len(list) == 0 || options.DisableTieBreak {
+		return
+	}
+	for i, f := range fields {
+		if !f.Pk() {
+			continue
+		}
+		for _, n := range options.Sort {
+			if n == i+1 {
+				return
+			}
+		}
+		list = append(list, f.Column)
+		break
+	}
+
+	return
 }
 
+//
+// Filter and predicate combination.
+type Combine int
+
+const (
+	// Filter AND predicate.
+	CombineAnd Combine = iota
+	// Filter OR predicate.
+	CombineOr
+)
+
+//
+// Ordering of NULL values.
+type Nulls int
+
+const (
+	// The DB default. NULLs are first (ascending).
+	NullsDefault Nulls = iota
+	// NULLs first.
+	NullsFirst
+	// NULLs last.
+	NullsLast
+)
+
 //
 // List options.
 type ListOptions struct {
 	// Pagination.
+	// Limits greater than the (table) maximum are capped.
 	Page *Page
 	// Sort by field position.
 	Sort []int
+	// Ordering of NULL values by sort (field) position.
+	// Ignored for positions not included in the Sort.
+	Nulls map[int]Nulls
+	// Disable appending the PK to the sort criteria as
+	// a tie-breaker. Without the tie-breaker, models with
+	// equal sort values are returned in arbitrary order.
+	DisableTieBreak bool
 	// Field detail level.
-	//   0 = core: pk; key and virtual fields.
-	//   1 = all fields.
-	//   2 = plain fields.
-	//   3 = encoded fields.
+	//   0 = DetailCore: pk; key and virtual fields.
+	//   1 = DetailAll: all fields.
+	//   2 = DetailPlain: plain fields.
+	//   3 = DetailEncoded: encoded fields.
+	//   4-9 = fields tagged: `dN`.
+	// When 0, the model default is used when declared.
+	// See: DetailDefault.
 	Detail int
 	// Predicate
 	Predicate Predicate
+	// Filter (selector) model.
+	// The non-zero fields of the filter are matched
+	// for equality and combined with the predicate.
+	// Zero values and encoded fields are ignored; use
+	// the predicate to match zero values.
+	// Must be the listed model type.
+	Filter Model
+	// How the filter is combined with the predicate.
+	// Default: CombineAnd.
+	Combine Combine
+	// Predicate applied to groups (HAVING) which may
+	// reference aggregates. Example: CountAll().Gt(10).
+	// Valid only for grouped queries. See: CountGroups().
+	Having Predicate
+	// Expected number of models (hint).
+	// The listed slice is pre-allocated with the capacity.
+	// When 0, the page limit (up to MaxPageCapacity) is used.
+	Capacity int
+	// Model (listed).
+	model interface{}
 	// Table (name).
 	table string
+	// Naming strategy.
+	namer Namer
+	// Maximum page limit.
+	maxLimit int
+	// Maximum (unpaged) rows.
+	maxRows int
+	// SQLite limits.
+	limits Limits
 	// Fields.
 	fields []*Field
 	// Params.
 	params []interface{}
+	// Param name prefix.
+	prefix string
+	// The built predicate.
+	predicate Predicate
+	// Predicate interceptor (bound).
+	intercept func(Predicate) (Predicate, error)
+	// Page limit (param).
+	limit string
+	// Page offset (param).
+	offset string
+	// Full-text search query (param).
+	search string
+	// Full-text search query.
+	query string
+	// Grouped query.
+	grouped bool
+	// Building the having predicate.
+	having bool
+}
+
+//
+// Clone the options.
+// The (built) state is not copied so the clone may be
+// built (used) independently of the original.
+func (l *ListOptions) Clone() ListOptions {
+	clone := ListOptions{
+		DisableTieBreak: l.DisableTieBreak,
+		Detail:          l.Detail,
+		Predicate:       l.Predicate,
+		Filter:          l.Filter,
+		Combine:         l.Combine,
+		Having:          l.Having,
+		Capacity:        l.Capacity,
+		query:           l.query,
+	}
+	if l.Page != nil {
+		page := *l.Page
+		clone.Page = &page
+	}
+	if l.Sort != nil {
+		clone.Sort = append([]int{}, l.Sort...)
+	}
+	if l.Nulls != nil {
+		clone.Nulls = map[int]Nulls{}
+		for n, nulls := range l.Nulls {
+			clone.Nulls[n] = nulls
+		}
+	}
+
+	return clone
 }
 
 //
 // Validate options.
+// The (built) state is reset so the options may be
+// built more than once.
 func (l *ListOptions) Build(table string, fields []*Field) error {
 	l.table = table
 	l.fields = fields
-	if l.Predicate == nil {
-		return nil
+	l.params = nil
+	l.limit = ""
+	l.offset = ""
+	l.search = ""
+	l.predicate = l.Predicate
+	if l.Page != nil {
+		err := l.Page.Validate()
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		if l.maxLimit > 0 && l.Page.Limit > l.maxLimit {
+			page := *l.Page
+			page.Limit = l.maxLimit
+			l.Page = &page
+		}
+		l.limit = l.Param("limit", l.Page.Limit)
+		l.offset = l.Param("offset", l.Page.Offset)
 	}
-	err := l.Predicate.Build(l)
-	if err != nil {
-		return liberr.Wrap(err)
+	if l.query != "" {
+		searchable := false
+		for _, f := range fields {
+			if f.Fts() {
+				searchable = true
+				break
+			}
+		}
+		if !searchable {
+			return liberr.Wrap(FtsErr)
+		}
+		l.search = l.Param("search", l.query)
+	}
+	if l.Filter != nil {
+		selector, err := l.selector()
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		if selector != nil {
+			if l.predicate != nil {
+				switch l.Combine {
+				case CombineOr:
+					l.predicate = Or(selector, l.predicate)
+				default:
+					l.predicate = And(selector, l.predicate)
+				}
+			} else {
+				l.predicate = selector
+			}
+		}
+	}
+	if l.intercept != nil {
+		predicate, err := l.intercept(l.predicate)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		l.predicate = predicate
+	}
+	if l.predicate != nil {
+		err := l.predicate.Build(l)
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+	}
+	if l.Having != nil {
+		if !l.grouped {
+			return liberr.Wrap(HavingErr)
+		}
+		l.having = true
+		err := l.Having.Build(l)
+		l.having = false
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+	}
+	if l.limits.Variables > 0 && len(l.params) > l.limits.Variables {
+		return liberr.Wrap(ParamLimitErr)
+	}
+	if l.predicate != nil {
+		if l.limits.ExprDepth > 0 && exprDepth(l.predicate) > l.limits.ExprDepth {
+			return liberr.Wrap(ExprDepthErr)
+		}
 	}
 
 	return nil
 }
 
+//
+// Build the predicate for the filter (selector) model.
+// Each non-zero (not encoded) field is matched for equality.
+// Returns nil when no fields are selected.
+func (l *ListOptions) selector() (Predicate, error) {
+	fields, err := Table{Namer: l.namer}.Fields(l.Filter)
+	if err != nil {
+		return nil, liberr.Wrap(err)
+	}
+	predicates := []Predicate{}
+	for _, f := range fields {
+		if f.Encoded() || f.Value.IsZero() {
+			continue
+		}
+		predicates = append(
+			predicates,
+			Eq(f.Name, f.Value.Interface()))
+	}
+	if len(predicates) == 0 {
+		return nil, nil
+	}
+
+	return And(predicates...), nil
+}
+
+//
+// The capacity of the listed slice.
+func (l *ListOptions) capacity() int {
+	if l.Capacity > 0 {
+		return l.Capacity
+	}
+	if l.Page != nil && l.Page.Limit > 0 {
+		if l.Page.Limit > MaxPageCapacity {
+			return MaxPageCapacity
+		}
+		return l.Page.Limit
+	}
+
+	return 0
+}
+
 //
 // Get an appropriate parameter name.
 // Builds a parameter and adds it to the options.param list.
 func (l *ListOptions) Param(name string, value interface{}) string {
-	name = fmt.Sprintf("%s%d", name, len(l.params))
+	name = fmt.Sprintf("%s%s%d", l.prefix, name, len(l.params))
 	l.params = append(l.params, sql.Named(name, value))
 	return ":" + name
 }
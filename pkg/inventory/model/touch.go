@@ -0,0 +1,247 @@
+package model
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"reflect"
+	"text/template"
+	"time"
+)
+
+var DeleteStaleSQL = `
+DELETE FROM {{.Table}}
+WHERE
+{{ (index .Fields 0).Column }} < :cutoff
+;
+`
+
+var DeleteStaleLabelSQL = `
+DELETE FROM {{ .Label }}
+WHERE
+{{ .Kind }} = :kind AND
+{{ .Parent }} IN (
+SELECT {{ .Pk.Column }}
+FROM {{ .Table }}
+WHERE
+{{ .Touch.Column }} < :cutoff
+)
+;
+`
+
+//
+// Errors
+var (
+	// Touch field error.
+	TouchErr = errors.New("touch field must be (int64), unique to the model and not (pk, key, virtual, const)")
+)
+
+//
+// Touch the model in the DB.
+// Only the `touch` field is updated (to the current time)
+// by PK. Intended to cheaply mark a model as seen without
+// rewriting the model. Models are touched on Insert() and
+// Update() as well.
+func (t Table) Touch(model interface{}) error {
+	fields, err := t.Fields(model)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	touch := t.TouchField(fields)
+	if touch == nil {
+		return liberr.Wrap(TouchErr)
+	}
+	err = t.SetPk(fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	t.touch(fields)
+	stmt, err := t.touchSQL(t.Name(model), fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	params, err := t.Params(fields)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	r, err := t.db().Exec(stmt, params...)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	nRows, err := r.RowsAffected()
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	if nRows == 0 {
+		return liberr.Wrap(NotFound)
+	}
+
+	return nil
+}
+
+//
+// Delete models in the DB not touched since the cutoff.
+// The model must have a `touch` field. Returns the number
+// of models deleted. Labels are not deleted.
+func (t Table) DeleteStale(model interface{}, cutoff time.Time) (int64, error) {
+	fields, err := t.Fields(model)
+	if err != nil {
+		return 0, liberr.Wrap(err)
+	}
+	touch := t.TouchField(fields)
+	if touch == nil {
+		return 0, liberr.Wrap(TouchErr)
+	}
+	tpl := template.New("")
+	tpl, err = tpl.Parse(DeleteStaleSQL)
+	if err != nil {
+		return 0, liberr.Wrap(err)
+	}
+	bfr := &bytes.Buffer{}
+	err = tpl.Execute(
+		bfr,
+		TmplData{
+			Table:  t.Name(model),
+			Fields: []*Field{touch},
+		})
+	if err != nil {
+		return 0, liberr.Wrap(err)
+	}
+	r, err := t.db().Exec(
+		bfr.String(),
+		sql.Named("cutoff", cutoff.UnixNano()))
+	if err != nil {
+		return 0, liberr.Wrap(err)
+	}
+	nRows, err := r.RowsAffected()
+	if err != nil {
+		return 0, liberr.Wrap(err)
+	}
+
+	return nRows, nil
+}
+
+//
+// Get the `touch` field.
+// Returns nil when not found.
+func (t Table) TouchField(fields []*Field) *Field {
+	for _, f := range fields {
+		if f.Touch() {
+			return f
+		}
+	}
+
+	return nil
+}
+
+//
+// Set the `touch` field (when found) to the current
+// time as Unix nanoseconds.
+func (t Table) touch(fields []*Field) {
+	f := t.TouchField(fields)
+	if f != nil {
+		f.Value.SetInt(time.Now().UnixNano())
+	}
+}
+
+//
+// Validate the `touch` field(s).
+func (t Table) validateTouch(fields []*Field) error {
+	found := false
+	for _, f := range fields {
+		if !f.Touch() {
+			continue
+		}
+		if found {
+			return liberr.Wrap(TouchErr)
+		}
+		found = true
+		if f.Value.Kind() != reflect.Int64 || f.Custom() {
+			return liberr.Wrap(TouchErr)
+		}
+		if f.Pk() || f.Const() || f.Virtual() {
+			return liberr.Wrap(TouchErr)
+		}
+	}
+
+	return nil
+}
+
+//
+// Build model touch SQL.
+func (t Table) touchSQL(table string, fields []*Field) (string, error) {
+	tpl := template.New("")
+	tpl, err := tpl.Parse(UpdateSQL)
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+	bfr := &bytes.Buffer{}
+	err = tpl.Execute(
+		bfr,
+		TmplData{
+			Table:  table,
+			Fields: []*Field{t.TouchField(fields)},
+			Pk:     t.PkField(fields),
+		})
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+
+	return bfr.String(), nil
+}
+
+//
+// Delete the labels for models not touched since the cutoff.
+func (r *Labeler) DeleteStale(table Table, model Model, cutoff time.Time) error {
+	if r.Disabled {
+		return nil
+	}
+	fields, err := table.Fields(model)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	labelFields, err := table.Fields(&Label{})
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	column := func(name string) string {
+		f, _ := table.find(name, labelFields)
+		return f.Column
+	}
+	tpl := template.New("")
+	tpl, err = tpl.Parse(DeleteStaleLabelSQL)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	bfr := &bytes.Buffer{}
+	err = tpl.Execute(
+		bfr,
+		struct {
+			Label  string
+			Kind   string
+			Parent string
+			Table  string
+			Pk     *Field
+			Touch  *Field
+		}{
+			Label:  table.Name(&Label{}),
+			Kind:   column("Kind"),
+			Parent: column("Parent"),
+			Table:  table.Name(model),
+			Pk:     table.PkField(fields),
+			Touch:  table.TouchField(fields),
+		})
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	_, err = table.db().Exec(
+		bfr.String(),
+		sql.Named("kind", table.Name(model)),
+		sql.Named("cutoff", cutoff.UnixNano()))
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+//
+// Errors
+var (
+	// Transform not valid.
+	TransformErr = errors.New("transform must be known (registered) and on str field")
+)
+
+//
+// Regex used for `transform(A|B)` tags.
+var TransformRegex = regexp.MustCompile(`(transform)(\()(.+)(\))`)
+
+//
+// Field value transform.
+// Applied to (str) field values written to and read
+// from the DB. Must be idempotent.
+type Transform func(string) string
+
+//
+// Built-in transforms.
+var Transforms = map[string]Transform{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+}
+
+//
+// Get the (named) transforms applied to the field.
+// Applied in order.
+func (f *Field) Transforms() (list []string) {
+	for _, opt := range f.options() {
+		m := TransformRegex.FindStringSubmatch(opt)
+		if m != nil && len(m) == 5 {
+			for _, name := range strings.Split(m[3], "|") {
+				name = strings.TrimSpace(name)
+				if name != "" {
+					list = append(list, name)
+				}
+			}
+			return
+		}
+	}
+
+	return
+}
+
+//
+// Validate the field transforms.
+func (f *Field) validateTransforms() error {
+	names := f.Transforms()
+	if len(names) == 0 {
+		return nil
+	}
+	if f.Custom() || f.Value.Kind() != reflect.String {
+		return liberr.Wrap(TransformErr)
+	}
+	for _, name := range names {
+		if _, found := f.transformFn(name); !found {
+			return liberr.Wrap(TransformErr)
+		}
+	}
+
+	return nil
+}
+
+//
+// Apply the field transforms to the value.
+// Unknown transforms are ignored.
+func (f *Field) transform(value string) string {
+	for _, name := range f.Transforms() {
+		if fn, found := f.transformFn(name); found {
+			value = fn(value)
+		}
+	}
+
+	return value
+}
+
+//
+// Find a transform by name.
+// Transforms registered on the table (client) take
+// precedence over the built-in transforms.
+func (f *Field) transformFn(name string) (fn Transform, found bool) {
+	fn, found = f.transforms[name]
+	if !found {
+		fn, found = Transforms[name]
+	}
+
+	return
+}
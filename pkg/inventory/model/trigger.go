@@ -0,0 +1,191 @@
+package model
+
+import (
+	"bytes"
+	"errors"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+//
+// Trigger DDL template.
+var TriggerDDL = `
+CREATE TRIGGER IF NOT EXISTS {{ if .Schema }}{{ .Schema }}.{{ end }}{{ .Table }}{{ .Trigger.Name }}
+{{ .Trigger.Timing }} {{ .Trigger.Event }} ON {{ .Table }}
+FOR EACH ROW
+{{ if .When -}}
+WHEN {{ .When }}
+{{ end -}}
+BEGIN
+{{ .Body }}
+END;
+`
+
+//
+// Errors
+var (
+	// Invalid trigger.
+	TriggerErr = errors.New("trigger must have (name, event, body) and reference known models")
+)
+
+//
+// Regex used to validate trigger names.
+var TriggerRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+//
+// Optionally implemented by models to declare triggers
+// created with the table. Intended to have the DB maintain
+// derived (denormalized) columns and invariants.
+type Triggers interface {
+	Triggers() []Trigger
+}
+
+//
+// Trigger (descriptor).
+// The `When` and `Body` are templates. Tables are referenced
+// using the `table` function with the model type name which
+// ensures the model is known and the name is correct for
+// the naming strategy. Example:
+//   Trigger{
+//       Name:  "Count",
+//       Event: "INSERT",
+//       Body: `
+//           UPDATE {{ table "Parent" }}
+//           SET Children = Children + 1
+//           WHERE PK = new.Parent;`,
+//   }
+type Trigger struct {
+	// Name (identifier) unique to the model.
+	// The trigger is named: <table><name>.
+	Name string
+	// Timing: (BEFORE|AFTER). Default: AFTER.
+	Timing string
+	// Event: (INSERT|UPDATE|DELETE).
+	Event string
+	// Condition (optional) SQL expression.
+	When string
+	// SQL statements, each terminated by `;`.
+	Body string
+}
+
+//
+// Validate the trigger.
+func (r *Trigger) Validate() error {
+	if !TriggerRegex.MatchString(r.Name) {
+		return liberr.Wrap(TriggerErr)
+	}
+	switch strings.ToUpper(r.Timing) {
+	case "", "BEFORE", "AFTER":
+	default:
+		return liberr.Wrap(TriggerErr)
+	}
+	switch strings.ToUpper(r.Event) {
+	case "INSERT", "UPDATE", "DELETE":
+	default:
+		return liberr.Wrap(TriggerErr)
+	}
+	if strings.TrimSpace(r.Body) == "" {
+		return liberr.Wrap(TriggerErr)
+	}
+
+	return nil
+}
+
+//
+// Trigger template data.
+type TriggerData struct {
+	// Schema (attached database) name.
+	Schema string
+	// Table name.
+	Table string
+	// Trigger.
+	Trigger Trigger
+	// Rendered condition.
+	When string
+	// Rendered body.
+	Body string
+}
+
+//
+// Get the trigger DDL for the model.
+func (t Table) TriggerDDL(model interface{}) ([]string, error) {
+	list := []string{}
+	m, cast := model.(Triggers)
+	if !cast {
+		return list, nil
+	}
+	mt := reflect.TypeOf(model)
+	if mt.Kind() == reflect.Ptr {
+		mt = mt.Elem()
+	}
+	functions := template.FuncMap{
+		"table": func(kind string) (string, error) {
+			if kind == mt.Name() {
+				return t.namer().TableName(mt), nil
+			}
+			if rt, found := t.kinds[kind]; found {
+				return t.namer().TableName(rt), nil
+			}
+			return "", liberr.Wrap(TriggerErr)
+		},
+	}
+	schema, name := t.split(model)
+	for _, trigger := range m.Triggers() {
+		err := trigger.Validate()
+		if err != nil {
+			return nil, liberr.Wrap(err)
+		}
+		trigger.Event = strings.ToUpper(trigger.Event)
+		trigger.Timing = strings.ToUpper(trigger.Timing)
+		if trigger.Timing == "" {
+			trigger.Timing = "AFTER"
+		}
+		data := TriggerData{
+			Schema:  schema,
+			Table:   name,
+			Trigger: trigger,
+		}
+		data.When, err = t.render(trigger.When, functions)
+		if err != nil {
+			return nil, liberr.Wrap(err)
+		}
+		data.Body, err = t.render(trigger.Body, functions)
+		if err != nil {
+			return nil, liberr.Wrap(err)
+		}
+		tpl, err := template.New("").Parse(TriggerDDL)
+		if err != nil {
+			return nil, liberr.Wrap(err)
+		}
+		bfr := &bytes.Buffer{}
+		err = tpl.Execute(bfr, data)
+		if err != nil {
+			return nil, liberr.Wrap(err)
+		}
+		list = append(list, bfr.String())
+	}
+
+	return list, nil
+}
+
+//
+// Render a (trigger) template.
+func (t Table) render(text string, functions template.FuncMap) (string, error) {
+	tpl, err := template.New("").Funcs(functions).Parse(text)
+	if err != nil {
+		return "", liberr.Wrap(err)
+	}
+	bfr := &bytes.Buffer{}
+	err = tpl.Execute(bfr, nil)
+	if err != nil {
+		if errors.Is(err, TriggerErr) {
+			return "", liberr.Wrap(TriggerErr)
+		}
+		return "", liberr.Wrap(err)
+	}
+
+	return strings.TrimSpace(bfr.String()), nil
+}
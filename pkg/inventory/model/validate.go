@@ -0,0 +1,199 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"reflect"
+	"strings"
+)
+
+//
+// Errors
+var (
+	// FK references an unknown field.
+	FkErr = errors.New("fk must reference a known field of the (known) model")
+)
+
+//
+// Model validation error.
+// Reports all of the problems found with a model.
+// Each problem is (typically) a *SchemaError naming the field.
+type ValidationError struct {
+	// Model (type) name.
+	Model string
+	// Problems.
+	Errors []error
+}
+
+//
+// Error description.
+func (e *ValidationError) Error() string {
+	list := []string{}
+	for _, err := range e.Errors {
+		list = append(list, err.Error())
+	}
+
+	s := ""
+	if e.Model != "" {
+		s += "model: " + e.Model + " "
+	}
+
+	return s + fmt.Sprintf(
+		"(%d) problems: %s",
+		len(e.Errors),
+		strings.Join(list, "; "))
+}
+
+//
+// Get whether any of the problems matches the target.
+// Supports: errors.Is().
+func (e *ValidationError) Is(target error) bool {
+	for _, err := range e.Errors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//
+// Find the first problem matching the target.
+// Supports: errors.As().
+func (e *ValidationError) As(target interface{}) bool {
+	for _, err := range e.Errors {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//
+// Validate the model without a DB.
+// The FK references are validated against the model and
+// the (optional) related models.
+// Returns *ValidationError reporting all problems.
+// Example:
+//   err := ValidateModel(&Pet{}, &Person{})
+func ValidateModel(model interface{}, related ...interface{}) error {
+	kinds := map[string]reflect.Type{}
+	for _, m := range append([]interface{}{model}, related...) {
+		mt := reflect.TypeOf(m)
+		if mt != nil && mt.Kind() == reflect.Ptr {
+			mt = mt.Elem()
+		}
+		if mt != nil {
+			kinds[mt.Name()] = mt
+		}
+	}
+	t := Table{kinds: kinds}
+	return t.ValidateModel(model)
+}
+
+//
+// Validate the model (tags).
+// Checks the fields, PK, FK references, (map, touch)
+// fields and the table DDL. FK references to unknown
+// models are not validated. The DB is not used.
+// Returns *ValidationError reporting all problems.
+func (t Table) ValidateModel(model interface{}) error {
+	mt := reflect.TypeOf(model)
+	if mt == nil || mt.Kind() != reflect.Ptr {
+		return liberr.Wrap(MustBePtrErr)
+	}
+	vErr := &ValidationError{Model: mt.Elem().Name()}
+	fields, err := t.Fields(model)
+	if err != nil {
+		vErr.Errors = append(vErr.Errors, err)
+		return liberr.Wrap(vErr)
+	}
+	vErr.Errors = t.problems(fields)
+	for _, f := range fields {
+		err = t.validateFk(f)
+		if err != nil {
+			vErr.Errors = append(
+				vErr.Errors,
+				&SchemaError{
+					Field: f.Name,
+					Err:   err,
+				})
+		}
+	}
+	maps, err := t.MapFields(model)
+	if err != nil {
+		vErr.Errors = append(vErr.Errors, err)
+	}
+	for _, f := range maps {
+		err = t.validateMap(f)
+		if err != nil {
+			vErr.Errors = append(
+				vErr.Errors,
+				&SchemaError{
+					Field: f.Name,
+					Err:   err,
+				})
+		}
+	}
+	if len(vErr.Errors) == 0 {
+		_, err = t.ddl(model)
+		if err != nil {
+			vErr.Errors = append(vErr.Errors, err)
+		}
+	}
+	if len(vErr.Errors) > 0 {
+		return liberr.Wrap(vErr)
+	}
+
+	return nil
+}
+
+//
+// Get all of the problems with the fields.
+func (t Table) problems(fields []*Field) (list []error) {
+	if len(fields) == 0 {
+		list = append(list, liberr.Wrap(NoFieldsErr))
+		return
+	}
+	for _, f := range fields {
+		err := f.Validate()
+		if err != nil {
+			list = append(
+				list,
+				&SchemaError{
+					Field: f.Name,
+					Err:   err,
+				})
+		}
+	}
+	if t.PkField(fields) == nil {
+		list = append(list, liberr.Wrap(MustHavePkErr))
+	}
+	err := t.validateTouch(fields)
+	if err != nil {
+		list = append(list, err)
+	}
+
+	return
+}
+
+//
+// Validate the FK references a known field when
+// the referenced model is known.
+func (t Table) validateFk(f *Field) error {
+	fk := f.Fk()
+	if fk == nil {
+		return nil
+	}
+	mt, found := t.kinds[fk.Table]
+	if !found {
+		return nil
+	}
+	if _, found := mt.FieldByName(fk.Field); !found {
+		return liberr.Wrap(FkErr)
+	}
+
+	return nil
+}
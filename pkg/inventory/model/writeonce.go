@@ -0,0 +1,112 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"reflect"
+)
+
+//
+// Errors
+var (
+	// Write-once field changed.
+	WriteOnceErr = errors.New("write-once field cannot be changed")
+)
+
+//
+// Write-once field error.
+// The update changed the value of a write-once field.
+type WriteOnceError struct {
+	// Field name.
+	Field string
+}
+
+//
+// Error description.
+func (e *WriteOnceError) Error() string {
+	return fmt.Sprintf(
+		"field: %s %s",
+		e.Field,
+		WriteOnceErr.Error())
+}
+
+//
+// Match WriteOnceErr.
+func (e *WriteOnceError) Is(target error) bool {
+	return target == WriteOnceErr
+}
+
+//
+// Get whether the field is write-once.
+// The field is set on insert and not updated. Unlike
+// `const`, an update changing the value is an error.
+func (f *Field) WriteOnce() bool {
+	return f.hasOpt("writeonce")
+}
+
+//
+// Validate the write-once fields are not changed.
+// The stored values are fetched by PK and compared with
+// the model values. Returns *WriteOnceError for the
+// first changed field. A model not found is ignored.
+func (t Table) validateWriteOnce(model interface{}, fields []*Field) error {
+	changed := []*Field{}
+	for _, f := range fields {
+		if f.WriteOnce() {
+			changed = append(changed, f)
+		}
+	}
+	pk := t.PkField(fields)
+	if len(changed) == 0 || pk == nil {
+		return nil
+	}
+	stored := reflect.New(reflect.TypeOf(model).Elem()).Interface()
+	storedFields, err := t.Fields(stored)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	selected := []*Field{}
+	for _, f := range storedFields {
+		if f.Pk() {
+			f.Value.Set(*pk.Value)
+			selected = append(selected, f)
+			continue
+		}
+		if f.WriteOnce() {
+			selected = append(selected, f)
+		}
+	}
+	stmt, err := t.getSQL(t.Name(model), selected, nil)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	params, err := t.Params(selected)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	cursor, err := t.db().Query(stmt, params...)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	defer cursor.Close()
+	if !cursor.Next() {
+		return liberr.Wrap(cursor.Err())
+	}
+	err = t.scan(cursor, selected)
+	if err != nil {
+		return liberr.Wrap(err)
+	}
+	for _, f := range changed {
+		_, err = f.Pull()
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		sf, _ := t.find(f.Name, selected)
+		if !reflect.DeepEqual(f.Value.Interface(), sf.Value.Interface()) {
+			return liberr.Wrap(&WriteOnceError{Field: f.Name})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"database/sql"
+	liberr "github.com/konveyor/controller/pkg/error"
+	"sync"
+)
+
+//
+// Serialized write queue.
+// Writes are executed (in order) by a single goroutine.
+type writer struct {
+	// Queued writes.
+	jobs chan writeJob
+	// Closed when stopped.
+	done chan struct{}
+}
+
+//
+// Queued write.
+type writeJob struct {
+	// The write.
+	fn func() error
+	// Delivers the result.
+	result chan error
+}
+
+//
+// Start the writer.
+// Each write is executed holding the client write mutex
+// so writes remain serialized with transactions.
+func (w *writer) start(mutex sync.Locker) {
+	w.jobs = make(chan writeJob)
+	w.done = make(chan struct{})
+	go func() {
+		for {
+			select {
+			case job := <-w.jobs:
+				mutex.Lock()
+				err := job.fn()
+				mutex.Unlock()
+				job.result <- err
+			case <-w.done:
+				return
+			}
+		}
+	}()
+}
+
+//
+// Stop the writer.
+func (w *writer) stop() {
+	close(w.done)
+}
+
+//
+// Queue the write and wait for the result.
+// Returns ClosedErr when the writer is stopped.
+func (w *writer) write(fn func() error) error {
+	job := writeJob{
+		fn:     fn,
+		result: make(chan error, 1),
+	}
+	select {
+	case w.jobs <- job:
+	case <-w.done:
+		return liberr.Wrap(ClosedErr)
+	}
+
+	return <-job.result
+}
+
+//
+// Perform a (non-transactional) write.
+// The write is queued when the client is a single writer.
+// Else, executed holding the write mutex.
+func (r *Client) write(fn func(db *sql.DB) error) error {
+	if r.ReadOnly {
+		return liberr.Wrap(ReadOnlyErr)
+	}
+	write := func() error {
+		db, err := r.pool()
+		if err != nil {
+			return liberr.Wrap(err)
+		}
+		return fn(db)
+	}
+	r.stateMutex.RLock()
+	w := r.writer
+	r.stateMutex.RUnlock()
+	if w != nil {
+		return w.write(write)
+	}
+	r.dbMutex.Lock()
+	defer r.dbMutex.Unlock()
+	return write()
+}
@@ -51,7 +51,7 @@ func (h *Paged) setPage(ctx *gin.Context) int {
 	pLimit := q.Get("limit")
 	if len(pLimit) != 0 {
 		nLimit, err := strconv.Atoi(pLimit)
-		if err != nil || nLimit < 0 {
+		if err != nil || nLimit < 1 {
 			return http.StatusBadRequest
 		}
 		page.Limit = nLimit